@@ -0,0 +1,205 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+// Package hdkey 分层确定性密钥派生。
+// 采用 SLIP-0010 的 Ed25519 派生规则（仅支持强化路径），
+// 主种子可由 BIP-39 助记词短语生成，派生出的密钥用于签名和构造账户地址。
+//
+// 注：
+// 助记词仅用于生成种子，不含词表校验，词表和校验和由钱包端负责。
+// 短语应当已按 Unicode NFKD 规范化（英文词表本身即满足）。
+package hdkey
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cxio/suite/cbase/paddr"
+	"github.com/cxio/suite/cbase/sigs"
+	"github.com/cxio/suite/locale"
+)
+
+// 本地化文本获取。
+var _T = locale.GetText
+
+const (
+	// 强化索引起始值。
+	// 路径中以 ' 或 H 后缀表示，如 m/44'/0'。
+	Hardened uint32 = 0x80000000
+
+	// 主种子长度范围（字节）。
+	SeedMin = 16
+	SeedMax = 64
+
+	// 助记词种子的 PBKDF2 迭代次数和长度（BIP-39）。
+	mnemonicIter = 2048
+	mnemonicSize = 64
+)
+
+// SLIP-0010 Ed25519 主密钥的 HMAC 键。
+var curveKey = []byte("ed25519 seed")
+
+var (
+	// 种子长度无效。
+	ErrSeedSize = errors.New(_T("主种子长度无效（16-64字节）"))
+
+	// 非强化索引。
+	ErrNotHardened = errors.New(_T("Ed25519 仅支持强化派生"))
+
+	// 派生路径格式无效。
+	ErrPath = errors.New(_T("派生路径格式无效"))
+)
+
+// 由助记词短语生成主种子。
+// 即 PBKDF2-HMAC-SHA512(mnemonic, "mnemonic"+passphrase, 2048)，64字节。
+// 词之间以单个空格分隔，首尾空白被忽略。
+func MnemonicSeed(mnemonic, passphrase string) []byte {
+	words := strings.Join(strings.Fields(mnemonic), " ")
+
+	seed, err := pbkdf2.Key(sha512.New, words, []byte("mnemonic"+passphrase), mnemonicIter, mnemonicSize)
+	if err != nil {
+		panic(err) // 参数固定，不应出错
+	}
+	return seed
+}
+
+// 扩展私钥。
+// 包含32字节私钥种子和32字节链码，以及在派生树中的位置。
+type Key struct {
+	seed  [32]byte
+	chain [32]byte
+	depth uint8
+	index uint32
+}
+
+// 创建主密钥。
+// seed 为主种子，长度在 SeedMin 到 SeedMax 之间。
+func NewMaster(seed []byte) (*Key, error) {
+	if len(seed) < SeedMin || len(seed) > SeedMax {
+		return nil, ErrSeedSize
+	}
+	return newKey(curveKey, seed, 0, 0), nil
+}
+
+// 由 HMAC-SHA512 输出构造密钥。
+// 左半为私钥种子，右半为链码。
+func newKey(key, data []byte, depth uint8, index uint32) *Key {
+	m := hmac.New(sha512.New, key)
+	m.Write(data)
+	sum := m.Sum(nil)
+
+	k := &Key{depth: depth, index: index}
+	copy(k.seed[:], sum[:32])
+	copy(k.chain[:], sum[32:])
+
+	return k
+}
+
+// 派生子密钥。
+// i 必须为强化索引（>= Hardened），否则返回 ErrNotHardened。
+func (k *Key) Child(i uint32) (*Key, error) {
+	if i < Hardened {
+		return nil, fmt.Errorf("%w: %d", ErrNotHardened, i)
+	}
+	var data [1 + 32 + 4]byte
+	copy(data[1:], k.seed[:])
+	binary.BigEndian.PutUint32(data[33:], i)
+
+	return newKey(k.chain[:], data[:], k.depth+1, i), nil
+}
+
+// 按路径派生。
+// 路径格式见 ParsePath，从当前密钥开始逐级派生。
+func (k *Key) Derive(path string) (*Key, error) {
+	ids, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range ids {
+		if k, err = k.Child(i); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// 私钥种子（32字节）。
+func (k *Key) Seed() []byte {
+	return append([]byte(nil), k.seed[:]...)
+}
+
+// 链码（32字节）。
+func (k *Key) ChainCode() []byte {
+	return append([]byte(nil), k.chain[:]...)
+}
+
+// 派生深度，主密钥为0。
+func (k *Key) Depth() int {
+	return int(k.depth)
+}
+
+// 派生索引，主密钥为0。
+func (k *Key) Index() uint32 {
+	return k.index
+}
+
+// 签名私钥。
+func (k *Key) PrivateKey() sigs.PrivateKey {
+	s, _ := sigs.Get(sigs.Ed25519)
+
+	pk, err := s.NewKey(k.seed[:])
+	if err != nil {
+		panic(err) // Ed25519 接受任意32字节种子
+	}
+	return pk
+}
+
+// 公钥（32字节）。
+func (k *Key) PublicKey() []byte {
+	return k.PrivateKey().Public()
+}
+
+// 公钥地址。
+func (k *Key) Address() paddr.PKAddr {
+	return paddr.Hash(k.PublicKey(), nil)
+}
+
+// 账户地址。
+// 即公钥地址的 Base58 文本形式，prefix 为地址前缀（见 paddr.Encode）。
+func (k *Key) Encode(prefix string) string {
+	return paddr.Encode(k.Address(), prefix)
+}
+
+// 解析派生路径。
+// 格式如 m/44'/0'/1H，首段 m 表示当前密钥（可省略），
+// 各段为十进制索引，后缀 ' 或 H 表示强化（加 Hardened）。
+// 注：Ed25519 仅支持强化派生，非强化段在派生时出错而非此处。
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+
+	if parts[0] == "m" {
+		parts = parts[1:]
+	}
+	ids := make([]uint32, 0, len(parts))
+
+	for _, p := range parts {
+		var h uint32
+		if s, ok := strings.CutSuffix(p, "'"); ok {
+			p, h = s, Hardened
+		} else if s, ok := strings.CutSuffix(p, "H"); ok {
+			p, h = s, Hardened
+		}
+		n, err := strconv.ParseUint(p, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrPath, path)
+		}
+		ids = append(ids, uint32(n)|h)
+	}
+	return ids, nil
+}
@@ -0,0 +1,119 @@
+package hdkey_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/cxio/suite/cbase/hdkey"
+	"github.com/cxio/suite/cbase/paddr"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// SLIP-0010 Ed25519 测试向量1。
+func TestSLIP10(t *testing.T) {
+	master, err := hdkey.NewMaster(unhex("000102030405060708090a0b0c0d0e0f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path  string
+		chain string
+		priv  string
+		pub   string
+	}{
+		{"m",
+			"90046a93de5380a72b5e45010748567d5ea02bbf6522f979e05c0d8d8ca9fffb",
+			"2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7",
+			"a4b2856bfec510abab89753fac1ac0e1112364e7d250545963f135f2a33188ed"},
+		{"m/0H",
+			"8b59aa11380b624e81507a27fedda59fea6d0b779a778918a2fd3590e16e9c69",
+			"68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3",
+			"8c8a13df77a28f3445213a0f432fde644acaa215fc72dcdf300d5efaa85d350c"},
+		{"m/0'/1'",
+			"a320425f77d1b5c2505a6b1b27382b37368ee640e3557c315416801243552f14",
+			"b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2",
+			"1932a5270f335bed617d5b935c80aedb1a35bd9fc1e31acafd5372c30f5c1187"},
+	}
+	for _, tt := range tests {
+		k, err := master.Derive(tt.path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if got := hex.EncodeToString(k.ChainCode()); got != tt.chain {
+			t.Errorf("%s chain: %s", tt.path, got)
+		}
+		if got := hex.EncodeToString(k.Seed()); got != tt.priv {
+			t.Errorf("%s priv: %s", tt.path, got)
+		}
+		if got := hex.EncodeToString(k.PublicKey()); got != tt.pub {
+			t.Errorf("%s pub: %s", tt.path, got)
+		}
+	}
+}
+
+// BIP-39 英文测试向量（口令 TREZOR）。
+func TestMnemonicSeed(t *testing.T) {
+	phrase := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	want := "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"
+
+	if got := hex.EncodeToString(hdkey.MnemonicSeed(phrase, "TREZOR")); got != want {
+		t.Errorf("seed: %s", got)
+	}
+	// 多余空白不影响结果
+	if got := hex.EncodeToString(hdkey.MnemonicSeed("  "+phrase+"\n", "TREZOR")); got != want {
+		t.Errorf("seed with spaces: %s", got)
+	}
+}
+
+func TestAddress(t *testing.T) {
+	master, _ := hdkey.NewMaster(hdkey.MnemonicSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", ""))
+	k, err := master.Derive("m/44'/0'/0'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Depth() != 3 || k.Index() != hdkey.Hardened {
+		t.Errorf("depth %d, index %x", k.Depth(), k.Index())
+	}
+	addr := k.Address()
+	if !bytes.Equal(addr, paddr.Hash(k.PublicKey(), nil)) {
+		t.Error("address differs from paddr.Hash")
+	}
+	pkh, prefix, err := paddr.Decode(k.Encode("cx"))
+	if err != nil || prefix != "cx" || !bytes.Equal(pkh, addr) {
+		t.Errorf("encode: %x %q %v", pkh, prefix, err)
+	}
+	msg := []byte("hello")
+	sig, _ := k.PrivateKey().Sign(msg)
+
+	if other, _ := master.Derive("m/44'/0'/1'"); bytes.Equal(other.PublicKey(), k.PublicKey()) {
+		t.Error("sibling keys equal")
+	}
+	if len(sig) != 64 {
+		t.Errorf("signature size %d", len(sig))
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := hdkey.NewMaster(make([]byte, 15)); !errors.Is(err, hdkey.ErrSeedSize) {
+		t.Errorf("short seed: %v", err)
+	}
+	master, _ := hdkey.NewMaster(make([]byte, 32))
+
+	if _, err := master.Derive("m/44'/0"); !errors.Is(err, hdkey.ErrNotHardened) {
+		t.Errorf("normal index: %v", err)
+	}
+	for _, p := range []string{"", "m/", "m/x'", "m/2147483648'", "m/-1'"} {
+		if _, err := master.Derive(p); !errors.Is(err, hdkey.ErrPath) {
+			t.Errorf("%q: %v", p, err)
+		}
+	}
+}
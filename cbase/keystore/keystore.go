@@ -0,0 +1,516 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+// Package keystore 加密的签名私钥存储。
+// 每个私钥存为目录中的一个文件，私钥种子由口令经 Argon2id 派生的密钥以
+// XChaCha20-Poly1305 加密，方案版本和公钥作为附加数据参与认证。
+// 私钥以公钥地址（paddr.PKAddr）为索引，也可通过账户地址（paddr.Encode 的文本）查找。
+// 解锁后的私钥仅驻留内存，可随时锁定。
+package keystore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/cxio/suite/cbase/paddr"
+	"github.com/cxio/suite/cbase/sigs"
+	"github.com/cxio/suite/locale"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// 本地化文本获取。
+var _T = locale.GetText
+
+const (
+	// 文件格式版本。
+	FormatVersion = 1
+
+	// 私钥文件扩展名。
+	fileExt = ".key"
+
+	// 私钥种子长度。
+	seedSize = 32
+
+	// 盐值长度。
+	saltSize = 16
+)
+
+var (
+	// 私钥不存在。
+	ErrNotFound = errors.New(_T("私钥不存在"))
+
+	// 私钥已存在。
+	ErrExists = errors.New(_T("私钥已存在"))
+
+	// 私钥未解锁。
+	ErrLocked = errors.New(_T("私钥未解锁"))
+
+	// 口令错误（或数据被篡改）。
+	ErrPassword = errors.New(_T("口令错误或数据已损坏"))
+
+	// 私钥文件格式无效。
+	ErrFormat = errors.New(_T("私钥文件格式无效"))
+)
+
+// 口令派生参数（Argon2id）。
+// 参数随私钥文件保存，修改默认值不影响已有的文件。
+type KDF struct {
+	Time    uint32 `json:"time"`    // 迭代次数
+	Memory  uint32 `json:"memory"`  // 内存（KiB）
+	Threads uint8  `json:"threads"` // 并行度
+}
+
+var (
+	// 默认参数。
+	DefaultKDF = KDF{Time: 3, Memory: 64 << 10, Threads: 4}
+
+	// 轻量参数。
+	// 仅用于测试或资源受限的场合，抗暴力破解能力较弱。
+	LightKDF = KDF{Time: 1, Memory: 1 << 10, Threads: 1}
+)
+
+// 派生参数上限。
+// 参数来自私钥文件（可能为外部导入），派生前检查，
+// 防止构造的文件耗尽内存或计算资源。
+const (
+	MaxKDFTime   = 16      // 迭代次数
+	MaxKDFMemory = 1 << 20 // 内存（KiB），即 1 GiB
+)
+
+// 派生加密密钥。
+func (k KDF) key(password string, salt []byte) []byte {
+	return argon2.IDKey([]byte(password), salt, k.Time, k.Memory, k.Threads, chacha20poly1305.KeySize)
+}
+
+// 有效性检查。
+// 并行度的上限即 uint8 的范围（255）。
+func (k KDF) valid() bool {
+	return k.Time > 0 && k.Time <= MaxKDFTime &&
+		k.Threads > 0 &&
+		k.Memory >= 8*uint32(k.Threads) && k.Memory <= MaxKDFMemory
+}
+
+// 私钥文件。
+// 字节序列皆为十六进制文本。
+type keyFile struct {
+	Version    int      `json:"version"`
+	Scheme     int      `json:"scheme"`
+	Public     hexBytes `json:"public"`
+	KDF        KDF      `json:"kdf"`
+	Salt       hexBytes `json:"salt"`
+	Nonce      hexBytes `json:"nonce"`
+	Ciphertext hexBytes `json:"ciphertext"`
+	addr       paddr.PKAddr
+}
+
+// 十六进制编码的字节序列。
+type hexBytes []byte
+
+func (b hexBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(b)), nil
+}
+
+func (b *hexBytes) UnmarshalText(text []byte) (err error) {
+	*b, err = hex.DecodeString(string(text))
+	return
+}
+
+// 附加认证数据。
+// 方案版本和公钥，防止被替换。
+func (f *keyFile) aad() []byte {
+	return fmt.Appendf(nil, "cxio/keystore/%d/%d/%x", f.Version, f.Scheme, f.Public)
+}
+
+// 加密私钥种子。
+func seal(ver int, seed []byte, password string, kdf KDF) (*keyFile, error) {
+	s, err := sigs.Get(ver)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := s.NewKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	f := &keyFile{
+		Version: FormatVersion,
+		Scheme:  ver,
+		Public:  pk.Public(),
+		KDF:     kdf,
+		Salt:    make([]byte, saltSize),
+		Nonce:   make([]byte, chacha20poly1305.NonceSizeX),
+		addr:    s.Address(pk.Public()),
+	}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return nil, err
+	}
+	aead, _ := chacha20poly1305.NewX(kdf.key(password, f.Salt))
+	f.Ciphertext = aead.Seal(nil, f.Nonce, seed, f.aad())
+
+	return f, nil
+}
+
+// 解密私钥。
+// 同时检查解密出的私钥与公钥一致。
+func (f *keyFile) open(password string) (sigs.PrivateKey, []byte, error) {
+	aead, _ := chacha20poly1305.NewX(f.KDF.key(password, f.Salt))
+
+	seed, err := aead.Open(nil, f.Nonce, f.Ciphertext, f.aad())
+	if err != nil {
+		return nil, nil, ErrPassword
+	}
+	s, err := sigs.Get(f.Scheme)
+	if err != nil {
+		return nil, nil, err
+	}
+	pk, err := s.NewKey(seed)
+	if err != nil || !bytes.Equal(pk.Public(), f.Public) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrFormat, _T("公钥不匹配"))
+	}
+	return pk, seed, nil
+}
+
+// 检查口令。
+func (f *keyFile) check(password string) error {
+	_, seed, err := f.open(password)
+	clear(seed)
+	return err
+}
+
+// 解析私钥文件。
+func parseKeyFile(data []byte) (*keyFile, error) {
+	f := new(keyFile)
+
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	if f.Version != FormatVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrFormat, f.Version)
+	}
+	if !f.KDF.valid() || len(f.Salt) != saltSize || len(f.Nonce) != chacha20poly1305.NonceSizeX {
+		return nil, fmt.Errorf("%w: %s", ErrFormat, _T("加密参数无效"))
+	}
+	s, err := sigs.Get(f.Scheme)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	if err := s.CheckKey(f.Public); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	f.addr = s.Address(f.Public)
+
+	return f, nil
+}
+
+//
+// 存储库
+///////////////////////////////////////////////////////////////////////////////
+
+// 私钥存储库。
+// 并发安全。
+type Store struct {
+	dir    string
+	kdf    KDF
+	mu     sync.RWMutex
+	files  map[string]*keyFile        // 键：公钥地址（string）
+	unlock map[string]sigs.PrivateKey // 已解锁的私钥
+}
+
+// 打开存储库。
+// dir 为存储目录，不存在时创建。kdf 为新建或修改口令时采用的派生参数。
+// 目录中格式无效的私钥文件会导致出错。
+func Open(dir string, kdf KDF) (*Store, error) {
+	if !kdf.valid() {
+		return nil, fmt.Errorf("%w: %s", ErrFormat, _T("加密参数无效"))
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*"+fileExt))
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{
+		dir:    dir,
+		kdf:    kdf,
+		files:  make(map[string]*keyFile),
+		unlock: make(map[string]sigs.PrivateKey),
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parseKeyFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(name), err)
+		}
+		s.files[string(f.addr)] = f
+	}
+	return s, nil
+}
+
+// 存储目录。
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// 添加私钥。
+// ver 为签名方案版本（见 sigs 包），seed 为32字节私钥种子（如由 hdkey 派生）。
+// 返回公钥地址。
+func (s *Store) Add(ver int, seed []byte, password string) (paddr.PKAddr, error) {
+	f, err := seal(ver, seed, password, s.kdf)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.insert(f); err != nil {
+		return nil, err
+	}
+	return f.addr, nil
+}
+
+// 生成新私钥。
+// 种子取自系统随机源，返回公钥地址。
+func (s *Store) Generate(ver int, password string) (paddr.PKAddr, error) {
+	seed := make([]byte, seedSize)
+	for {
+		if _, err := rand.Read(seed); err != nil {
+			return nil, err
+		}
+		addr, err := s.Add(ver, seed, password)
+		// 超出曲线阶的种子，概率可忽略
+		if !errors.Is(err, sigs.ErrSeed) {
+			return addr, err
+		}
+	}
+}
+
+// 导入私钥。
+// data 为 Export 导出的加密数据，password 为其口令，导入前先验证。
+// 导入后原样保存，口令不变。
+func (s *Store) Import(data []byte, password string) (paddr.PKAddr, error) {
+	f, err := parseKeyFile(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.check(password); err != nil {
+		return nil, err
+	}
+	if err := s.insert(f); err != nil {
+		return nil, err
+	}
+	return f.addr, nil
+}
+
+// 导出私钥。
+// 返回加密的私钥数据，需提供正确的口令。
+func (s *Store) Export(addr paddr.PKAddr, password string) ([]byte, error) {
+	f, err := s.get(addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.check(password); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(f, "", "\t")
+}
+
+// 修改口令。
+// 以当前的派生参数重新加密，已解锁的状态不变。
+func (s *Store) ChangePassword(addr paddr.PKAddr, old, password string) error {
+	f, err := s.get(addr)
+	if err != nil {
+		return err
+	}
+	_, seed, err := f.open(old)
+	if err != nil {
+		return err
+	}
+	nf, err := seal(f.Scheme, seed, password, s.kdf)
+	clear(seed)
+
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.write(nf); err != nil {
+		return err
+	}
+	s.files[string(addr)] = nf
+	return nil
+}
+
+// 删除私钥。
+// 需提供正确的口令，删除后不可恢复。
+func (s *Store) Delete(addr paddr.PKAddr, password string) error {
+	f, err := s.get(addr)
+	if err != nil {
+		return err
+	}
+	if err := f.check(password); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.path(addr)); err != nil {
+		return err
+	}
+	delete(s.files, string(addr))
+	delete(s.unlock, string(addr))
+	return nil
+}
+
+// 解锁私钥。
+// 解锁后可通过 Signer 签名，直到 Lock 或 LockAll。
+func (s *Store) Unlock(addr paddr.PKAddr, password string) error {
+	f, err := s.get(addr)
+	if err != nil {
+		return err
+	}
+	pk, seed, err := f.open(password)
+	if err != nil {
+		return err
+	}
+	clear(seed)
+
+	s.mu.Lock()
+	s.unlock[string(addr)] = pk
+	s.mu.Unlock()
+
+	return nil
+}
+
+// 锁定私钥。
+func (s *Store) Lock(addr paddr.PKAddr) {
+	s.mu.Lock()
+	delete(s.unlock, string(addr))
+	s.mu.Unlock()
+}
+
+// 锁定全部私钥。
+func (s *Store) LockAll() {
+	s.mu.Lock()
+	clear(s.unlock)
+	s.mu.Unlock()
+}
+
+// 是否已解锁。
+func (s *Store) Unlocked(addr paddr.PKAddr) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.unlock[string(addr)]
+	return ok
+}
+
+// 公钥地址清单。
+// 按字节序排列。
+func (s *Store) Addresses() []paddr.PKAddr {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]paddr.PKAddr, 0, len(s.files))
+	for _, f := range s.files {
+		list = append(list, f.addr)
+	}
+	slices.SortFunc(list, func(a, b paddr.PKAddr) int { return bytes.Compare(a, b) })
+
+	return list
+}
+
+// 按账户地址查找。
+// account 为带前缀的账户地址文本（见 paddr.Encode），返回对应的公钥地址。
+func (s *Store) Find(account string) (paddr.PKAddr, error) {
+	pkh, _, err := paddr.Decode(account)
+	if err != nil {
+		return nil, err
+	}
+	f, err := s.get(pkh)
+	if err != nil {
+		return nil, err
+	}
+	return f.addr, nil
+}
+
+// 获取签名器。
+// 私钥需已解锁，签名器在私钥锁定后失效（签名返回 ErrLocked）。
+func (s *Store) Signer(addr paddr.PKAddr) (Signer, error) {
+	f, err := s.get(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !s.Unlocked(addr) {
+		return nil, ErrLocked
+	}
+	return &signer{store: s, file: f}, nil
+}
+
+// 获取私钥文件。
+func (s *Store) get(addr []byte) (*keyFile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f := s.files[string(addr)]
+	if f == nil {
+		return nil, fmt.Errorf("%w: %x", ErrNotFound, addr)
+	}
+	return f, nil
+}
+
+// 添加私钥文件。
+func (s *Store) insert(f *keyFile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.files[string(f.addr)] != nil {
+		return fmt.Errorf("%w: %x", ErrExists, []byte(f.addr))
+	}
+	if err := s.write(f); err != nil {
+		return err
+	}
+	s.files[string(f.addr)] = f
+	return nil
+}
+
+// 私钥文件路径。
+func (s *Store) path(addr []byte) string {
+	return filepath.Join(s.dir, hex.EncodeToString(addr)+fileExt)
+}
+
+// 写入私钥文件。
+// 先写临时文件再改名，避免中断时损坏原文件。
+// 注：调用者需持有锁。
+func (s *Store) write(f *keyFile) error {
+	data, err := json.MarshalIndent(f, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(f.addr))
+}
@@ -0,0 +1,173 @@
+package keystore_test
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/cxio/suite/cbase"
+	"github.com/cxio/suite/cbase/keystore"
+	"github.com/cxio/suite/cbase/paddr"
+	"github.com/cxio/suite/cbase/sigs"
+	"github.com/cxio/suite/cbase/tx"
+	"github.com/cxio/suite/script/instor"
+)
+
+func open(t *testing.T, dir string) *keystore.Store {
+	t.Helper()
+	s, err := keystore.Open(dir, keystore.LightKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s := open(t, dir)
+
+	addr, err := s.Generate(sigs.ECDSAP256, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := bytes.Repeat([]byte{3}, 32)
+	addr2, err := s.Add(sigs.Ed25519, seed, "pass2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(sigs.Ed25519, seed, "x"); !errors.Is(err, keystore.ErrExists) {
+		t.Errorf("duplicate: %v", err)
+	}
+	// 重新打开
+	s = open(t, dir)
+	if got := s.Addresses(); len(got) != 2 {
+		t.Fatalf("addresses: %d", len(got))
+	}
+	found, err := s.Find(paddr.Encode(addr2, "cx"))
+	if err != nil || !bytes.Equal(found, addr2) {
+		t.Errorf("find: %x %v", found, err)
+	}
+	if _, err := s.Signer(addr); !errors.Is(err, keystore.ErrLocked) {
+		t.Errorf("locked signer: %v", err)
+	}
+	if err := s.Unlock(addr, "wrong"); !errors.Is(err, keystore.ErrPassword) {
+		t.Errorf("wrong password: %v", err)
+	}
+	if err := s.Unlock(addr, "pass"); err != nil {
+		t.Fatal(err)
+	}
+	sg, err := s.Signer(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := sg.Sign([]byte("msg"))
+	if err != nil || !sigs.Verify(sg.Scheme(), sg.PublicKey(), []byte("msg"), sig) {
+		t.Errorf("sign: %v", err)
+	}
+	if !bytes.Equal(sg.Address(), addr) {
+		t.Error("signer address")
+	}
+	s.LockAll()
+	if _, err := sg.Sign([]byte("msg")); !errors.Is(err, keystore.ErrLocked) {
+		t.Errorf("sign after lock: %v", err)
+	}
+}
+
+func TestPassword(t *testing.T) {
+	s := open(t, t.TempDir())
+	addr, _ := s.Generate(sigs.Schnorr25519, "old")
+
+	if err := s.ChangePassword(addr, "bad", "new"); !errors.Is(err, keystore.ErrPassword) {
+		t.Errorf("bad old password: %v", err)
+	}
+	if err := s.ChangePassword(addr, "old", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Unlock(addr, "old"); !errors.Is(err, keystore.ErrPassword) {
+		t.Errorf("old password accepted: %v", err)
+	}
+	if err := s.Unlock(addr, "new"); err != nil {
+		t.Error(err)
+	}
+	// 导出后导入另一存储库
+	data, err := s.Export(addr, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := open(t, t.TempDir())
+	if _, err := other.Import(data, "old"); !errors.Is(err, keystore.ErrPassword) {
+		t.Errorf("import with bad password: %v", err)
+	}
+	got, err := other.Import(data, "new")
+	if err != nil || !bytes.Equal(got, addr) {
+		t.Fatalf("import: %x %v", got, err)
+	}
+	// 篡改密文
+	bad := bytes.Replace(data, []byte(`"ciphertext": "`), []byte(`"ciphertext": "00`), 1)
+	if _, err := open(t, t.TempDir()).Import(bad, "new"); !errors.Is(err, keystore.ErrPassword) {
+		t.Errorf("tampered: %v", err)
+	}
+	if err := s.Delete(addr, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Unlocked(addr) || len(open(t, s.Dir()).Addresses()) != 0 {
+		t.Error("key not deleted")
+	}
+}
+
+// 派生参数超出上限的文件在派生之前被拒绝。
+func TestKDFLimit(t *testing.T) {
+	s := open(t, t.TempDir())
+	addr, _ := s.Generate(sigs.Ed25519, "pass")
+
+	data, err := s.Export(addr, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kdf := range []string{
+		`"kdf": {"time": 1, "memory": 4294967295, "threads": 1}`,
+		`"kdf": {"time": 4294967295, "memory": 1024, "threads": 1}`,
+		`"kdf": {"time": 1, "memory": 1024, "threads": 0}`,
+	} {
+		bad := kdfRe.ReplaceAll(data, []byte(kdf))
+		if bytes.Equal(bad, data) {
+			t.Fatal("kdf block not found")
+		}
+		if _, err := open(t, t.TempDir()).Import(bad, "pass"); !errors.Is(err, keystore.ErrFormat) {
+			t.Errorf("%s: %v", kdf, err)
+		}
+	}
+	if _, err := keystore.Open(t.TempDir(), keystore.KDF{Time: 1, Memory: 8 << 20, Threads: 1}); !errors.Is(err, keystore.ErrFormat) {
+		t.Errorf("open with oversized kdf: %v", err)
+	}
+}
+
+// 私钥文件中的派生参数块。
+var kdfRe = regexp.MustCompile(`"kdf": \{[^}]*\}`)
+
+func TestSignTx(t *testing.T) {
+	s := open(t, t.TempDir())
+	addr, _ := s.Generate(sigs.Ed25519, "p")
+	s.Unlock(addr, "p")
+	sg, _ := s.Signer(addr)
+
+	h, b, err := tx.NewBuilder(1, 1700000000000).
+		Minter(addr, 0, nil).
+		Input(100, 2, 0).
+		Coin(addr, 500, nil).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := cbase.KeyID(100, 2, 0)
+	sig, err := keystore.SignTx(sg, id, h, b, 0, instor.SigAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := tx.SigHash(sigs.Ed25519, id, h, b, 0, instor.SigAll)
+
+	if !sigs.Verify(sigs.Ed25519, sg.PublicKey(), msg, sig) {
+		t.Error("transaction signature invalid")
+	}
+}
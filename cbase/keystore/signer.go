@@ -0,0 +1,61 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package keystore
+
+import (
+	"github.com/cxio/suite/cbase/paddr"
+	"github.com/cxio/suite/cbase/tx"
+)
+
+// 签名器。
+// 交易签名所需的最小接口，私钥本身不外露。
+type Signer interface {
+	// 签名方案版本，即对应脚本的版本（Actuator.Ver）。
+	Scheme() int
+
+	// 公钥。
+	PublicKey() []byte
+
+	// 公钥地址。
+	Address() paddr.PKAddr
+
+	// 签名消息。
+	Sign(msg []byte) ([]byte, error)
+}
+
+// 存储库私钥的签名器。
+// 每次签名时检查解锁状态，锁定后即失效。
+type signer struct {
+	store *Store
+	file  *keyFile
+}
+
+func (s *signer) Scheme() int { return s.file.Scheme }
+
+func (s *signer) PublicKey() []byte { return s.file.Public }
+
+func (s *signer) Address() paddr.PKAddr { return s.file.addr }
+
+func (s *signer) Sign(msg []byte) ([]byte, error) {
+	s.store.mu.RLock()
+	pk := s.store.unlock[string(s.file.addr)]
+	s.store.mu.RUnlock()
+
+	if pk == nil {
+		return nil, ErrLocked
+	}
+	return pk.Sign(msg)
+}
+
+// 签名交易输入。
+// 以签名器的方案版本构造签名消息（见 tx.SigHash）并签名，
+// 参数 id, h, b, in, flag 同 tx.SigHash。
+// 返回的签名与公钥一起作为解锁数据，供脚本中的 FN_CHECKSIG 验证。
+func SignTx(s Signer, id []byte, h *tx.Header, b *tx.Body, in, flag int) ([]byte, error) {
+	msg, err := tx.SigHash(s.Scheme(), id, h, b, in, flag)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sign(msg)
+}
@@ -0,0 +1,226 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package paddr
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//
+// 多重签名账户
+// 描述有序的成员清单和签名门限（n/T），构造多重签名地址，
+// 并为指定的签名者生成 MulHash 所需的 pks/pkhs 两个集合。
+//
+// 文本描述格式：
+//
+//	multi(n,key1,key2,...)
+//
+// 成员依序排列，每项为十六进制的公钥，或公钥地址（HashSize 字节，仅知哈希的成员）。
+///////////////////////////////////////////////////////////////////////////////
+
+// 描述格式的前后缀。
+const (
+	descPrefix = "multi("
+	descSuffix = ")"
+)
+
+var (
+	// 签名门限无效。
+	ErrMSigThreshold = errors.New(_T("多重签名门限无效（1 <= n <= T）"))
+
+	// 签名者无效。
+	ErrMSigSigner = errors.New(_T("多重签名的签名者无效"))
+
+	// 多重签名地址无效。
+	ErrMSigAddr = errors.New(_T("无效的多重签名地址"))
+
+	// 描述格式无效。
+	ErrMSigDesc = errors.New(_T("多重签名描述格式无效"))
+)
+
+// 多重签名账户。
+type MultiSigAccount struct {
+	n    int
+	keys [][]byte // 公钥或公钥地址
+}
+
+// 创建多重签名账户。
+// n 为最少签名数量，keys 为有序的成员清单，
+// 每项为公钥，或长度为 HashSize 的公钥地址（仅知哈希的成员）。
+// 成员顺序决定其序位，因而影响账户地址。
+func NewMultiSig(n int, keys ...[]byte) (*MultiSigAccount, error) {
+	t := len(keys)
+
+	if t > MulSigMaxN {
+		return nil, ErrMSigSize
+	}
+	if n < 1 || n > t {
+		return nil, fmt.Errorf("%w: %d/%d", ErrMSigThreshold, n, t)
+	}
+	m := &MultiSigAccount{n: n, keys: make([][]byte, t)}
+
+	for i, k := range keys {
+		if len(k) == 0 {
+			return nil, fmt.Errorf("%w: %s %d", ErrMSigDesc, _T("空成员"), i)
+		}
+		m.keys[i] = bytes.Clone(k)
+	}
+	return m, nil
+}
+
+// 最少签名数量。
+func (m *MultiSigAccount) N() int {
+	return m.n
+}
+
+// 成员总数。
+func (m *MultiSigAccount) T() int {
+	return len(m.keys)
+}
+
+// 成员的公钥。
+// 仅知公钥地址的成员返回nil。
+func (m *MultiSigAccount) PubKey(i int) []byte {
+	if k := m.keys[i]; len(k) != HashSize {
+		return k
+	}
+	return nil
+}
+
+// 成员的公钥地址。
+func (m *MultiSigAccount) KeyHash(i int) PKAddr {
+	k := m.keys[i]
+
+	if len(k) == HashSize {
+		return PKAddr(k)
+	}
+	return Hash(k, nil)
+}
+
+// 查找公钥的序位。
+// 未找到时返回-1。
+func (m *MultiSigAccount) Index(pubkey []byte) int {
+	for i, k := range m.keys {
+		if bytes.Equal(k, pubkey) || (len(k) == HashSize && bytes.Equal(k, Hash(pubkey, nil))) {
+			return i
+		}
+	}
+	return -1
+}
+
+// 多重签名地址。
+// 即前置 n/T 配比的22字节公钥地址。
+func (m *MultiSigAccount) Address() (PKAddr, error) {
+	pkhs := make([][]byte, len(m.keys))
+
+	for i := range m.keys {
+		pkhs[i] = m.KeyHash(i)
+	}
+	return hashMPKH(pkhs, m.n)
+}
+
+// 拆分签名集合。
+// signers 为签名成员的序位，数量需等于 n，且成员的公钥已知。
+// 返回 MulHash 所需的签名公钥集和未签名公钥地址集，成员皆前置1字节序位。
+// pks 按 signers 的顺序排列，以便与签名集一一对应，pkhs 按序位排列。
+func (m *MultiSigAccount) Split(signers ...int) (pks, pkhs [][]byte, err error) {
+	if len(signers) != m.n {
+		return nil, nil, fmt.Errorf("%w: %d signers for %d/%d", ErrMSigSigner, len(signers), m.n, len(m.keys))
+	}
+	used := make([]bool, len(m.keys))
+
+	for _, i := range signers {
+		if i < 0 || i >= len(m.keys) || used[i] {
+			return nil, nil, fmt.Errorf("%w: %d", ErrMSigSigner, i)
+		}
+		pk := m.PubKey(i)
+		if pk == nil {
+			return nil, nil, fmt.Errorf("%w: %s %d", ErrMSigSigner, _T("公钥未知"), i)
+		}
+		used[i] = true
+		pks = append(pks, append([]byte{byte(i)}, pk...))
+	}
+	for i, ok := range used {
+		if !ok {
+			pkhs = append(pkhs, append([]byte{byte(i)}, m.KeyHash(i)...))
+		}
+	}
+	return pks, pkhs, nil
+}
+
+// 文本描述。
+func (m *MultiSigAccount) String() string {
+	var b strings.Builder
+
+	b.WriteString(descPrefix)
+	b.WriteString(strconv.Itoa(m.n))
+
+	for _, k := range m.keys {
+		b.WriteByte(',')
+		b.WriteString(hex.EncodeToString(k))
+	}
+	b.WriteString(descSuffix)
+
+	return b.String()
+}
+
+// 编码为文本描述。
+func (m *MultiSigAccount) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// 从文本描述解码。
+func (m *MultiSigAccount) UnmarshalText(text []byte) error {
+	v, err := ParseMultiSig(string(text))
+	if err != nil {
+		return err
+	}
+	*m = *v
+	return nil
+}
+
+// 解析文本描述。
+// 格式见 MultiSigAccount.String，首尾空白被忽略。
+func ParseMultiSig(s string) (*MultiSigAccount, error) {
+	body, ok := strings.CutPrefix(strings.TrimSpace(s), descPrefix)
+	if ok {
+		body, ok = strings.CutSuffix(body, descSuffix)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrMSigDesc, s)
+	}
+	parts := strings.Split(body, ",")
+
+	n, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMSigDesc, err)
+	}
+	keys := make([][]byte, len(parts)-1)
+
+	for i, p := range parts[1:] {
+		if keys[i], err = hex.DecodeString(p); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMSigDesc, err)
+		}
+	}
+	return NewMultiSig(n, keys...)
+}
+
+// 提取多重签名地址的 n/T 配比。
+// addr 为22字节的多重签名公钥地址，其前2字节即明码的配比。
+func MulRatio(addr PKAddr) (n, t int, err error) {
+	if len(addr) != HashSize+2 {
+		return 0, 0, fmt.Errorf("%w: %d bytes", ErrMSigAddr, len(addr))
+	}
+	n, t = int(addr[0]), int(addr[1])
+
+	if n < 1 || n > t {
+		return 0, 0, fmt.Errorf("%w: %d/%d", ErrMSigAddr, n, t)
+	}
+	return n, t, nil
+}
@@ -0,0 +1,77 @@
+package paddr_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cxio/suite/cbase/paddr"
+)
+
+func TestMultiSig(t *testing.T) {
+	pk1 := bytes.Repeat([]byte{1}, 32)
+	pk2 := bytes.Repeat([]byte{2}, 33)
+	pk3 := bytes.Repeat([]byte{3}, 32)
+
+	// 第三个成员仅知公钥地址
+	m, err := paddr.NewMultiSig(2, pk1, pk2, paddr.Hash(pk3, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := m.Address()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, tt, err := paddr.MulRatio(addr); err != nil || n != 2 || tt != 3 {
+		t.Errorf("ratio: %d/%d %v", n, tt, err)
+	}
+	pks, pkhs, err := m.Split(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pks[0][0] != 1 || pkhs[0][0] != 2 {
+		t.Errorf("positions: %d, %d", pks[0][0], pkhs[0][0])
+	}
+	got, err := paddr.MulHash(pks, pkhs)
+	if err != nil || !bytes.Equal(got, addr) {
+		t.Errorf("MulHash: %x, want %x (%v)", got, addr, err)
+	}
+	if m.Index(pk3) != 2 || m.Index(pk2) != 1 || m.Index([]byte("x")) != -1 {
+		t.Error("Index")
+	}
+	// 描述往返
+	m2, err := paddr.ParseMultiSig(m.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m2.String() != m.String() {
+		t.Errorf("round trip: %s", m2)
+	}
+	if a2, _ := m2.Address(); !bytes.Equal(a2, addr) {
+		t.Error("round trip address")
+	}
+}
+
+func TestMultiSigErrors(t *testing.T) {
+	pk := bytes.Repeat([]byte{1}, 32)
+	pkh := paddr.Hash(pk, nil)
+
+	if _, err := paddr.NewMultiSig(3, pk, pk); !errors.Is(err, paddr.ErrMSigThreshold) {
+		t.Errorf("threshold: %v", err)
+	}
+	m, _ := paddr.NewMultiSig(1, pk, pkh)
+
+	for _, signers := range [][]int{{}, {0, 1}, {2}, {1}} {
+		if _, _, err := m.Split(signers...); !errors.Is(err, paddr.ErrMSigSigner) {
+			t.Errorf("%v: %v", signers, err)
+		}
+	}
+	for _, s := range []string{"", "multi(1)", "multi(x,00)", "multi(1,zz)", "multi(1,00"} {
+		if _, err := paddr.ParseMultiSig(s); err == nil {
+			t.Errorf("%q accepted", s)
+		}
+	}
+	if _, _, err := paddr.MulRatio(pkh); !errors.Is(err, paddr.ErrMSigAddr) {
+		t.Errorf("single address: %v", err)
+	}
+}
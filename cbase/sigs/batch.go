@@ -0,0 +1,182 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package sigs
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+//
+// 批量验证
+// 收集多个交易输入（或整个区块）的签名条目，由工作池并行验证。
+// 注：
+// 未采用随机线性组合的批量方程（曲线点运算已可由 edwards25519 包提供）：
+// 批量方程需乘以余因子，而单条验证（ed25519.Verify 及本包的 Schnorr）不乘，
+// 对构造的含小阶分量的签名两者结论可能不同，作为共识规则不可接受。
+// 因此采用并行逐条验证，结论与 Verify 完全一致，失败时可直接定位到条目。
+///////////////////////////////////////////////////////////////////////////////
+
+// 小于此数量的批次直接顺序验证，并行的调度开销不值得。
+const batchSerial = 4
+
+// 同消息多签名（VerifyAll）直接顺序验证的数量上限。
+// 多重签名验证发生在脚本执行中，脚本通常已由外层并行执行，
+// 为单条指令再建工作池得不偿失，仅数量较大时才并行。
+const multiSerial = 16
+
+// 批量验证失败。
+// 具体的失败条目由 BatchError 给出，可由 errors.Is 检视。
+var ErrBatch = errors.New(_T("批量签名验证失败"))
+
+// 验证条目。
+type Item struct {
+	Ver int    // 方案版本
+	Pub []byte // 公钥
+	Msg []byte // 消息
+	Sig []byte // 签名
+}
+
+// 批量验证错误。
+// Index 为验证失败的条目中序位最小者，与并行调度无关。
+type BatchError struct {
+	Index int
+	Item  Item
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%s: item %d (ver %d)", ErrBatch, e.Index, e.Item.Ver)
+}
+
+func (e *BatchError) Unwrap() error {
+	return ErrBatch
+}
+
+// 批量验证器。
+// 添加条目不是并发安全的，应当在收集完成后调用 Verify。
+type Batch struct {
+	items   []Item
+	workers int
+}
+
+// 新建批量验证器。
+// workers 为并行验证的工作者数量，小于等于零时取 GOMAXPROCS。
+func NewBatch(workers int) *Batch {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &Batch{workers: workers}
+}
+
+// 添加验证条目。
+// 数据直接引用，验证完成前不应修改。
+func (b *Batch) Add(ver int, pub, msg, sig []byte) {
+	b.items = append(b.items, Item{Ver: ver, Pub: pub, Msg: msg, Sig: sig})
+}
+
+// 条目数量。
+func (b *Batch) Len() int {
+	return len(b.items)
+}
+
+// 清空条目，以便复用。
+func (b *Batch) Reset() {
+	clear(b.items)
+	b.items = b.items[:0]
+}
+
+// 执行验证。
+// 全部条目有效时返回nil，否则返回 *BatchError 指明序位最小的失败条目。
+// 未登记的方案版本视为验证失败。
+func (b *Batch) Verify() error {
+	n := len(b.items)
+	w := min(b.workers, n)
+
+	if n < batchSerial || w <= 1 {
+		for i := range b.items {
+			if !verifyItem(&b.items[i]) {
+				return b.fail(i)
+			}
+		}
+		return nil
+	}
+	// 条目按序领取，失败序位之前的条目必然已被领取并完成，
+	// 因此最终记录的是序位最小的失败条目。
+	var (
+		next atomic.Int64
+		fail atomic.Int64
+		wg   sync.WaitGroup
+	)
+	fail.Store(int64(n))
+
+	for range w {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := next.Add(1) - 1
+				if i >= fail.Load() {
+					return
+				}
+				if !verifyItem(&b.items[i]) {
+					storeMin(&fail, i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if i := int(fail.Load()); i < n {
+		return b.fail(i)
+	}
+	return nil
+}
+
+// 创建失败错误。
+func (b *Batch) fail(i int) error {
+	return &BatchError{Index: i, Item: b.items[i]}
+}
+
+// 验证单个条目。
+func verifyItem(it *Item) bool {
+	return Verify(it.Ver, it.Pub, it.Msg, it.Sig)
+}
+
+// 原子存储较小值。
+func storeMin(v *atomic.Int64, n int64) {
+	for {
+		old := v.Load()
+		if n >= old || v.CompareAndSwap(old, n) {
+			return
+		}
+	}
+}
+
+// 同消息多签名验证。
+// 适用于多重签名，pubs 与 ss 一一对应，数量不同时视为失败。
+// 数量不超过 multiSerial 时直接顺序验证，不启用工作池。
+// 返回nil表示全部有效。
+func VerifyAll(ver int, pubs [][]byte, msg []byte, ss [][]byte) error {
+	if len(pubs) != len(ss) {
+		return fmt.Errorf("%w: %d pubkeys, %d signatures", ErrBatch, len(pubs), len(ss))
+	}
+	if len(pubs) <= multiSerial {
+		for i, pk := range pubs {
+			if !Verify(ver, pk, msg, ss[i]) {
+				return &BatchError{Index: i, Item: Item{Ver: ver, Pub: pk, Msg: msg, Sig: ss[i]}}
+			}
+		}
+		return nil
+	}
+	b := NewBatch(0)
+	b.items = make([]Item, 0, len(pubs))
+
+	for i, pk := range pubs {
+		b.Add(ver, pk, msg, ss[i])
+	}
+	return b.Verify()
+}
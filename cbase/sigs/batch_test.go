@@ -0,0 +1,143 @@
+package sigs_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cxio/suite/cbase/sigs"
+)
+
+// 创建 n 个有效条目。
+func batchItems(tb testing.TB, ver, n int) []sigs.Item {
+	s, _ := sigs.Get(ver)
+	items := make([]sigs.Item, n)
+
+	for i := range items {
+		key, err := s.NewKey(bytes.Repeat([]byte{byte(i + 1)}, 32))
+		if err != nil {
+			tb.Fatal(err)
+		}
+		msg := fmt.Appendf(nil, "input %d", i)
+		sig, _ := key.Sign(msg)
+		items[i] = sigs.Item{Ver: ver, Pub: key.Public(), Msg: msg, Sig: sig}
+	}
+	return items
+}
+
+func newBatch(items []sigs.Item) *sigs.Batch {
+	b := sigs.NewBatch(0)
+	for _, it := range items {
+		b.Add(it.Ver, it.Pub, it.Msg, it.Sig)
+	}
+	return b
+}
+
+func TestBatch(t *testing.T) {
+	var items []sigs.Item
+	for _, ver := range versions {
+		items = append(items, batchItems(t, ver, 20)...)
+	}
+	if err := newBatch(items).Verify(); err != nil {
+		t.Fatal(err)
+	}
+	// 多个失败条目，报告序位最小者
+	for _, bad := range []int{0, 7, 33, len(items) - 1} {
+		xs := append([]sigs.Item(nil), items...)
+		for _, i := range []int{len(xs) - 1, bad} {
+			xs[i].Msg = []byte("forged")
+		}
+		var be *sigs.BatchError
+		err := newBatch(xs).Verify()
+
+		if !errors.As(err, &be) || !errors.Is(err, sigs.ErrBatch) {
+			t.Fatalf("bad %d: %v", bad, err)
+		}
+		if be.Index != bad {
+			t.Errorf("bad %d: got index %d", bad, be.Index)
+		}
+	}
+}
+
+func TestBatchSmall(t *testing.T) {
+	items := batchItems(t, sigs.Ed25519, 2)
+	items[1].Ver = 99
+
+	var be *sigs.BatchError
+	if err := newBatch(items).Verify(); !errors.As(err, &be) || be.Index != 1 {
+		t.Errorf("unknown version: %v", err)
+	}
+	if err := sigs.NewBatch(1).Verify(); err != nil {
+		t.Errorf("empty batch: %v", err)
+	}
+}
+
+func TestVerifyAll(t *testing.T) {
+	s, _ := sigs.Get(sigs.Schnorr25519)
+	msg := []byte("multisig")
+
+	var pubs, ss [][]byte
+	for i := range 5 {
+		key, _ := s.NewKey(bytes.Repeat([]byte{byte(i + 9)}, 32))
+		sig, _ := key.Sign(msg)
+		pubs = append(pubs, key.Public())
+		ss = append(ss, sig)
+	}
+	if err := sigs.VerifyAll(sigs.Schnorr25519, pubs, msg, ss); err != nil {
+		t.Fatal(err)
+	}
+	if err := sigs.VerifyAll(sigs.Schnorr25519, pubs, msg, ss[:4]); !errors.Is(err, sigs.ErrBatch) {
+		t.Errorf("count mismatch: %v", err)
+	}
+	ss[2], ss[3] = ss[3], ss[2]
+
+	var be *sigs.BatchError
+	if err := sigs.VerifyAll(sigs.Schnorr25519, pubs, msg, ss); !errors.As(err, &be) || be.Index != 2 {
+		t.Errorf("swapped signatures: %v", err)
+	}
+}
+
+// 大数量的多签名经由工作池验证，结论与顺序验证相同。
+func TestVerifyAllLarge(t *testing.T) {
+	s, _ := sigs.Get(sigs.Ed25519)
+	msg := []byte("multisig")
+
+	var pubs, ss [][]byte
+	for i := range 40 {
+		key, _ := s.NewKey(bytes.Repeat([]byte{byte(i + 1)}, 32))
+		sig, _ := key.Sign(msg)
+		pubs = append(pubs, key.Public())
+		ss = append(ss, sig)
+	}
+	if err := sigs.VerifyAll(sigs.Ed25519, pubs, msg, ss); err != nil {
+		t.Fatal(err)
+	}
+	ss[30] = ss[31]
+
+	var be *sigs.BatchError
+	if err := sigs.VerifyAll(sigs.Ed25519, pubs, msg, ss); !errors.As(err, &be) || be.Index != 30 {
+		t.Errorf("bad signature: %v", err)
+	}
+}
+
+// 对比：逐条顺序验证。
+func BenchmarkLoop(b *testing.B) {
+	items := batchItems(b, sigs.Ed25519, 256)
+	for b.Loop() {
+		for _, it := range items {
+			if !sigs.Verify(it.Ver, it.Pub, it.Msg, it.Sig) {
+				b.Fatal("invalid")
+			}
+		}
+	}
+}
+
+func BenchmarkBatch(b *testing.B) {
+	batch := newBatch(batchItems(b, sigs.Ed25519, 256))
+	for b.Loop() {
+		if err := batch.Verify(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
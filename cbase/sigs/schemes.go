@@ -0,0 +1,259 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package sigs
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"math/big"
+
+	"filippo.io/edwards25519"
+	"github.com/cxio/suite/cbase/paddr"
+)
+
+//
+// Ed25519
+///////////////////////////////////////////////////////////////////////////////
+
+type edScheme struct{}
+
+type edKey ed25519.PrivateKey
+
+func (edScheme) Name() string { return "ed25519" }
+
+func (edScheme) NewKey(seed []byte) (PrivateKey, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, ErrSeed
+	}
+	return edKey(ed25519.NewKeyFromSeed(seed)), nil
+}
+
+func (edScheme) CheckKey(pub []byte) error {
+	if len(pub) != ed25519.PublicKeySize {
+		return ErrPublicKey
+	}
+	return nil
+}
+
+func (edScheme) Verify(pub, msg, sig []byte) bool {
+	if len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(pub, msg, sig)
+}
+
+func (edScheme) Address(pub []byte) paddr.PKAddr { return address(pub) }
+
+func (k edKey) Public() []byte {
+	return []byte(ed25519.PrivateKey(k).Public().(ed25519.PublicKey))
+}
+
+func (k edKey) Sign(msg []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(k), msg), nil
+}
+
+//
+// ECDSA P-256
+// 签名为 r||s 定长编码，s 规范为低值（<= N/2），避免签名延展性。
+// 涉及私钥的运算皆经由 crypto/ecdh 和 crypto/ecdsa（常量时间实现）。
+///////////////////////////////////////////////////////////////////////////////
+
+// 标量长度。
+const scalarSize = 32
+
+var (
+	p256  = elliptic.P256()
+	order = p256.Params().N
+	half  = new(big.Int).Rsh(order, 1)
+)
+
+type ecdsaScheme struct{}
+
+type ecdsaKey struct {
+	priv *ecdsa.PrivateKey
+	pub  []byte // 压缩编码
+}
+
+func (ecdsaScheme) Name() string { return "ecdsa-p256" }
+
+// 由种子创建私钥。
+// 种子即标量，需在 [1, N) 范围内。
+func (ecdsaScheme) NewKey(seed []byte) (PrivateKey, error) {
+	if len(seed) != scalarSize {
+		return nil, ErrSeed
+	}
+	k, err := ecdh.P256().NewPrivateKey(seed)
+	if err != nil {
+		return nil, ErrSeed
+	}
+	// 非压缩编码：0x04||X||Y
+	b := k.PublicKey().Bytes()
+	x := new(big.Int).SetBytes(b[1 : 1+scalarSize])
+	y := new(big.Int).SetBytes(b[1+scalarSize:])
+
+	priv := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: p256, X: x, Y: y},
+		D:         new(big.Int).SetBytes(seed),
+	}
+	return ecdsaKey{priv, elliptic.MarshalCompressed(p256, x, y)}, nil
+}
+
+func (ecdsaScheme) CheckKey(pub []byte) error {
+	if _, _, ok := parsePoint(pub); !ok {
+		return ErrPublicKey
+	}
+	return nil
+}
+
+func (ecdsaScheme) Verify(pub, msg, sig []byte) bool {
+	x, y, ok := parsePoint(pub)
+	if !ok || len(sig) != 2*scalarSize {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:scalarSize])
+	s := new(big.Int).SetBytes(sig[scalarSize:])
+
+	if s.Cmp(half) > 0 {
+		return false
+	}
+	h := sha256.Sum256(msg)
+	return ecdsa.Verify(&ecdsa.PublicKey{Curve: p256, X: x, Y: y}, h[:], r, s)
+}
+
+func (ecdsaScheme) Address(pub []byte) paddr.PKAddr { return address(pub) }
+
+func (k ecdsaKey) Public() []byte {
+	return k.pub
+}
+
+func (k ecdsaKey) Sign(msg []byte) ([]byte, error) {
+	h := sha256.Sum256(msg)
+
+	r, s, err := ecdsa.Sign(rand.Reader, k.priv, h[:])
+	if err != nil {
+		return nil, err
+	}
+	if s.Cmp(half) > 0 {
+		s.Sub(order, s)
+	}
+	return append(scalarBytes(r), scalarBytes(s)...), nil
+}
+
+// 解析压缩公钥。
+func parsePoint(pub []byte) (x, y *big.Int, ok bool) {
+	if len(pub) != 1+scalarSize {
+		return nil, nil, false
+	}
+	x, y = elliptic.UnmarshalCompressed(p256, pub)
+	return x, y, x != nil
+}
+
+// 标量的定长编码。
+func scalarBytes(n *big.Int) []byte {
+	return n.FillBytes(make([]byte, scalarSize))
+}
+
+//
+// Schnorr edwards25519
+// 签名：R = kG，e = H(R||P||m)，s = k + e·d（模 l），签名为 R||s（各32字节）。
+// 验证：sG == R + eP（R 需为规范编码）。
+// 私钥 d = H(seed)，随机数 k = H(d||H(m))，确定性生成，不依赖外部随机源。
+// H 为 SHA-512 归约到标量。群运算由 edwards25519 包提供，签名路径为常量时间。
+///////////////////////////////////////////////////////////////////////////////
+
+// 编码长度（点和标量）。
+const edSize = 32
+
+type schnorrScheme struct{}
+
+type schnorrKey struct {
+	d   *edwards25519.Scalar
+	pub []byte
+}
+
+func (schnorrScheme) Name() string { return "schnorr-25519" }
+
+func (schnorrScheme) NewKey(seed []byte) (PrivateKey, error) {
+	if len(seed) != edSize {
+		return nil, ErrSeed
+	}
+	d := hashScalar(seed)
+
+	if d.Equal(edwards25519.NewScalar()) == 1 {
+		return nil, ErrSeed // 概率可忽略
+	}
+	p := new(edwards25519.Point).ScalarBaseMult(d)
+
+	return schnorrKey{d: d, pub: p.Bytes()}, nil
+}
+
+func (schnorrScheme) CheckKey(pub []byte) error {
+	if _, ok := parseEdPoint(pub); !ok {
+		return ErrPublicKey
+	}
+	return nil
+}
+
+func (schnorrScheme) Verify(pub, msg, sig []byte) bool {
+	p, ok := parseEdPoint(pub)
+	if !ok || len(sig) != 2*edSize {
+		return false
+	}
+	s, err := new(edwards25519.Scalar).SetCanonicalBytes(sig[edSize:])
+	if err != nil {
+		return false
+	}
+	rb := sig[:edSize]
+	e := hashScalar(rb, pub, msg)
+
+	// sG - eP
+	r := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(e.Negate(e), p, s)
+
+	return string(r.Bytes()) == string(rb)
+}
+
+func (schnorrScheme) Address(pub []byte) paddr.PKAddr { return address(pub) }
+
+func (k schnorrKey) Public() []byte {
+	return k.pub
+}
+
+func (k schnorrKey) Sign(msg []byte) ([]byte, error) {
+	mh := sha512.Sum512(msg)
+	n := hashScalar(k.d.Bytes(), mh[:])
+
+	rb := new(edwards25519.Point).ScalarBaseMult(n).Bytes()
+	e := hashScalar(rb, k.pub, msg)
+	s := edwards25519.NewScalar().MultiplyAdd(e, k.d, n)
+
+	return append(rb, s.Bytes()...), nil
+}
+
+// 解析公钥。
+// 仅接受规范编码。
+func parseEdPoint(pub []byte) (*edwards25519.Point, bool) {
+	if len(pub) != edSize {
+		return nil, false
+	}
+	p, err := new(edwards25519.Point).SetBytes(pub)
+	if err != nil || string(p.Bytes()) != string(pub) {
+		return nil, false
+	}
+	return p, true
+}
+
+// 哈希为标量（模 l）。
+func hashScalar(parts ...[]byte) *edwards25519.Scalar {
+	h := sha512.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	s, _ := edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+	return s
+}
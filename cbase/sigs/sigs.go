@@ -0,0 +1,124 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+// Package sigs 签名方案集。
+// 以脚本版本（Actuator.Ver）为键登记签名方案，验证时按版本选用。
+// 各方案定义自己的公钥格式和签名格式，公钥地址统一为公钥的 paddr.Hash，
+// 因此单签名和多重签名的地址构造与方案无关。
+//
+// 内置方案：
+//   - 1 Ed25519：公钥32字节，签名64字节。
+//   - 2 ECDSA P-256：公钥33字节（压缩），签名64字节（r||s，低s），消息先经 SHA-256。
+//   - 3 Schnorr edwards25519：公钥32字节，签名64字节（R||s），确定性随机数。
+package sigs
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cxio/suite/cbase/paddr"
+	"github.com/cxio/suite/locale"
+)
+
+// 本地化文本获取。
+var _T = locale.GetText
+
+// 内置方案的版本值。
+const (
+	Ed25519      = 1 + iota // Ed25519
+	ECDSAP256               // ECDSA P-256
+	Schnorr25519            // Schnorr edwards25519
+)
+
+var (
+	// 未知的方案版本。
+	ErrScheme = errors.New(_T("未知的签名方案版本"))
+
+	// 公钥格式无效。
+	ErrPublicKey = errors.New(_T("公钥格式无效"))
+
+	// 私钥种子无效。
+	ErrSeed = errors.New(_T("私钥种子无效"))
+)
+
+// 私钥。
+type PrivateKey interface {
+	// 公钥的规范编码。
+	Public() []byte
+
+	// 签名消息。
+	Sign(msg []byte) ([]byte, error)
+}
+
+// 签名方案。
+type Scheme interface {
+	// 方案名称。
+	Name() string
+
+	// 由32字节种子创建私钥。
+	// 种子通常来自密钥派生，无效时（如超出曲线阶）返回 ErrSeed。
+	NewKey(seed []byte) (PrivateKey, error)
+
+	// 检查公钥格式。
+	CheckKey(pub []byte) error
+
+	// 验证签名。
+	// 公钥或签名格式无效时返回假。
+	Verify(pub, msg, sig []byte) bool
+
+	// 公钥地址。
+	Address(pub []byte) paddr.PKAddr
+}
+
+// 方案登记表。
+var (
+	mu        sync.RWMutex
+	__schemes = make(map[int]Scheme)
+)
+
+// 登记签名方案。
+// 同一版本重复登记会引发恐慌，应当在程序初始化时调用。
+func Register(ver int, s Scheme) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := __schemes[ver]; ok {
+		panic(fmt.Sprintf(_T("签名方案版本 %d 已登记"), ver))
+	}
+	__schemes[ver] = s
+}
+
+// 获取签名方案。
+// 未登记时返回 ErrScheme。
+func Get(ver int) (Scheme, error) {
+	mu.RLock()
+	s, ok := __schemes[ver]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrScheme, ver)
+	}
+	return s, nil
+}
+
+// 验证签名。
+// 按版本选用方案，未登记的版本视为验证失败。
+func Verify(ver int, pub, msg, sig []byte) bool {
+	s, err := Get(ver)
+	if err != nil {
+		return false
+	}
+	return s.Verify(pub, msg, sig)
+}
+
+// 通用的公钥地址。
+func address(pub []byte) paddr.PKAddr {
+	return paddr.Hash(pub, nil)
+}
+
+func init() {
+	Register(Ed25519, edScheme{})
+	Register(ECDSAP256, ecdsaScheme{})
+	Register(Schnorr25519, schnorrScheme{})
+}
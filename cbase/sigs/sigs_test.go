@@ -0,0 +1,109 @@
+package sigs_test
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/cxio/suite/cbase/paddr"
+	"github.com/cxio/suite/cbase/sigs"
+)
+
+var versions = []int{sigs.Ed25519, sigs.ECDSAP256, sigs.Schnorr25519}
+
+func TestSignVerify(t *testing.T) {
+	msg := []byte("message")
+	seed := bytes.Repeat([]byte{0x42}, 32)
+
+	for _, ver := range versions {
+		s, err := sigs.Get(ver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := s.NewKey(seed)
+		if err != nil {
+			t.Fatalf("%s: %v", s.Name(), err)
+		}
+		pub := key.Public()
+
+		if err := s.CheckKey(pub); err != nil {
+			t.Errorf("%s: CheckKey: %v", s.Name(), err)
+		}
+		if !bytes.Equal(s.Address(pub), paddr.Hash(pub, nil)) {
+			t.Errorf("%s: address differs from paddr.Hash", s.Name())
+		}
+		sig, err := key.Sign(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sigs.Verify(ver, pub, msg, sig) {
+			t.Errorf("%s: valid signature rejected", s.Name())
+		}
+		if sigs.Verify(ver, pub, []byte("other"), sig) {
+			t.Errorf("%s: signature accepted for other message", s.Name())
+		}
+		bad := bytes.Clone(sig)
+		bad[len(bad)-1] ^= 1
+		if sigs.Verify(ver, pub, msg, bad) {
+			t.Errorf("%s: tampered signature accepted", s.Name())
+		}
+		if sigs.Verify(ver, pub[:len(pub)-1], msg, sig) {
+			t.Errorf("%s: short key accepted", s.Name())
+		}
+	}
+}
+
+// ECDSA 仅接受低s签名。
+func TestECDSALowS(t *testing.T) {
+	s, _ := sigs.Get(sigs.ECDSAP256)
+	key, _ := s.NewKey(bytes.Repeat([]byte{1}, 32))
+	msg := []byte("malleable")
+
+	sig, _ := key.Sign(msg)
+	n, _ := new(big.Int).SetString("ffffffff00000000ffffffffffffffffbce6faada7179e84f3b9cac2fc632551", 16)
+
+	hs := new(big.Int).Sub(n, new(big.Int).SetBytes(sig[32:]))
+	high := append(bytes.Clone(sig[:32]), hs.FillBytes(make([]byte, 32))...)
+
+	if sigs.Verify(sigs.ECDSAP256, key.Public(), msg, high) {
+		t.Error("high-s signature accepted")
+	}
+}
+
+// Schnorr 仅接受规范编码的 s（< l）。
+func TestSchnorrCanonical(t *testing.T) {
+	s, _ := sigs.Get(sigs.Schnorr25519)
+	key, _ := s.NewKey(bytes.Repeat([]byte{1}, 32))
+	msg := []byte("malleable")
+
+	sig, _ := key.Sign(msg)
+	l, _ := new(big.Int).SetString("1000000000000000000000000000000014def9dea2f79cd65812631a5cf5d3ed", 16)
+
+	// 小端序转换
+	rev := func(b []byte) []byte {
+		b = bytes.Clone(b)
+		slices.Reverse(b)
+		return b
+	}
+	hs := new(big.Int).Add(l, new(big.Int).SetBytes(rev(sig[32:])))
+	high := append(bytes.Clone(sig[:32]), rev(hs.FillBytes(make([]byte, 32)))...)
+
+	if sigs.Verify(sigs.Schnorr25519, key.Public(), msg, high) {
+		t.Error("non-canonical s accepted")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	if _, err := sigs.Get(99); !errors.Is(err, sigs.ErrScheme) {
+		t.Errorf("unknown version: %v", err)
+	}
+	if sigs.Verify(99, nil, nil, nil) {
+		t.Error("unknown version verified")
+	}
+	s, _ := sigs.Get(sigs.ECDSAP256)
+	if _, err := s.NewKey(make([]byte, 32)); !errors.Is(err, sigs.ErrSeed) {
+		t.Errorf("zero seed: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package tx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/cxio/suite/cbase"
+	"github.com/cxio/suite/cbase/paddr"
+)
+
+//
+// 交易构造与检查
+///////////////////////////////////////////////////////////////////////////////
+
+// 收益分成上限（n/100）。
+const ScaleMax = 100
+
+// 结构检查错误。
+// 具体的出错位置由包装的错误消息给出，可由 errors.Is 检视类别。
+var (
+	ErrScale    = errors.New(_T("收益分成超出上限"))
+	ErrStaker   = errors.New(_T("收益地址与分成不匹配"))
+	ErrAddress  = errors.New(_T("公钥地址长度无效"))
+	ErrAmount   = errors.New(_T("币金数量为负"))
+	ErrEmptyOut = errors.New(_T("空输出项"))
+	ErrDupInput = errors.New(_T("重复的输入"))
+	ErrHashBody = errors.New(_T("交易体哈希不匹配"))
+)
+
+// 创建一个输入项。
+// 由源输出的脚本ID（cbase.KeyID）构成，不足部分为零。
+// h 为源交易所在区块高度，n 为交易序位，i 为输出序位。
+func NewVin(h, n, i int) Vin {
+	var v Vin
+	copy(v[:], cbase.KeyID(h, n, i))
+	return v
+}
+
+// 交易构造器。
+// 各方法返回构造器自身，便于链式调用，最后由 Build 检查并生成交易。
+type Builder struct {
+	head  Header
+	vins  []Vin
+	vouts []Vout
+}
+
+// 新建一个构造器。
+// ver 为交易版本，ts 为交易时间戳（毫秒）。
+func NewBuilder(ver int32, ts int64) *Builder {
+	return &Builder{head: Header{Version: ver, Timestamp: ts}}
+}
+
+// 设置主链绑定。
+func (b *Builder) Link(link [20]byte) *Builder {
+	b.head.BlockLink = link
+	return b
+}
+
+// 设置铸造者。
+// scale 为收益地址的分成（n/100），staker 为收益地址，无收益地址时为nil且分成为0。
+func (b *Builder) Minter(minter PKAddr, scale uint8, staker PKAddr) *Builder {
+	b.head.Minter = minter
+	b.head.Scale = scale
+	b.head.Staker = staker
+	return b
+}
+
+// 添加输入。
+// 参数同 NewVin。
+func (b *Builder) Input(h, n, i int) *Builder {
+	b.vins = append(b.vins, NewVin(h, n, i))
+	return b
+}
+
+// 添加币金输出。
+func (b *Builder) Coin(to PKAddr, amount int64, script []byte) *Builder {
+	b.vouts = append(b.vouts, CoinOut(&Coin{Receiver: to, Amount: amount, Script: script}))
+	return b
+}
+
+// 添加凭信输出。
+func (b *Builder) Credit(c Credit) *Builder {
+	b.vouts = append(b.vouts, CreditOut(&c))
+	return b
+}
+
+// 添加证据输出。
+func (b *Builder) Evidence(e Evidence) *Builder {
+	b.vouts = append(b.vouts, EvidenceOut(&e))
+	return b
+}
+
+// 生成交易。
+// 计算交易体哈希并填入交易头，然后执行结构检查。
+func (b *Builder) Build() (*Header, *Body, error) {
+	head := b.head
+	body := NewBody(b.vins, b.vouts)
+
+	hash, err := body.Hash()
+	if err != nil {
+		return nil, nil, err
+	}
+	head.HashBody = hash
+
+	if err := Validate(&head, body); err != nil {
+		return nil, nil, err
+	}
+	return &head, body, nil
+}
+
+// 交易结构检查。
+// 检查内容：
+// - 收益分成不超过 ScaleMax，有收益地址时分成非零，无收益地址时分成为零。
+// - 铸造者、收益地址和接收者的公钥地址长度（20 或 22 字节）。
+// - 每个输出项为单一类别，币金数量非负。
+// - 输入项不重复。
+// - 交易头的 HashBody 与交易体一致。
+// 注：不含签名、余额等需要链数据的检查。
+func Validate(h *Header, b *Body) error {
+	if h.Scale > ScaleMax {
+		return fmt.Errorf("%w: %d", ErrScale, h.Scale)
+	}
+	if (len(h.Staker) == 0) != (h.Scale == 0) {
+		return fmt.Errorf("%w: scale %d", ErrStaker, h.Scale)
+	}
+	if err := checkAddr("minter", h.Minter); err != nil {
+		return err
+	}
+	if len(h.Staker) > 0 {
+		if err := checkAddr("staker", h.Staker); err != nil {
+			return err
+		}
+	}
+	seen := make(map[Vin]bool, len(b.vins))
+
+	for i, v := range b.vins {
+		if seen[v] {
+			return fmt.Errorf("%w: vin %d", ErrDupInput, i)
+		}
+		seen[v] = true
+	}
+	for i := range b.vouts {
+		if err := checkVout(&b.vouts[i]); err != nil {
+			return fmt.Errorf("vout %d: %w", i, err)
+		}
+	}
+	hash, err := b.Hash()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hash, h.HashBody) {
+		return ErrHashBody
+	}
+	return nil
+}
+
+// 检查输出项。
+func checkVout(v *Vout) error {
+	switch v.Type() {
+	case TypeCoin:
+		if v.coin.Amount < 0 {
+			return fmt.Errorf("%w: %d", ErrAmount, v.coin.Amount)
+		}
+		return checkAddr("receiver", v.coin.Receiver)
+	case TypeCredit:
+		return checkAddr("receiver", v.credit.Receiver)
+	case TypeEvidence:
+		return nil
+	}
+	return ErrEmptyOut
+}
+
+// 检查公钥地址长度。
+// 单签名地址为20字节，多重签名地址前置2字节配比（n/T）。
+func checkAddr(name string, addr PKAddr) error {
+	switch len(addr) {
+	case paddr.HashSize, paddr.HashSize + 2:
+		return nil
+	}
+	return fmt.Errorf("%w: %s (%d)", ErrAddress, name, len(addr))
+}
@@ -0,0 +1,70 @@
+package tx_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cxio/suite/cbase/tx"
+)
+
+// 20字节测试地址。
+func addr(c byte) tx.PKAddr {
+	return bytes.Repeat([]byte{c}, 20)
+}
+
+func TestBuilder(t *testing.T) {
+	h, b, err := tx.NewBuilder(1, 1700000000000).
+		Minter(addr('m'), 10, addr('s')).
+		Input(100, 2, 0).
+		Input(100, 2, 1).
+		Coin(addr('a'), 500, nil).
+		Credit(tx.Credit{Receiver: addr('b'), Description: []byte("badge")}).
+		Evidence(tx.Evidence{Title: []byte("proof")}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Vins()) != 2 || len(b.Vouts()) != 3 {
+		t.Fatalf("body: %d vins, %d vouts", len(b.Vins()), len(b.Vouts()))
+	}
+	if b.Vouts()[1].Type() != tx.TypeCredit || b.Vouts()[1].Coin() != nil {
+		t.Error("vout kind mixed")
+	}
+	if err := tx.Validate(h, b); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	// 交易体改变后哈希链接失效
+	b.Vouts()[0].Coin().Amount = 501
+	if err := tx.Validate(h, b); !errors.Is(err, tx.ErrHashBody) {
+		t.Errorf("modified body: %v", err)
+	}
+}
+
+func TestValidateReject(t *testing.T) {
+	base := func() *tx.Builder {
+		return tx.NewBuilder(1, 0).Minter(addr('m'), 0, nil).Input(1, 0, 0)
+	}
+	tests := []struct {
+		name string
+		b    *tx.Builder
+		err  error
+	}{
+		{"scale", base().Minter(addr('m'), 101, addr('s')), tx.ErrScale},
+		{"staker missing", base().Minter(addr('m'), 5, nil), tx.ErrStaker},
+		{"scale missing", base().Minter(addr('m'), 0, addr('s')), tx.ErrStaker},
+		{"minter", base().Minter([]byte("short"), 0, nil), tx.ErrAddress},
+		{"receiver", base().Coin([]byte("short"), 1, nil), tx.ErrAddress},
+		{"amount", base().Coin(addr('a'), -1, nil), tx.ErrAmount},
+		{"dup input", base().Input(1, 0, 0), tx.ErrDupInput},
+	}
+	for _, tt := range tests {
+		if _, _, err := tt.b.Build(); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expect %v, got %v", tt.name, tt.err, err)
+		}
+	}
+	// 22字节（多重签名）地址有效
+	if _, _, err := base().Coin(bytes.Repeat([]byte{1}, 22), 1, nil).Build(); err != nil {
+		t.Errorf("multisig receiver: %v", err)
+	}
+}
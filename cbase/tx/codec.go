@@ -0,0 +1,365 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package tx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/cxio/suite/cbase/chash"
+)
+
+//
+// 二进制编码
+// 交易头和交易体的规范编码，用于存储、传输和哈希计算。
+// 规则：
+// - 首字节为编码版本（CodecVersion）。
+// - 字段依结构定义的顺序，无字段名。
+// - 无符号整数为 uvarint，有符号整数为 varint（zigzag），必须为最短形式。
+// - 字节序列前置 uvarint 长度，空序列与nil相同（解码为nil）。
+// - 定长数组（如 Vin, BlockLink）直接写入。
+// - 输出项前置类型值（TypeCoin 等），空输出不可编码。
+// 同一数据只有唯一的合法编码，解码时拒绝非规范形式和尾部多余数据。
+///////////////////////////////////////////////////////////////////////////////
+
+// 当前编码版本。
+const CodecVersion = 1
+
+// 编码无效。
+// 解码错误皆包装此错误值，可由 errors.Is 检视。
+var ErrEncoding = errors.New(_T("交易数据编码无效"))
+
+// 创建解码错误。
+func encodingError(format string, a ...any) error {
+	return fmt.Errorf("%w: %s", ErrEncoding, fmt.Sprintf(format, a...))
+}
+
+// 编码交易头。
+func (h *Header) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.uint(CodecVersion)
+	e.header(h)
+	return e.buf, nil
+}
+
+// 解码交易头。
+func (h *Header) UnmarshalBinary(data []byte) error {
+	d := &decoder{buf: data}
+	d.version()
+	d.header(h)
+	return d.finish()
+}
+
+// 交易ID。
+// 即交易头编码的哈希（32字节），交易头含交易体的哈希（HashBody）。
+func (h *Header) ID() []byte {
+	e := &encoder{}
+	e.uint(CodecVersion)
+	e.header(h)
+	return chash.Sum256(CodecVersion, e.buf)
+}
+
+// 编码输出项。
+func (v *Vout) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	if err := e.vout(v); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// 解码输出项。
+// 注：输出项为交易体的成员，编码中不含版本。
+func (v *Vout) UnmarshalBinary(data []byte) error {
+	d := &decoder{buf: data}
+	d.vout(v)
+	return d.finish()
+}
+
+// 编码交易体。
+func (b *Body) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.uint(CodecVersion)
+
+	if err := e.body(b); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// 解码交易体。
+func (b *Body) UnmarshalBinary(data []byte) error {
+	d := &decoder{buf: data}
+	d.version()
+	d.body(b)
+	return d.finish()
+}
+
+// 交易体哈希。
+// 即交易体编码的哈希（32字节），用于交易头的 HashBody 字段。
+// 含空输出时出错。
+func (b *Body) Hash() ([]byte, error) {
+	data, err := b.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return chash.Sum256(CodecVersion, data), nil
+}
+
+//
+// 编码器
+///////////////////////////////////////////////////////////////////////////////
+
+// 编码器。
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) uint(n uint64) {
+	e.buf = binary.AppendUvarint(e.buf, n)
+}
+
+func (e *encoder) int(n int64) {
+	e.buf = binary.AppendVarint(e.buf, n)
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.uint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) fixed(b []byte) {
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) header(h *Header) {
+	e.int(int64(h.Version))
+	e.int(h.Timestamp)
+	e.fixed(h.BlockLink[:])
+	e.bytes(h.Minter)
+	e.uint(uint64(h.Scale))
+	e.bytes(h.Staker)
+	e.bytes(h.HashBody)
+}
+
+func (e *encoder) vout(v *Vout) error {
+	switch {
+	case v.coin != nil:
+		c := v.coin
+		e.uint(TypeCoin)
+		e.bytes(c.Receiver)
+		e.int(c.Amount)
+		e.bytes(c.Script)
+	case v.credit != nil:
+		c := v.credit
+		e.uint(TypeCredit)
+		e.bytes(c.Receiver)
+		e.bytes(c.Creator)
+		e.bytes(c.Description)
+		e.bytes(c.Script)
+		e.bytes(c.Attachment)
+	case v.evidence != nil:
+		x := v.evidence
+		e.uint(TypeEvidence)
+		e.bytes(x.Title)
+		e.bytes(x.Content)
+		e.bytes(x.Script)
+		e.bytes(x.Attachment)
+	default:
+		return encodingError("%s", _T("空输出"))
+	}
+	return nil
+}
+
+func (e *encoder) body(b *Body) error {
+	e.uint(uint64(len(b.vins)))
+	for _, v := range b.vins {
+		e.fixed(v[:])
+	}
+	e.uint(uint64(len(b.vouts)))
+
+	for i := range b.vouts {
+		if err := e.vout(&b.vouts[i]); err != nil {
+			return fmt.Errorf("vout %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+//
+// 解码器
+// 首个错误之后的读取皆为空操作，最后由 finish 返回该错误。
+///////////////////////////////////////////////////////////////////////////////
+
+// 解码器。
+type decoder struct {
+	buf []byte
+	err error
+}
+
+// 记录首个错误。
+func (d *decoder) fail(format string, a ...any) {
+	if d.err == nil {
+		d.err = encodingError(format, a...)
+	}
+}
+
+// 结束检查。
+func (d *decoder) finish() error {
+	if d.err == nil && len(d.buf) > 0 {
+		d.fail(_T("尾部有 %d 字节多余数据"), len(d.buf))
+	}
+	return d.err
+}
+
+func (d *decoder) version() {
+	if v := d.uint(); d.err == nil && v != CodecVersion {
+		d.fail(_T("不支持的编码版本 %d"), v)
+	}
+}
+
+func (d *decoder) uint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	n, k := binary.Uvarint(d.buf)
+	if k <= 0 {
+		d.fail("%s", _T("整数读取失败"))
+		return 0
+	}
+	// 最短形式：多字节时末字节不为零
+	if k > 1 && d.buf[k-1] == 0 {
+		d.fail("%s", _T("整数非最短编码"))
+		return 0
+	}
+	d.buf = d.buf[k:]
+	return n
+}
+
+func (d *decoder) int() int64 {
+	u := d.uint()
+	// zigzag
+	n := int64(u >> 1)
+	if u&1 != 0 {
+		n = ^n
+	}
+	return n
+}
+
+// 读取限定范围的无符号整数。
+func (d *decoder) uintMax(max uint64) uint64 {
+	n := d.uint()
+	if n > max {
+		d.fail(_T("数值 %d 超出上限 %d"), n, max)
+		return 0
+	}
+	return n
+}
+
+// 读取 int32 范围的有符号整数。
+// 超出范围视为无效编码，避免截断后同一值有多种编码。
+func (d *decoder) int32() int32 {
+	n := d.int()
+	if n < math.MinInt32 || n > math.MaxInt32 {
+		d.fail(_T("数值 %d 超出 int32 范围"), n)
+		return 0
+	}
+	return int32(n)
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uint()
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(len(d.buf)) {
+		d.fail(_T("字节序列长度 %d 超出剩余数据"), n)
+		return nil
+	}
+	if n == 0 {
+		return nil
+	}
+	b := make([]byte, n)
+	copy(b, d.buf)
+	d.buf = d.buf[n:]
+
+	return b
+}
+
+func (d *decoder) fixed(b []byte) {
+	if d.err != nil {
+		return
+	}
+	if len(d.buf) < len(b) {
+		d.fail(_T("定长数据不足 %d 字节"), len(b))
+		return
+	}
+	copy(b, d.buf)
+	d.buf = d.buf[len(b):]
+}
+
+func (d *decoder) header(h *Header) {
+	h.Version = d.int32()
+	h.Timestamp = d.int()
+	d.fixed(h.BlockLink[:])
+	h.Minter = d.bytes()
+	h.Scale = uint8(d.uintMax(0xff))
+	h.Staker = d.bytes()
+	h.HashBody = d.bytes()
+}
+
+func (d *decoder) vout(v *Vout) {
+	*v = Vout{}
+
+	switch t := d.uint(); {
+	case d.err != nil:
+	case t == TypeCoin:
+		v.coin = &Coin{
+			Receiver: d.bytes(),
+			Amount:   d.int(),
+			Script:   d.bytes(),
+		}
+	case t == TypeCredit:
+		v.credit = &Credit{
+			Receiver:    d.bytes(),
+			Creator:     d.bytes(),
+			Description: d.bytes(),
+			Script:      d.bytes(),
+			Attachment:  d.bytes(),
+		}
+	case t == TypeEvidence:
+		v.evidence = &Evidence{
+			Title:      d.bytes(),
+			Content:    d.bytes(),
+			Script:     d.bytes(),
+			Attachment: d.bytes(),
+		}
+	default:
+		d.fail(_T("未知的输出类型 %d"), t)
+	}
+}
+
+func (d *decoder) body(b *Body) {
+	// 成员数不超出剩余字节数，避免恶意的超大分配
+	n := d.uintMax(uint64(len(d.buf)) / InIDSize)
+	b.vins = nil
+
+	if n > 0 {
+		b.vins = make([]Vin, n)
+	}
+	for i := range b.vins {
+		d.fixed(b.vins[i][:])
+	}
+	n = d.uintMax(uint64(len(d.buf)))
+	b.vouts = nil
+
+	if n > 0 {
+		b.vouts = make([]Vout, n)
+	}
+	for i := range b.vouts {
+		d.vout(&b.vouts[i])
+	}
+}
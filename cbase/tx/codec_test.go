@@ -0,0 +1,128 @@
+package tx_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/cxio/suite/cbase/tx"
+)
+
+// 含三类输出的交易体。
+func fullBody() *tx.Body {
+	return tx.NewBody(
+		[]tx.Vin{{1, 2, 3}, {4, 5, 6}},
+		[]tx.Vout{
+			tx.CoinOut(&tx.Coin{Receiver: []byte("alice"), Amount: 12345, Script: []byte{0x01, 0x02}}),
+			tx.CreditOut(&tx.Credit{Receiver: []byte("bob"), Creator: []byte("org"), Description: []byte("badge")}),
+			tx.EvidenceOut(&tx.Evidence{Title: []byte("title"), Content: []byte("content")}),
+		},
+	)
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	b := fullBody()
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b2 tx.Body
+	if err := b2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(b, &b2) {
+		t.Errorf("body round trip:\n%+v\n%+v", b, &b2)
+	}
+	hb, err := b.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &tx.Header{
+		Version:   1,
+		Timestamp: -5, // 负值亦可编码
+		BlockLink: [20]byte{9},
+		Minter:    []byte("minter"),
+		Scale:     200,
+		HashBody:  hb,
+	}
+	data, err = h.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h2 tx.Header
+	if err := h2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(h, &h2) {
+		t.Errorf("header round trip:\n%+v\n%+v", h, &h2)
+	}
+	if id := h.ID(); len(id) != 32 || !bytes.Equal(id, h2.ID()) {
+		t.Errorf("txid: %x", id)
+	}
+}
+
+// 交易ID随交易体变化（经由 HashBody）。
+func TestHeaderID(t *testing.T) {
+	b := fullBody()
+	h1, _ := b.Hash()
+
+	b.Vouts()[0].Coin().Amount++
+	h2, _ := b.Hash()
+
+	if bytes.Equal((&tx.Header{HashBody: h1}).ID(), (&tx.Header{HashBody: h2}).ID()) {
+		t.Error("txid unchanged")
+	}
+}
+
+func TestCodecReject(t *testing.T) {
+	data, err := fullBody().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"version", append([]byte{2}, data[1:]...)},
+		{"trailing", append(append([]byte{}, data...), 0)},
+		{"truncated", data[:len(data)-1]},
+		{"non-minimal", append([]byte{0x81, 0x00}, data[1:]...)},
+		{"vout type", []byte{1, 0, 1, 7}},
+		{"huge count", []byte{1, 0xff, 0xff, 0xff, 0xff, 0x0f}},
+	}
+	for _, tt := range tests {
+		var b tx.Body
+		if err := b.UnmarshalBinary(tt.data); !errors.Is(err, tx.ErrEncoding) {
+			t.Errorf("%s: expect ErrEncoding, got %v", tt.name, err)
+		}
+	}
+	if _, err := tx.NewBody(nil, []tx.Vout{{}}).MarshalBinary(); !errors.Is(err, tx.ErrEncoding) {
+		t.Errorf("empty vout: %v", err)
+	}
+}
+
+// 交易头的非规范编码。
+func TestHeaderReject(t *testing.T) {
+	data, err := (&tx.Header{Version: 1}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// data[1] 为版本 1 的 zigzag 编码
+	wide := func(v int64) []byte {
+		b := binary.AppendVarint([]byte{data[0]}, v)
+		return append(b, data[2:]...)
+	}
+	var h tx.Header
+	if err := h.UnmarshalBinary(wide(1)); err != nil || h.Version != 1 {
+		t.Fatalf("version 1: %v, %d", err, h.Version)
+	}
+	for _, v := range []int64{1<<32 + 1, math.MaxInt32 + 1, math.MinInt32 - 1} {
+		if err := h.UnmarshalBinary(wide(v)); !errors.Is(err, tx.ErrEncoding) {
+			t.Errorf("version %d: expect ErrEncoding, got %v", v, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package tx
+
+import (
+	"errors"
+
+	"github.com/cxio/suite/cbase/chash"
+	"github.com/cxio/suite/locale"
+	"github.com/cxio/suite/script/instor"
+)
+
+// 本地化文本获取。
+var _T = locale.GetText
+
+// 签名消息域标识。
+// 区分签名消息与其它哈希数据，避免跨用途的签名重放。
+const sigDomain = "cxio/sighash"
+
+var (
+	// 输入序位超出范围。
+	ErrSigInput = errors.New(_T("签名消息的输入序位超出范围"))
+
+	// 无同序位的输出（SigSingle）。
+	ErrSigSingle = errors.New(_T("签名消息缺少与输入同序位的输出"))
+
+	// 签名消息类型无效。
+	ErrSigFlag = errors.New(_T("签名消息类型无效"))
+
+	// 排除的输出无效（SigExclude）。
+	ErrSigExclude = errors.New(_T("签名消息排除的输出无效"))
+)
+
+// 构造签名消息。
+// 验证端（脚本中的 FN_CHECKSIG/FN_MCHECKSIG）与钱包端使用此函数得到完全相同的消息。
+// 参数：
+// ver  脚本版本（Actuator.Ver），用于签名方式的升级。
+// id   被解锁的脚本标识（cbase.KeyID）。
+// h    交易头。
+// b    交易体。
+// in   当前输入的序位。
+// flag 签名消息类型（instor.SigXXX）。
+// 消息构成（依序，编码规则同交易数据的二进制编码）：
+// - 域标识、版本、类型、脚本标识、输入序位。
+// - 交易头各字段，数据体哈希除外（它涵盖了全部输入和输出）。
+// - 输入集：SigAnyOne 时仅当前输入，否则全部。
+// - 输出集：依输出方式选取，每项前置其序位。
+// 返回：32字节哈希。
+func SigHash(ver int, id []byte, h *Header, b *Body, in, flag int) ([]byte, error) {
+	if in < 0 || in >= len(b.vins) {
+		return nil, ErrSigInput
+	}
+	e := &encoder{}
+	e.bytes([]byte(sigDomain))
+	e.uint(uint64(ver))
+	e.uint(uint64(flag & 0xff))
+	e.bytes(id)
+	e.uint(uint64(in))
+
+	e.int(int64(h.Version))
+	e.int(h.Timestamp)
+	e.fixed(h.BlockLink[:])
+	e.bytes(h.Minter)
+	e.uint(uint64(h.Scale))
+	e.bytes(h.Staker)
+
+	// 输入集
+	if flag&instor.SigAnyOne != 0 {
+		e.uint(1)
+		e.fixed(b.vins[in][:])
+	} else {
+		e.uint(uint64(len(b.vins)))
+		for _, v := range b.vins {
+			e.fixed(v[:])
+		}
+	}
+	// 输出集
+	outs, err := sigOutputs(len(b.vouts), in, flag)
+	if err != nil {
+		return nil, err
+	}
+	e.uint(uint64(len(outs)))
+
+	for _, i := range outs {
+		e.uint(uint64(i))
+		if err := e.vout(&b.vouts[i]); err != nil {
+			return nil, err
+		}
+	}
+	return chash.Sum256(ver, e.buf), nil
+}
+
+// 选取签名消息涵盖的输出序位。
+// n 为输出总数，in 为当前输入序位。
+// 排除位图不能标记不存在的输出，也不能排除全部输出。
+// 位图仅能覆盖序位 0-4，因此排除方式仅适用于输出数不超过 instor.SigExcludes 的交易，
+// 否则返回 ErrSigExclude，而非让超出的输出无法被排除。
+func sigOutputs(n, in, flag int) ([]int, error) {
+	if flag < 0 || flag > 0xff {
+		return nil, ErrSigFlag
+	}
+	mask := flag >> 2 & 0x1f
+	var buf []int
+
+	switch flag & instor.SigOutMask {
+	case instor.SigAll:
+		if mask != 0 {
+			return nil, ErrSigFlag
+		}
+		for i := range n {
+			buf = append(buf, i)
+		}
+	case instor.SigSingle:
+		if mask != 0 {
+			return nil, ErrSigFlag
+		}
+		if in >= n {
+			return nil, ErrSigSingle
+		}
+		buf = append(buf, in)
+	case instor.SigExclude:
+		if n > instor.SigExcludes || mask == 0 || mask>>n != 0 {
+			return nil, ErrSigExclude
+		}
+		for i := range n {
+			if mask&(1<<i) != 0 {
+				continue
+			}
+			buf = append(buf, i)
+		}
+		if len(buf) == 0 {
+			return nil, ErrSigExclude
+		}
+	default:
+		return nil, ErrSigFlag
+	}
+	return buf, nil
+}
+
+// 构造排除方式的签名消息类型。
+// idx 为排除的输出序位，仅支持 0-4（见 instor.SigExcludes），超出时返回 ErrSigExclude。
+// 注：排除方式仅用于输出数不超过 instor.SigExcludes 的交易（见 SigHash）。
+// 需要仅含当前输入时，可对结果附加 instor.SigAnyOne。
+func SigExcludeFlag(idx ...int) (int, error) {
+	if len(idx) == 0 {
+		return 0, ErrSigExclude
+	}
+	flag := instor.SigExclude
+
+	for _, i := range idx {
+		if i < 0 || i >= instor.SigExcludes {
+			return 0, ErrSigExclude
+		}
+		flag |= 1 << i << 2
+	}
+	return flag, nil
+}
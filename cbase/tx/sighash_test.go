@@ -0,0 +1,135 @@
+package tx_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cxio/suite/cbase/tx"
+	"github.com/cxio/suite/script/instor"
+)
+
+// 测试交易：3个输入，3个输出。
+func testTx(amounts ...int64) (*tx.Header, *tx.Body) {
+	h := &tx.Header{Version: 1, Timestamp: 1700000000000, Minter: []byte("minter")}
+	vins := []tx.Vin{{1}, {2}, {3}}
+
+	var vouts []tx.Vout
+	for _, n := range amounts {
+		vouts = append(vouts, tx.CoinOut(&tx.Coin{Receiver: []byte("to"), Amount: n}))
+	}
+	return h, tx.NewBody(vins, vouts)
+}
+
+func sighash(t *testing.T, h *tx.Header, b *tx.Body, in, flag int) []byte {
+	t.Helper()
+
+	msg, err := tx.SigHash(1, []byte("script"), h, b, in, flag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestSigHashModes(t *testing.T) {
+	h, b := testTx(10, 20, 30)
+	_, b2 := testTx(10, 20, 99) // 第3个输出不同
+
+	tests := []struct {
+		flag int
+		same bool // 输出2改变后消息是否相同
+	}{
+		{instor.SigAll, false},
+		{instor.SigSingle, true},               // 输入1仅涵盖输出1
+		{instor.SigExclude | (1<<2)<<2, true},  // 排除输出2
+		{instor.SigExclude | (1<<1)<<2, false}, // 排除输出1
+	}
+	for _, tt := range tests {
+		m1 := sighash(t, h, b, 1, tt.flag)
+		m2 := sighash(t, h, b2, 1, tt.flag)
+
+		if bytes.Equal(m1, m2) != tt.same {
+			t.Errorf("flag %#x: same = %v, want %v", tt.flag, !tt.same, tt.same)
+		}
+	}
+	// 不同类型、不同输入的消息各不相同
+	if bytes.Equal(sighash(t, h, b, 1, instor.SigAll), sighash(t, h, b, 1, instor.SigAll|instor.SigAnyOne)) {
+		t.Error("flags not bound")
+	}
+	if bytes.Equal(sighash(t, h, b, 0, instor.SigAll), sighash(t, h, b, 1, instor.SigAll)) {
+		t.Error("input index not bound")
+	}
+}
+
+func TestSigHashAnyOne(t *testing.T) {
+	h, b := testTx(10, 20)
+	// 他人追加了输入，当前输入的序位不变
+	b2 := tx.NewBody(append(b.Vins(), tx.Vin{4}), b.Vouts())
+
+	flag := instor.SigAll | instor.SigAnyOne
+	if !bytes.Equal(sighash(t, h, b, 0, flag), sighash(t, h, b2, 0, flag)) {
+		t.Error("anyone-can-pay message changed by extra input")
+	}
+	if bytes.Equal(sighash(t, h, b, 0, instor.SigAll), sighash(t, h, b2, 0, instor.SigAll)) {
+		t.Error("all-inputs message unchanged by extra input")
+	}
+}
+
+func TestSigHashInvalid(t *testing.T) {
+	h, b := testTx(10)
+
+	if _, err := tx.SigHash(1, nil, h, b, 3, instor.SigAll); !errors.Is(err, tx.ErrSigInput) {
+		t.Errorf("input out of range: %v", err)
+	}
+	if _, err := tx.SigHash(1, nil, h, b, 1, instor.SigSingle); !errors.Is(err, tx.ErrSigSingle) {
+		t.Errorf("single without output: %v", err)
+	}
+}
+
+func TestSigHashExclude(t *testing.T) {
+	h, b := testTx(0, 1, 2, 3, 4)
+	_, b2 := testTx(0, 1, 2, 3, 99) // 第5个输出不同
+
+	flag, err := tx.SigExcludeFlag(1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sighash(t, h, b, 0, flag), sighash(t, h, b2, 0, flag)) {
+		t.Error("excluded output 4 changed message")
+	}
+	// 输出超过5个时，位图无法覆盖全部输出，排除方式不可用
+	_, b3 := testTx(0, 1, 2, 3, 4, 5)
+	if _, err := tx.SigHash(1, nil, h, b3, 0, flag); !errors.Is(err, tx.ErrSigExclude) {
+		t.Errorf("6 outputs: %v", err)
+	}
+	// 位图无法表示序位5及以上
+	for _, idx := range [][]int{{5}, {1, 7}, {-1}, nil} {
+		if _, err := tx.SigExcludeFlag(idx...); !errors.Is(err, tx.ErrSigExclude) {
+			t.Errorf("SigExcludeFlag(%v): %v", idx, err)
+		}
+	}
+	// 序位5的位与 SigAnyOne 重叠，不会被当作排除位图
+	if _, err := tx.SigHash(1, nil, h, b, 0, instor.SigExclude|1<<5<<2); !errors.Is(err, tx.ErrSigExclude) {
+		t.Errorf("exclude bit 5: %v", err)
+	}
+}
+
+func TestSigHashFlagInvalid(t *testing.T) {
+	h, b := testTx(10, 20)
+
+	for _, tt := range []struct {
+		flag int
+		err  error
+	}{
+		{instor.SigOutMask, tx.ErrSigFlag},              // 保留的输出方式
+		{instor.SigAll | 1<<2, tx.ErrSigFlag},           // 非排除方式带位图
+		{0x100, tx.ErrSigFlag},                          // 超出1字节
+		{instor.SigExclude, tx.ErrSigExclude},           // 空位图
+		{instor.SigExclude | 1<<2<<2, tx.ErrSigExclude}, // 排除不存在的输出2
+		{instor.SigExclude | 3<<2, tx.ErrSigExclude},    // 排除全部输出
+	} {
+		if _, err := tx.SigHash(1, nil, h, b, 0, tt.flag); !errors.Is(err, tt.err) {
+			t.Errorf("flag %#x: %v, want %v", tt.flag, err, tt.err)
+		}
+	}
+}
@@ -70,3 +70,69 @@ type Body struct {
 	vins  []Vin  // 输入集
 	vouts []Vout // 输出集
 }
+
+// 输出类型。
+const (
+	TypeCoin     = 1 + iota // 币金
+	TypeCredit              // 凭信
+	TypeEvidence            // 证据
+)
+
+// 创建币金输出。
+func CoinOut(c *Coin) Vout {
+	return Vout{coin: c}
+}
+
+// 创建凭信输出。
+func CreditOut(c *Credit) Vout {
+	return Vout{credit: c}
+}
+
+// 创建证据输出。
+func EvidenceOut(e *Evidence) Vout {
+	return Vout{evidence: e}
+}
+
+// 获取输出类型。
+// 空输出返回0。
+func (v *Vout) Type() int {
+	switch {
+	case v.coin != nil:
+		return TypeCoin
+	case v.credit != nil:
+		return TypeCredit
+	case v.evidence != nil:
+		return TypeEvidence
+	}
+	return 0
+}
+
+// 获取币金数据，非币金输出返回nil。
+func (v *Vout) Coin() *Coin {
+	return v.coin
+}
+
+// 获取凭信数据，非凭信输出返回nil。
+func (v *Vout) Credit() *Credit {
+	return v.credit
+}
+
+// 获取证据数据，非证据输出返回nil。
+func (v *Vout) Evidence() *Evidence {
+	return v.evidence
+}
+
+// 创建交易体。
+func NewBody(vins []Vin, vouts []Vout) *Body {
+	return &Body{vins, vouts}
+}
+
+// 获取输入集。
+func (b *Body) Vins() []Vin {
+	return b.vins
+}
+
+// 获取输出集。
+func (b *Body) Vouts() []Vout {
+	return b.vouts
+}
@@ -2,7 +2,10 @@ module github.com/cxio/suite
 
 go 1.24.1
 
-require golang.org/x/crypto v0.37.0
+require (
+	filippo.io/edwards25519 v1.1.0
+	golang.org/x/crypto v0.37.0
+)
 
 require (
 	golang.org/x/sys v0.32.0 // indirect
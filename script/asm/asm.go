@@ -0,0 +1,1236 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+// Package asm 脚本汇编器。
+// 将文本形式的脚本源码编译为指令字节序列。
+//
+// 源码由指令名（icode 常量名）、值字面量和符号指令构成，以空白分隔，
+// // 开始到行尾为注释。书写规则：
+//
+//   - 附参紧随指令名以小括号书写，中间不能有空白，如：SHIFT(2) GOTO(1,2,3)。
+//   - 名称类附参以花括号书写，如：ENV{Height} OUT{0, Amount} FN_HASH256{sha3}。
+//   - 子语句块以花括号书写，长度自动计算，如：IF{...} EACH{...} MODEL(1){...}。
+//   - 整数自动选择最窄的编码（Uint8n|Uint8|Uint63n|Uint63|BigInt）。
+//   - 浮点数在无精度损失时采用 Float32，否则采用 Float64。
+//   - 字符串依长度选择 TEXT8 或 TEXT16，DATA{0x...} 依长度选择 DATA8 或 DATA16。
+//   - 字符字面量（'x'）为 Rune，正则字面量（/.../）为 RegExp。
+//   - 独立的小括号为表达式（Expr），其中可用运算符 * / + - 以及别名
+//     % ** << >> == != < <= > >= && || !（对应 MOD POW LMOV RMOV EQUAL NEQUAL LT LTE GT GTE BOTH EITHER NOT）。
+//   - 符号指令：@ ~ $ $(n) ${Name} #(n) &(n) _ _(n) ?(n) ?{...} !{Type} !{a, b} !{a, b, d} ...
+//
+// 模式区外的表达式在汇编时即做语法检查（由 inst 包注册检查器，见 instor.ExprCheck）。
+// 模式区内，?(n) 之后的指令按通配标识省略相应部分后编码（源码中仍完整书写）。
+package asm
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cxio/suite/cbase/chash"
+	"github.com/cxio/suite/locale"
+	"github.com/cxio/suite/script/icode"
+	"github.com/cxio/suite/script/instor"
+)
+
+// 本地化文本获取。
+var _T = locale.GetText
+
+// 长度上限定义。
+const (
+	maxSize8  = math.MaxUint8         // 单字节长度
+	maxSize16 = math.MaxUint16        // 双字节长度
+	maxModel  = 0b0011_1111_1111_1111 // 模式区长度（低14位）
+)
+
+// 模式区取值标记。
+const modelPick = 0b1000_0000
+
+// 正则匹配通关性标记。
+const rePass = 0b1000_0000
+
+// 局部通配标识位。
+const (
+	wildData = 0b0100_0000 // 关联数据通配
+	wildHash = 0b1000_0000 // 哈希匹配
+)
+
+// Error 汇编错误。
+// 包含出错处的源码位置。
+type Error struct {
+	Pos
+	Msg string // 错误信息
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// 抛出汇编错误。
+// 内部统一以异常传递，在 Assemble 中恢复为错误值。
+func fail(pos Pos, msg string, args ...any) {
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	panic(&Error{pos, msg})
+}
+
+// Assemble 汇编脚本源码。
+// 返回编译后的指令序列，出错时返回 *Error。
+func Assemble(src []byte) (code []byte, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			e, ok := v.(*Error)
+			if !ok {
+				panic(v)
+			}
+			code, err = nil, e
+		}
+	}()
+	p := &parser{sc: newScanner(src)}
+	p.next()
+
+	return p.list(""), nil
+}
+
+// 指令编码片段。
+// 保留各部分的独立性，以便在局部通配时省略。
+type piece struct {
+	code int      // 指令码
+	args [][]byte // 附参序列
+	data []byte   // 关联数据
+	kind int      // 编码类型
+}
+
+// 编码类型。
+// 对应模式匹配时的通配处理方式。
+const (
+	kindArgs  = iota // 普通附参，各自独立通配
+	kindValue        // 附参和数据为同一值
+	kindBytes        // 附参（长度）决定数据，支持哈希
+	kindExten        // 扩展类，附参决定数据，不支持哈希
+	kindModel        // 模式指令，不可通配修饰
+)
+
+// 完整编码。
+func (pc *piece) bytes() []byte {
+	buf := []byte{byte(pc.code)}
+
+	for _, a := range pc.args {
+		buf = append(buf, a...)
+	}
+	return append(buf, pc.data...)
+}
+
+// 局部通配编码。
+// flag 为前置 ?(n) 指令的通配标识。
+// 规则与模式匹配捡取器一致：通配的部分不编码，附参通配则数据一并省略。
+func (pc *piece) masked(t token, flag byte) []byte {
+	buf := []byte{byte(pc.code)}
+	// 从1开始计数
+	wild := func(n int) bool { return flag&(1<<n) != 0 }
+
+	switch pc.kind {
+	case kindModel:
+		fail(t.pos, _T("模式指令不能被局部通配修饰"))
+
+	case kindValue:
+		if flag&wildHash != 0 {
+			return append(buf, chash.Sum160(0, pc.data)...)
+		}
+		if !wild(1) && flag&wildData == 0 {
+			buf = append(buf, pc.data...)
+		}
+	case kindBytes, kindExten:
+		if pc.kind == kindBytes && flag&wildHash != 0 {
+			return append(buf, chash.Sum160(0, pc.data)...)
+		}
+		if !wild(1) {
+			buf = append(buf, pc.args[0]...)
+
+			if flag&wildData == 0 {
+				buf = append(buf, pc.data...)
+			}
+		}
+	default:
+		for i, a := range pc.args {
+			if !wild(i + 1) {
+				buf = append(buf, a...)
+			}
+		}
+	}
+	return buf
+}
+
+// 语法解析器。
+// 解析的同时完成编码。
+type parser struct {
+	sc    *scanner
+	tok   token   // 当前词法单元
+	expr  int     // 表达式嵌套深度
+	model int     // 模式区嵌套深度
+	marks *[]mark // 当前表达式体的指令位置记录
+}
+
+// 指令位置记录。
+// 用于将表达式内的出错偏移还原为源码位置。
+type mark struct {
+	off int // 指令在表达式体内的偏移
+	pos Pos // 源码位置
+}
+
+// 前进到下一个词法单元。
+// 返回前进之前的单元。
+func (p *parser) next() token {
+	t := p.tok
+	p.tok = p.sc.next(p.expr > 0)
+	return t
+}
+
+// 以当前模式重新扫描当前单元。
+// 用于表达式状态切换后，斜线的含义可能改变。
+func (p *parser) rescan() {
+	p.sc.off, p.sc.line, p.sc.col = p.tok.off, p.tok.pos.Line, p.tok.pos.Col
+	p.tok = p.sc.next(p.expr > 0)
+}
+
+// 当前单元是否紧邻前一单元（无空白）。
+func (p *parser) adjacent(prev token) bool {
+	return p.tok.off == prev.end
+}
+
+// 断言并跳过目标符号。
+func (p *parser) expect(s string) token {
+	if !p.tok.is(s) {
+		fail(p.tok.pos, _T("期待 %q"), s)
+	}
+	return p.next()
+}
+
+// 解析指令序列。
+// end 为结束符号，空串表示源码末尾（结束符不被跳过）。
+func (p *parser) list(end string) []byte {
+	var buf []byte
+
+	for !p.at(end) {
+		t := p.tok
+		p.mark(len(buf), t)
+		pc := p.inst()
+
+		if pc.code != icode.Wildpart {
+			buf = append(buf, pc.bytes()...)
+			continue
+		}
+		// 局部通配的目标指令
+		if p.at(end) {
+			fail(t.pos, _T("局部通配（?）之后缺少目标指令"))
+		}
+		buf = append(buf, pc.bytes()...)
+		t = p.tok
+		p.mark(len(buf), t)
+		x := p.inst()
+		buf = append(buf, x.masked(t, pc.args[0][0])...)
+	}
+	return buf
+}
+
+// 记录指令位置（表达式体内）。
+func (p *parser) mark(off int, t token) {
+	if p.marks != nil {
+		*p.marks = append(*p.marks, mark{off, t.pos})
+	}
+}
+
+// 是否抵达序列结束。
+func (p *parser) at(end string) bool {
+	if p.tok.kind == tokEOF {
+		if end != "" {
+			fail(p.tok.pos, _T("缺少结束符 %q"), end)
+		}
+		return true
+	}
+	return end != "" && p.tok.is(end)
+}
+
+// 解析单个指令。
+func (p *parser) inst() *piece {
+	t := p.next()
+
+	switch t.kind {
+	case tokInt:
+		return p.integer(t, false)
+	case tokFloat:
+		return floatPiece(t, false)
+	case tokString:
+		return textPiece(t, t.text)
+	case tokChar:
+		r, _ := utf8.DecodeRuneInString(t.text)
+		return runePiece(r)
+	case tokRegexp:
+		if t.flag != "" {
+			fail(t.pos, _T("正则字面量不支持标记（%s），请使用 RE{}"), t.flag)
+		}
+		return regexpPiece(t, t.text)
+	case tokIdent:
+		return p.mnemonic(t)
+	}
+	return p.symbol(t)
+}
+
+// 解析指令名。
+func (p *parser) mnemonic(t token) *piece {
+	switch t.text {
+	case "nil":
+		return &piece{code: icode.NIL}
+	case "true":
+		return &piece{code: icode.TRUE}
+	case "false":
+		return &piece{code: icode.FALSE}
+	case "TEXT":
+		return textPiece(t, p.braceText(t))
+	case "DATA":
+		return dataPiece(t, p.braceData(t))
+	}
+	c, ok := __Codes[t.text]
+	if !ok {
+		fail(t.pos, _T("未知的指令：%s"), t.text)
+	}
+	if f := __Forms[c]; f != nil {
+		return f(p, t, c)
+	}
+	return &piece{code: c}
+}
+
+// 解析符号指令。
+func (p *parser) symbol(t token) *piece {
+	switch t.text {
+	case "@":
+		return &piece{code: icode.Capture}
+	case "~":
+		return &piece{code: icode.Bring}
+
+	case "$":
+		if p.tok.is("(") && p.adjacent(t) {
+			return formArg1x(p, t, icode.ScopeVal)
+		}
+		if p.tok.is("{") {
+			return formName(instor.LoopNames)(p, t, icode.LoopVal)
+		}
+		return &piece{code: icode.ScopeAdd}
+
+	case "(":
+		return p.exprBlock(t, ")")
+
+	case "-":
+		if (p.tok.kind == tokInt || p.tok.kind == tokFloat) && p.adjacent(t) {
+			n := p.next()
+			n.text = "-" + n.text
+			n.pos = t.pos
+
+			if n.kind == tokInt {
+				return p.integer(n, false)
+			}
+			return floatPiece(n, false)
+		}
+		if p.expr > 0 {
+			return &piece{code: icode.Sub}
+		}
+	}
+	if c, ok := __Opers[t.text]; ok && p.expr > 0 {
+		return &piece{code: c}
+	}
+	if p.model == 0 {
+		if strings.Contains(patterns, t.text) {
+			fail(t.pos, _T("模式指令只能用于模式区内"))
+		}
+		fail(t.pos, _T("无效的符号：%s"), t.text)
+	}
+	return p.pattern(t)
+}
+
+// 模式区专用符号。
+const patterns = "#&_?!..."
+
+// 解析模式区专用的符号指令。
+func (p *parser) pattern(t token) *piece {
+	var pc *piece
+
+	switch t.text {
+	case "#":
+		pc = formArg1(p, t, icode.ValPick)
+	case "&":
+		pc = formArg1(p, t, icode.RePick)
+	case "...":
+		pc = &piece{code: icode.WildLump}
+	case "_":
+		pc = &piece{code: icode.Wildcard}
+		if p.tok.is("(") && p.adjacent(t) {
+			pc = formArg1(p, t, icode.Wildnum)
+		}
+	case "?":
+		if p.tok.is("{") {
+			pc = formBlock8(p, t, icode.Wildlist)
+			break
+		}
+		if !p.tok.is("(") || !p.adjacent(t) {
+			fail(t.pos, _T("局部通配需要紧随标识附参：?(n)"))
+		}
+		pc = formArg1(p, t, icode.Wildpart)
+	case "!":
+		pc = p.within(t)
+	default:
+		fail(t.pos, _T("无效的符号：%s"), t.text)
+	}
+	pc.kind = kindModel
+	return pc
+}
+
+// 解析 !{...} 类型或范围匹配。
+// - !{Type}       类型匹配（TypeIs）
+// - !{a, b}       整数范围匹配（WithinInt）
+// - !{a, b, d}    浮点数范围匹配（WithinFloat），任一值为浮点数时亦同
+func (p *parser) within(t token) *piece {
+	if !p.tok.is("{") {
+		fail(t.pos, _T("类型或范围匹配需要花括号：!{...}"))
+	}
+	p.next()
+
+	if p.tok.kind == tokIdent {
+		n := p.nameIndex(instor.TypeNames)
+		p.expect("}")
+		return &piece{code: icode.TypeIs, args: [][]byte{{byte(n)}}}
+	}
+	var vs []token
+	for {
+		vs = append(vs, p.numberToken())
+		if p.tok.is("}") {
+			break
+		}
+		p.expect(",")
+	}
+	p.next()
+
+	flo := len(vs) == 3
+	for _, v := range vs {
+		flo = flo || v.kind == tokFloat
+	}
+	if len(vs) < 2 || len(vs) > 3 {
+		fail(t.pos, _T("范围匹配需要2或3个值"))
+	}
+	if !flo {
+		var a, b [binary.MaxVarintLen64]byte
+		n1 := binary.PutVarint(a[:], intValue(vs[0]))
+		n2 := binary.PutVarint(b[:], intValue(vs[1]))
+		return &piece{code: icode.WithinInt, args: [][]byte{a[:n1], b[:n2]}}
+	}
+	var dev float64
+	if len(vs) == 3 {
+		dev = floatValue(vs[2])
+	}
+	low := binary.BigEndian.AppendUint64(nil, math.Float64bits(floatValue(vs[0])))
+	up := binary.BigEndian.AppendUint64(nil, math.Float64bits(floatValue(vs[1])))
+	d := binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(dev)))
+
+	return &piece{code: icode.WithinFloat, args: [][]byte{low, up, d}}
+}
+
+// 解析表达式体。
+// 起始符已跳过，end 为结束符。内部的斜线视为除号。
+// 模式区外的表达式在此完成语法检查，嵌套的分组先于外层检查。
+func (p *parser) exprBlock(t token, end string) *piece {
+	var ms []mark
+	x := p.marks
+	p.marks = &ms
+
+	p.expr++
+	// 当前单元已在旧模式下扫描
+	p.rescan()
+	body := p.list(end)
+	p.expr--
+	p.marks = x
+	e := p.next()
+
+	if len(body) > maxSize8 {
+		fail(t.pos, _T("表达式长度超出上限（%d）"), maxSize8)
+	}
+	if p.model == 0 {
+		exprCheck(body, ms, e.pos)
+	}
+	return &piece{code: icode.Expr, args: [][]byte{{byte(len(body))}}, data: body, kind: kindBytes}
+}
+
+// 解析花括号子块。
+// 块内不继承表达式状态。
+func (p *parser) block(t token) []byte {
+	x, ms := p.expr, p.marks
+	p.expr, p.marks = 0, nil
+	defer func() { p.marks = ms }()
+
+	if !p.tok.is("{") {
+		fail(t.pos, _T("%s 需要花括号子块"), t.text)
+	}
+	p.next()
+	body := p.list("}")
+	p.expr = x
+	p.next()
+
+	return body
+}
+
+// 表达式语法检查。
+// 借用校验器注册的检查器（instor.ExprCheck），未注册时略过。
+// ms 为表达式体的指令位置记录，end 为结束符位置（表达式不完整时报告于此）。
+func exprCheck(body []byte, ms []mark, end Pos) {
+	if instor.ExprCheck == nil {
+		return
+	}
+	i, err := instor.ExprCheck(body)
+	if err == nil {
+		return
+	}
+	pos := end
+	for _, m := range ms {
+		if m.off > i {
+			break
+		}
+		if i < len(body) {
+			pos = m.pos
+		}
+	}
+	fail(pos, _T("表达式语法错误：%v"), err)
+}
+
+//
+// 操作数解析
+///////////////////////////////////////////////////////////////////////////////
+
+// 解析紧随的小括号附参列表。
+// 成员为整数或字符。
+func (p *parser) parenArgs(t token) []token {
+	if !p.tok.is("(") || !p.adjacent(t) {
+		fail(t.pos, _T("%s 需要紧随的附参：%s(...)"), t.text, t.text)
+	}
+	x := p.expr
+	p.expr = 0
+	p.next()
+
+	var vs []token
+	for {
+		vs = append(vs, p.numberToken())
+		if p.tok.is(")") {
+			break
+		}
+		p.expect(",")
+	}
+	p.expr = x
+	p.next()
+
+	return vs
+}
+
+// 解析单个附参值。
+func (p *parser) parenArg(t token) token {
+	vs := p.parenArgs(t)
+	if len(vs) != 1 {
+		fail(t.pos, _T("%s 需要1个附参"), t.text)
+	}
+	return vs[0]
+}
+
+// 解析数值单元。
+// 支持紧邻的负号，字符视为其码点值。
+func (p *parser) numberToken() token {
+	t := p.next()
+
+	if t.is("-") && p.adjacent(t) && (p.tok.kind == tokInt || p.tok.kind == tokFloat) {
+		n := p.next()
+		n.text = "-" + n.text
+		n.pos = t.pos
+		return n
+	}
+	switch t.kind {
+	case tokInt, tokFloat:
+		return t
+	case tokChar:
+		r, _ := utf8.DecodeRuneInString(t.text)
+		t.kind, t.text = tokInt, strconv.Itoa(int(r))
+		return t
+	}
+	fail(t.pos, _T("期待数值"))
+	return t
+}
+
+// 解析名称或数值。
+// 名称在 names 中查找，返回其下标。
+func (p *parser) nameIndex(names []string) int {
+	if p.tok.kind != tokIdent {
+		return int(intValue(p.numberToken()))
+	}
+	t := p.next()
+
+	for i, s := range names {
+		if s != "" && s == t.text {
+			return i
+		}
+	}
+	fail(t.pos, _T("未知的名称：%s"), t.text)
+	return 0
+}
+
+// 解析花括号内的单个值单元。
+func (p *parser) braceToken(t token) token {
+	if !p.tok.is("{") {
+		fail(t.pos, _T("%s 需要花括号取值：%s{...}"), t.text, t.text)
+	}
+	p.next()
+	v := p.numberOrText()
+	p.expect("}")
+
+	return v
+}
+
+// 解析数值、字符串或正则式单元。
+func (p *parser) numberOrText() token {
+	switch p.tok.kind {
+	case tokString, tokRegexp:
+		return p.next()
+	}
+	return p.numberToken()
+}
+
+// 解析花括号内的文本。
+func (p *parser) braceText(t token) string {
+	v := p.braceToken(t)
+	if v.kind != tokString {
+		fail(v.pos, _T("期待字符串"))
+	}
+	return v.text
+}
+
+// 解析花括号内的字节序列。
+// 支持十六进制（0x...）或字符串形式。
+func (p *parser) braceData(t token) []byte {
+	if !p.tok.is("{") {
+		fail(t.pos, _T("%s 需要花括号取值：%s{...}"), t.text, t.text)
+	}
+	p.next()
+
+	var b []byte
+	v := p.next()
+
+	switch {
+	case v.kind == tokString:
+		b = []byte(v.text)
+	case v.kind == tokInt && strings.HasPrefix(strings.ToLower(v.text), "0x"):
+		var err error
+		if b, err = hex.DecodeString(v.text[2:]); err != nil {
+			fail(v.pos, _T("无效的十六进制数据：%s"), err)
+		}
+	case v.is("}"):
+		return nil
+	default:
+		fail(v.pos, _T("期待十六进制数据（0x...）或字符串"))
+	}
+	p.expect("}")
+
+	return b
+}
+
+//
+// 数值转换
+///////////////////////////////////////////////////////////////////////////////
+
+// 获取整数值。
+func intValue(t token) int64 {
+	if t.kind != tokInt {
+		fail(t.pos, _T("期待整数"))
+	}
+	v, err := strconv.ParseInt(t.text, 0, 64)
+	if err != nil {
+		fail(t.pos, _T("无效的整数：%s"), t.text)
+	}
+	return v
+}
+
+// 获取浮点数值。
+// 整数也被接受。
+func floatValue(t token) float64 {
+	if t.kind == tokInt {
+		return float64(intValue(t))
+	}
+	v, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		fail(t.pos, _T("无效的浮点数：%s"), t.text)
+	}
+	return v
+}
+
+// 获取范围内的整数值。
+func rangeValue(t token, min, max int64) int64 {
+	v := intValue(t)
+
+	if v < min || v > max {
+		fail(t.pos, _T("值 %d 超出范围 [%d, %d]"), v, min, max)
+	}
+	return v
+}
+
+//
+// 值编码
+///////////////////////////////////////////////////////////////////////////////
+
+// 整数编码。
+// 自动选择最窄的形式，超出 int64 范围的正整数采用 BigInt。
+// forced 为是否已指定为大整数。
+func (p *parser) integer(t token, forced bool) *piece {
+	v, err := strconv.ParseInt(t.text, 0, 64)
+
+	if err != nil || forced {
+		n, ok := new(big.Int).SetString(t.text, 0)
+		if !ok {
+			fail(t.pos, _T("无效的整数：%s"), t.text)
+		}
+		return bigPiece(t, n)
+	}
+	return intPiece(v)
+}
+
+// 整数值编码。
+func intPiece(v int64) *piece {
+	switch {
+	case v >= 0 && v <= maxSize8:
+		return &piece{code: icode.Uint8, data: []byte{byte(v)}, kind: kindValue}
+	case v < 0 && v >= -maxSize8:
+		return &piece{code: icode.Uint8n, data: []byte{byte(-v)}, kind: kindValue}
+	case v > 0:
+		return &piece{code: icode.Uint63, data: binary.AppendUvarint(nil, uint64(v)), kind: kindValue}
+	}
+	// 注：uint64(-v) 对最小负值依然正确。
+	return &piece{code: icode.Uint63n, data: binary.AppendUvarint(nil, uint64(-v)), kind: kindValue}
+}
+
+// 大整数编码。
+// 仅支持非负值（存储为大端字节序列）。
+func bigPiece(t token, n *big.Int) *piece {
+	if n.Sign() < 0 {
+		fail(t.pos, _T("大整数不支持负值"))
+	}
+	b := n.Bytes()
+
+	if len(b) > maxSize8 {
+		fail(t.pos, _T("大整数超出长度上限（%d 字节）"), maxSize8)
+	}
+	return &piece{code: icode.BigInt, args: [][]byte{{byte(len(b))}}, data: b, kind: kindBytes}
+}
+
+// 浮点数编码。
+// 可无损表示为 float32 时采用 Float32。
+// wide 为是否强制 Float64。
+func floatPiece(t token, wide bool) *piece {
+	v := floatValue(t)
+
+	if !wide && float64(float32(v)) == v {
+		b := binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(v)))
+		return &piece{code: icode.Float32, data: b, kind: kindValue}
+	}
+	b := binary.BigEndian.AppendUint64(nil, math.Float64bits(v))
+	return &piece{code: icode.Float64, data: b, kind: kindValue}
+}
+
+// 字符编码。
+func runePiece(r rune) *piece {
+	b := binary.BigEndian.AppendUint32(nil, uint32(r))
+	return &piece{code: icode.Rune, data: b, kind: kindValue}
+}
+
+// 文本编码。
+// 依长度选择 TEXT8 或 TEXT16。
+func textPiece(t token, s string) *piece {
+	pc := dataPiece(t, []byte(s))
+	pc.code += icode.TEXT8 - icode.DATA8
+	return pc
+}
+
+// 字节序列编码。
+// 依长度选择 DATA8 或 DATA16。
+func dataPiece(t token, b []byte) *piece {
+	switch {
+	case len(b) <= maxSize8:
+		return &piece{code: icode.DATA8, args: [][]byte{{byte(len(b))}}, data: b, kind: kindBytes}
+	case len(b) <= maxSize16:
+		n := binary.BigEndian.AppendUint16(nil, uint16(len(b)))
+		return &piece{code: icode.DATA16, args: [][]byte{n}, data: b, kind: kindBytes}
+	}
+	fail(t.pos, _T("数据长度超出上限（%d）"), maxSize16)
+	return nil
+}
+
+// 指定长度形式的编码。
+// 允许短数据采用双字节长度（非最窄），但长数据不能采用单字节长度。
+func sizedPiece(t token, c int, b []byte) *piece {
+	pc := dataPiece(t, b)
+	if c == icode.TEXT8 || c == icode.TEXT16 {
+		pc.code += icode.TEXT8 - icode.DATA8
+	}
+	switch {
+	case pc.code == c:
+		return pc
+	case c == icode.DATA16 || c == icode.TEXT16:
+		pc.code = c
+		pc.args[0] = binary.BigEndian.AppendUint16(nil, uint16(len(b)))
+		return pc
+	}
+	fail(t.pos, _T("数据长度超出 %s 的上限（%d）"), t.text, maxSize8)
+	return nil
+}
+
+// 正则表达式编码。
+func regexpPiece(t token, re string) *piece {
+	if _, err := regexp.Compile(re); err != nil {
+		fail(t.pos, _T("无效的正则表达式：%s"), err)
+	}
+	if len(re) > maxSize8 {
+		fail(t.pos, _T("正则表达式长度超出上限（%d）"), maxSize8)
+	}
+	return &piece{code: icode.RegExp, args: [][]byte{{byte(len(re))}}, data: []byte(re), kind: kindBytes}
+}
+
+//
+// 指令形式
+// 按指令码定义其附参和数据的书写形式。
+///////////////////////////////////////////////////////////////////////////////
+
+// 指令形式解析器。
+// t 为指令名单元，c 为指令码。
+type form func(p *parser, t token, c int) *piece
+
+// 形式解析器集。
+// 未设置者为无附参的单指令。
+var __Forms [256]form
+
+// 指令名到指令码的映射。
+var __Codes = make(map[string]int)
+
+// 表达式运算符。
+// 四则运算之外的为普通指令的别名，仅在表达式内有效。
+var __Opers = map[string]int{
+	"*":  icode.Mul,
+	"/":  icode.Div,
+	"+":  icode.Add,
+	"-":  icode.Sub,
+	"%":  icode.MOD,
+	"**": icode.POW,
+	"<<": icode.LMOV,
+	">>": icode.RMOV,
+	"==": icode.EQUAL,
+	"!=": icode.NEQUAL,
+	"<":  icode.LT,
+	"<=": icode.LTE,
+	">":  icode.GT,
+	">=": icode.GTE,
+	"&&": icode.BOTH,
+	"||": icode.EITHER,
+	"!":  icode.NOT,
+}
+
+// 单字节附参：NAME(n)
+func formArg1(p *parser, t token, c int) *piece {
+	v := rangeValue(p.parenArg(t), 0, math.MaxUint8)
+	return &piece{code: c, args: [][]byte{{byte(v)}}}
+}
+
+// 单字节有符号附参：NAME(n)
+func formArg1x(p *parser, t token, c int) *piece {
+	v := rangeValue(p.parenArg(t), math.MinInt8, math.MaxInt8)
+	return &piece{code: c, args: [][]byte{{byte(int8(v))}}}
+}
+
+// 双字节附参：NAME(n)
+func formArg2(p *parser, t token, c int) *piece {
+	v := rangeValue(p.parenArg(t), 0, math.MaxUint16)
+	return &piece{code: c, args: [][]byte{binary.BigEndian.AppendUint16(nil, uint16(v))}}
+}
+
+// 名称附参：NAME{Name}
+// 也可直接使用数值下标。
+func formName(names []string) form {
+	return func(p *parser, t token, c int) *piece {
+		if !p.tok.is("{") {
+			fail(t.pos, _T("%s 需要花括号名称：%s{...}"), t.text, t.text)
+		}
+		p.next()
+		n := 0
+		// 首个名称为空时可省略（如 SYS_TIME{}）
+		if !p.tok.is("}") || len(names) == 0 || names[0] != "" {
+			n = p.nameIndex(names)
+		}
+		p.expect("}")
+
+		if n < 0 || n > math.MaxUint8 {
+			fail(t.pos, _T("名称下标超出范围"))
+		}
+		return &piece{code: c, args: [][]byte{{byte(n)}}}
+	}
+}
+
+// 双字节名称附参：NAME{Name}
+func formName2(names []string) form {
+	return func(p *parser, t token, c int) *piece {
+		if !p.tok.is("{") {
+			fail(t.pos, _T("%s 需要花括号名称：%s{...}"), t.text, t.text)
+		}
+		p.next()
+		n := p.nameIndex(names)
+		p.expect("}")
+
+		if n < 0 || n > math.MaxUint16 {
+			fail(t.pos, _T("名称下标超出范围"))
+		}
+		return &piece{code: c, args: [][]byte{binary.BigEndian.AppendUint16(nil, uint16(n))}}
+	}
+}
+
+// 输出项取值：OUT{i, Name}
+func formOut(p *parser, t token, c int) *piece {
+	if !p.tok.is("{") {
+		fail(t.pos, _T("%s 需要花括号取值：%s{i, Name}"), t.text, t.text)
+	}
+	p.next()
+	i := rangeValue(p.numberToken(), 0, math.MaxUint16)
+	p.expect(",")
+	n := p.nameIndex(instor.OutNames)
+	p.expect("}")
+
+	return &piece{
+		code: c,
+		args: [][]byte{binary.BigEndian.AppendUint16(nil, uint16(i)), {byte(n)}},
+	}
+}
+
+// 跳转类：GOTO(h, n, i)
+func formGoto(p *parser, t token, c int) *piece {
+	vs := p.parenArgs(t)
+	if len(vs) != 3 {
+		fail(t.pos, _T("%s 需要3个附参：%s(height, tx, script)"), t.text, t.text)
+	}
+	h := rangeValue(vs[0], 0, math.MaxUint32)
+	n := rangeValue(vs[1], 0, math.MaxUint32)
+	i := rangeValue(vs[2], 0, math.MaxUint16)
+
+	return &piece{
+		code: c,
+		args: [][]byte{
+			binary.BigEndian.AppendUint32(nil, uint32(h)),
+			binary.BigEndian.AppendUint32(nil, uint32(n)),
+			binary.BigEndian.AppendUint16(nil, uint16(i)),
+		},
+	}
+}
+
+// 单字节长度子块：NAME{...}
+func formBlock8(p *parser, t token, c int) *piece {
+	body := p.block(t)
+
+	if len(body) > maxSize8 {
+		fail(t.pos, _T("%s 子块长度 %d 超出上限（%d）"), t.text, len(body), maxSize8)
+	}
+	return &piece{code: c, args: [][]byte{{byte(len(body))}}, data: body, kind: kindBytes}
+}
+
+// 变长长度子块：NAME{...}
+func formBlockX(p *parser, t token, c int) *piece {
+	body := p.block(t)
+	n := binary.AppendUvarint(nil, uint64(len(body)))
+
+	return &piece{code: c, args: [][]byte{n}, data: body, kind: kindBytes}
+}
+
+// 表达式：Expr{...}
+func formExpr(p *parser, t token, c int) *piece {
+	if !p.tok.is("{") {
+		fail(t.pos, _T("%s 需要花括号子块"), t.text)
+	}
+	p.next()
+	return p.exprBlock(t, "}")
+}
+
+// 模式区：MODEL{...} 或 MODEL(1){...}
+// 附参为1时设置取值标记。
+func formModel(p *parser, t token, c int) *piece {
+	var flag uint16
+
+	if p.tok.is("(") && p.adjacent(t) {
+		if rangeValue(p.parenArg(t), 0, 1) == 1 {
+			flag = modelPick << 8
+		}
+	}
+	p.model++
+	body := p.block(t)
+	p.model--
+
+	if len(body) > maxModel {
+		fail(t.pos, _T("模式区长度 %d 超出上限（%d）"), len(body), maxModel)
+	}
+	n := binary.BigEndian.AppendUint16(nil, flag|uint16(len(body)))
+
+	return &piece{code: c, args: [][]byte{n}, data: body, kind: kindBytes}
+}
+
+// 正则匹配：RE{[!]/.../[g|G]}
+// 感叹号标记通关性检查（匹配非空才成功）。
+func formRE(p *parser, t token, c int) *piece {
+	if p.model == 0 {
+		fail(t.pos, _T("模式指令只能用于模式区内"))
+	}
+	if !p.tok.is("{") {
+		fail(t.pos, _T("RE 需要花括号正则式：RE{/.../}"))
+	}
+	p.next()
+
+	var f byte
+	if p.tok.is("!") {
+		f = rePass
+		p.next()
+	}
+	v := p.next()
+	if v.kind != tokRegexp {
+		fail(v.pos, _T("期待正则表达式"))
+	}
+	if len(v.flag) > 1 {
+		fail(v.pos, _T("无效的正则标记：%s"), v.flag)
+	}
+	if v.flag != "" {
+		f |= v.flag[0]
+	}
+	p.expect("}")
+
+	pc := regexpPiece(v, v.text)
+	pc.code = c
+	pc.args = [][]byte{{f}, pc.args[0]}
+	pc.kind = kindModel
+
+	return pc
+}
+
+// 扩展类：NAME(i, m)
+// size 为扩展目标自身数据的长度，n 为索引附参的字节数。
+// 数据值按大端序存储于 size 个字节中。
+func formExten(size func(int) int, n int) form {
+	return func(p *parser, t token, c int) *piece {
+		vs := p.parenArgs(t)
+		i := rangeValue(vs[0], 0, 1<<(8*n)-1)
+		sz := size(int(i))
+
+		if len(vs) != 1+min(sz, 1) {
+			fail(t.pos, _T("%s 附参数量不符（扩展数据 %d 字节）"), t.text, sz)
+		}
+		a := binary.BigEndian.AppendUint16(nil, uint16(i))[2-n:]
+
+		var d []byte
+		if sz > 0 {
+			v := rangeValue(vs[1], 0, math.MaxInt64)
+			b := binary.BigEndian.AppendUint64(nil, uint64(v))
+
+			if sz < 8 && v>>(8*sz) != 0 {
+				fail(vs[1].pos, _T("扩展数据值超出 %d 字节"), sz)
+			}
+			d = make([]byte, sz)
+			copy(d[max(sz-8, 0):], b[max(8-sz, 0):])
+		}
+		return &piece{code: c, args: [][]byte{a}, data: d, kind: kindExten}
+	}
+}
+
+// 值指令的显式形式：NAME{v}
+func formValue(p *parser, t token, c int) *piece {
+	switch c {
+	case icode.DATA8, icode.DATA16:
+		return sizedPiece(t, c, p.braceData(t))
+	case icode.TEXT8, icode.TEXT16:
+		return sizedPiece(t, c, []byte(p.braceText(t)))
+	}
+	v := p.braceToken(t)
+
+	switch c {
+	case icode.Uint8:
+		return intPiece(rangeValue(v, 0, maxSize8))
+	case icode.Uint8n:
+		return intPiece(rangeValue(v, -maxSize8, 0))
+	case icode.Uint63:
+		n := rangeValue(v, 0, math.MaxInt64)
+		return &piece{code: c, data: binary.AppendUvarint(nil, uint64(n)), kind: kindValue}
+	case icode.Uint63n:
+		n := rangeValue(v, math.MinInt64, 0)
+		return &piece{code: c, data: binary.AppendUvarint(nil, uint64(-n)), kind: kindValue}
+	case icode.Byte:
+		return &piece{code: c, data: []byte{byte(rangeValue(v, 0, math.MaxUint8))}, kind: kindValue}
+	case icode.Rune:
+		return runePiece(rune(rangeValue(v, 0, utf8.MaxRune)))
+	case icode.Float32:
+		pc := floatPiece(v, false)
+		if pc.code != c {
+			fail(v.pos, _T("值 %s 无法以 Float32 无损表示"), v.text)
+		}
+		return pc
+	case icode.Float64:
+		return floatPiece(v, true)
+	case icode.DATE:
+		return &piece{code: c, data: binary.AppendVarint(nil, intValue(v)), kind: kindValue}
+	case icode.BigInt:
+		return p.integer(v, true)
+	case icode.RegExp:
+		if v.kind != tokRegexp || v.flag != "" {
+			fail(v.pos, _T("期待正则表达式"))
+		}
+		return regexpPiece(v, v.text)
+	}
+	fail(t.pos, _T("不支持的值指令：%s"), t.text)
+	return nil
+}
+
+// 模式指令名直接书写时的检查。
+func formPattern(f form) form {
+	return func(p *parser, t token, c int) *piece {
+		if p.model == 0 {
+			fail(t.pos, _T("模式指令只能用于模式区内"))
+		}
+		pc := f(p, t, c)
+		pc.kind = kindModel
+		return pc
+	}
+}
+
+// 单指令模式指令。
+func formSingle(p *parser, t token, c int) *piece {
+	return &piece{code: c}
+}
+
+//
+// 初始化
+///////////////////////////////////////////////////////////////////////////////
+
+// 指令名映射。
+func init() {
+	for c, s := range instor.CodeNames {
+		if s != "" {
+			__Codes[s] = c
+		}
+	}
+}
+
+// 指令形式配置。
+func init() {
+	// 值指令
+	for _, c := range []int{
+		icode.Uint8n, icode.Uint8, icode.Uint63n, icode.Uint63, icode.Byte, icode.Rune,
+		icode.Float32, icode.Float64, icode.DATE, icode.BigInt,
+		icode.DATA8, icode.DATA16, icode.TEXT8, icode.TEXT16, icode.RegExp,
+	} {
+		__Forms[c] = formValue
+	}
+	__Forms[icode.CODE] = formBlock8
+
+	// 取值指令
+	__Forms[icode.ScopeVal] = formArg1x
+	__Forms[icode.LoopVal] = formName(instor.LoopNames)
+
+	// 栈操作指令
+	__Forms[icode.SHIFT] = formArg1
+	__Forms[icode.CLONE] = formArg1
+	__Forms[icode.POPS] = formArg1
+	__Forms[icode.TOPS] = formArg1
+	__Forms[icode.PEEKS] = formArg1
+
+	// 集合指令
+	__Forms[icode.MAP] = formBlock8
+	__Forms[icode.FILTER] = formBlock8
+
+	// 交互指令
+	__Forms[icode.INPUT] = formArg1
+	__Forms[icode.BUFDUMP] = formArg1
+
+	// 结果指令
+	__Forms[icode.GOTO] = formGoto
+	__Forms[icode.JUMP] = formGoto
+
+	// 流程指令
+	__Forms[icode.IF] = formBlock8
+	__Forms[icode.ELSE] = formBlock8
+	__Forms[icode.SWITCH] = formBlockX
+	__Forms[icode.CASE] = formBlock8
+	__Forms[icode.DEFAULT] = formBlock8
+	__Forms[icode.EACH] = formBlock8
+	__Forms[icode.BLOCK] = formBlockX
+
+	// 转换指令
+	__Forms[icode.STRING] = formArg1
+	__Forms[icode.ANYS] = formName(instor.TypeNames)
+
+	// 运算指令
+	__Forms[icode.Expr] = formExpr
+	__Forms[icode.DUP] = formArg1
+
+	// 逻辑指令
+	__Forms[icode.SOME] = formArg1
+
+	// 模式指令
+	__Forms[icode.MODEL] = formModel
+	__Forms[icode.ValPick] = formPattern(formArg1)
+	__Forms[icode.Wildcard] = formPattern(formSingle)
+	__Forms[icode.Wildnum] = formPattern(formArg1)
+	__Forms[icode.Wildpart] = formPattern(formArg1)
+	__Forms[icode.Wildlist] = formPattern(formBlock8)
+	__Forms[icode.TypeIs] = formPattern(formName(instor.TypeNames))
+	__Forms[icode.RE] = formRE
+	__Forms[icode.RePick] = formPattern(formArg1)
+	__Forms[icode.WildLump] = formPattern(formSingle)
+
+	// 环境指令
+	__Forms[icode.ENV] = formName(instor.EnvNames)
+	__Forms[icode.OUT] = formOut
+	__Forms[icode.IN] = formName(instor.InNames)
+	__Forms[icode.INOUT] = formName(instor.OutNames)
+	__Forms[icode.XFROM] = formName(instor.XFromNames)
+	__Forms[icode.VAR] = formArg1
+	__Forms[icode.SETVAR] = formArg1
+	__Forms[icode.SOURCE] = formArg1
+	__Forms[icode.MULSIG] = formArg1
+
+	// 工具指令
+	__Forms[icode.KEYVAL] = formArg1
+	__Forms[icode.MATCH] = formArg1
+	__Forms[icode.SUBSTR] = formArg2
+	__Forms[icode.REPLACE] = formArg1
+	__Forms[icode.CMPFLO] = formArg1x
+	__Forms[icode.RANGE] = formArg2
+
+	// 系统指令
+	__Forms[icode.SYS_TIME] = formName(instor.TimeNames)
+
+	// 函数指令
+	__Forms[icode.FN_CHECKSIG] = formName(instor.SigNames)
+	__Forms[icode.FN_MCHECKSIG] = formName(instor.SigNames)
+	__Forms[icode.FN_HASH224] = formName(instor.HashAlgo)
+	__Forms[icode.FN_HASH256] = formName(instor.HashAlgo)
+	__Forms[icode.FN_HASH384] = formName(instor.HashAlgo)
+	__Forms[icode.FN_HASH512] = formName(instor.HashAlgo)
+	__Forms[icode.FN_X] = formName(instor.FnXNames)
+
+	// 模块指令
+	__Forms[icode.MO_RE] = formName(instor.MOREMethod)
+	__Forms[icode.MO_TIME] = formName(instor.MOTimeMethod)
+	__Forms[icode.MO_MATH] = formName(nil)
+	__Forms[icode.MO_CRYPT] = formName(nil)
+	__Forms[icode.MO_X] = formExten(instor.MoxSize, 1)
+
+	// 扩展指令
+	__Forms[icode.EX_FN] = formName2(instor.ExFnNames)
+	__Forms[icode.EX_INST] = formExten(instor.ExtSize, 2)
+	__Forms[icode.EX_PRIV] = formExten(instor.PrivSize, 2)
+}
@@ -0,0 +1,141 @@
+package asm_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cxio/suite/script/asm"
+	"github.com/cxio/suite/script/icode"
+	"github.com/cxio/suite/script/instor"
+
+	// 注册表达式语法检查器
+	_ "github.com/cxio/suite/script/inst"
+)
+
+// 汇编结果测试。
+var assembleTests = []struct {
+	src  string
+	want []byte
+}{
+	// 值编码
+	{"0 255", []byte{icode.Uint8, 0, icode.Uint8, 255}},
+	{"-1 -255", []byte{icode.Uint8n, 1, icode.Uint8n, 255}},
+	{"256", []byte{icode.Uint63, 0x80, 0x02}},
+	{"-256", []byte{icode.Uint63n, 0x80, 0x02}},
+	{"0x1ffffffffffffffff", []byte{icode.BigInt, 9, 1, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	{"1.5", []byte{icode.Float32, 0x3f, 0xc0, 0, 0}},
+	{"0.1", []byte{icode.Float64, 0x3f, 0xb9, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}},
+	{"'a'", []byte{icode.Rune, 0, 0, 0, 'a'}},
+	{`"ab"`, []byte{icode.TEXT8, 2, 'a', 'b'}},
+	{"DATA{0x0102}", []byte{icode.DATA8, 2, 1, 2}},
+	{"Byte{'x'}", []byte{icode.Byte, 'x'}},
+	{"DATE{-1}", []byte{icode.DATE, 1}},
+	{"nil true false", []byte{icode.NIL, icode.TRUE, icode.FALSE}},
+	{"/a+/", []byte{icode.RegExp, 2, 'a', '+'}},
+
+	// 附参与名称
+	{"SHIFT(2) $(-1) ${Key}", []byte{icode.SHIFT, 2, icode.ScopeVal, 0xff, icode.LoopVal, 1}},
+	{"@ ~ $", []byte{icode.Capture, icode.Bring, icode.ScopeAdd}},
+	{"ENV{Height}", []byte{icode.ENV, byte(instor.EnvHeight)}},
+	{"OUT{1, Amount}", []byte{icode.OUT, 0, 1, byte(instor.OutAmount)}},
+	{"SYS_TIME{}", []byte{icode.SYS_TIME, 0}},
+	{"FN_HASH256{sha2}", []byte{icode.FN_HASH256, byte(instor.HashSHA2)}},
+	{"GOTO(1, 2, 3)", []byte{icode.GOTO, 0, 0, 0, 1, 0, 0, 0, 2, 0, 3}},
+	{"MATCH('g')", []byte{icode.MATCH, 'g'}},
+
+	// 子块与表达式
+	{"IF{PASS} ELSE{FAIL}", []byte{icode.IF, 1, icode.PASS, icode.ELSE, 1, icode.FAIL}},
+	{"BLOCK{NOP}", []byte{icode.BLOCK, 1, icode.NOP}},
+	{"(1 + 2 / 3)", []byte{icode.Expr, 8, icode.Uint8, 1, icode.Add, icode.Uint8, 2, icode.Div, icode.Uint8, 3}},
+	{"(1 - -2)", []byte{icode.Expr, 5, icode.Uint8, 1, icode.Sub, icode.Uint8n, 2}},
+	{"(2 ** 3 % 5)", []byte{icode.Expr, 8, icode.Uint8, 2, icode.POW, icode.Uint8, 3, icode.MOD, icode.Uint8, 5}},
+	{"(!true || 1<=2)", []byte{icode.Expr, 8, icode.NOT, icode.TRUE, icode.EITHER, icode.Uint8, 1, icode.LTE, icode.Uint8, 2}},
+
+	// 模式区
+	{"MODEL(1){ _ #(1) ... }", []byte{icode.MODEL, 0x80, 4, icode.Wildcard, icode.ValPick, 1, icode.WildLump}},
+	{"MODEL{ !{Int} !{1, 5} }", []byte{icode.MODEL, 0, 5, icode.TypeIs, byte(instor.TypeisInt), icode.WithinInt, 2, 10}},
+	{"MODEL{ RE{!/x/g} }", []byte{icode.MODEL, 0, 4, icode.RE, 0x80 | 'g', 1, 'x'}},
+	{"MODEL{ ?{NOP} }", []byte{icode.MODEL, 0, 3, icode.Wildlist, 1, icode.NOP}},
+	{"MODEL{ ?(2) OUT{1, Amount} }", []byte{icode.MODEL, 0, 4, icode.Wildpart, 2, icode.OUT, byte(instor.OutAmount)}},
+	{"MODEL{ ?(2) DATA{0x0102} }", []byte{icode.MODEL, 0, 3, icode.Wildpart, 2, icode.DATA8}},
+}
+
+func TestAssemble(t *testing.T) {
+	for _, tt := range assembleTests {
+		got, err := asm.Assemble([]byte(tt.src))
+		if err != nil {
+			t.Errorf("Assemble(%q): %v", tt.src, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("Assemble(%q) = %x, want %x", tt.src, got, tt.want)
+		}
+	}
+}
+
+// 汇编结果可被指令解析器逐个正确解析。
+func TestAssembleParse(t *testing.T) {
+	src := `
+	// 注释
+	"hello" 1000 DATA{"xyz"}
+	EACH{ ${Value} PRINT }
+	SWITCH{ CASE{1} DEFAULT{2} }
+	FN_CHECKSIG{All}
+	`
+	code, err := asm.Assemble([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{icode.TEXT8, icode.Uint63, icode.DATA8, icode.EACH, icode.SWITCH, icode.FN_CHECKSIG}
+
+	for i, c := range want {
+		if len(code) == 0 {
+			t.Fatalf("code ended at #%d", i)
+		}
+		ins := instor.Get(code)
+		if ins.Code != c {
+			t.Fatalf("#%d code = %s, want %s", i, instor.CodeNames[ins.Code], instor.CodeNames[c])
+		}
+		code = code[ins.Size:]
+	}
+	if len(code) != 0 {
+		t.Errorf("trailing bytes: %x", code)
+	}
+}
+
+// 出错位置测试。
+var errorTests = []struct {
+	src       string
+	line, col int
+}{
+	{"NOP\n  BADNAME", 2, 3},
+	{"IF{ NOP", 1, 8},
+	{"SHIFT (2)", 1, 1},
+	{"SHIFT(256)", 1, 7},
+	{"ENV{Nothing}", 1, 5},
+	{"_", 1, 1},
+	{"MODEL{ ?(1) _ }", 1, 13},
+	{`"abc`, 1, 1},
+	{"1 * 2", 1, 3},
+	{"( )", 1, 3},
+	{"( PASS 2.5 )", 1, 8},
+	{"1 (2 + (3 *))", 1, 12},
+	{"(1 + 2\n  3)", 2, 3},
+	{"Expr{ 1 + }", 1, 11},
+}
+
+func TestAssembleError(t *testing.T) {
+	for _, tt := range errorTests {
+		_, err := asm.Assemble([]byte(tt.src))
+
+		var e *asm.Error
+		if !errors.As(err, &e) {
+			t.Errorf("Assemble(%q): expect *asm.Error, got %v", tt.src, err)
+			continue
+		}
+		if e.Line != tt.line || e.Col != tt.col {
+			t.Errorf("Assemble(%q): error at %d:%d, want %d:%d (%s)", tt.src, e.Line, e.Col, tt.line, tt.col, e.Msg)
+		}
+	}
+}
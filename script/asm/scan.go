@@ -0,0 +1,305 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package asm
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 词法单元类型。
+const (
+	tokEOF    = iota // 源码结束
+	tokIdent         // 标识符（指令名、成员名）
+	tokInt           // 整数
+	tokFloat         // 浮点数
+	tokString        // 字符串
+	tokChar          // 字符
+	tokRegexp        // 正则表达式
+	tokPunct         // 符号
+)
+
+// 词法单元。
+// 字符串、字符和正则式的 text 为解码后的内容。
+type token struct {
+	kind int    // 单元类型
+	text string // 文本值
+	flag string // 正则式后缀标记（g|G）
+	pos  Pos    // 源码位置
+	off  int    // 起始偏移（字节）
+	end  int    // 结束偏移（字节）
+}
+
+// 是否为目标符号。
+func (t token) is(s string) bool {
+	return t.kind == tokPunct && t.text == s
+}
+
+// 源码位置。
+// 行列号都从1开始，列号按字符计。
+type Pos struct {
+	Line int
+	Col  int
+}
+
+// 词法扫描器。
+type scanner struct {
+	src  []byte // 源码
+	off  int    // 当前偏移
+	line int    // 当前行
+	col  int    // 当前列
+}
+
+func newScanner(src []byte) *scanner {
+	return &scanner{src: src, line: 1, col: 1}
+}
+
+// 查看当前字符。
+// 抵达末尾时返回-1。
+func (s *scanner) peek() rune {
+	if s.off >= len(s.src) {
+		return -1
+	}
+	r, _ := utf8.DecodeRune(s.src[s.off:])
+	return r
+}
+
+// 查看当前字符之后的第n个字节。
+func (s *scanner) peekByte(n int) byte {
+	if s.off+n >= len(s.src) {
+		return 0
+	}
+	return s.src[s.off+n]
+}
+
+// 前进一个字符。
+func (s *scanner) step() rune {
+	r, n := utf8.DecodeRune(s.src[s.off:])
+	s.off += n
+
+	if r == '\n' {
+		s.line++
+		s.col = 1
+	} else {
+		s.col++
+	}
+	return r
+}
+
+// 跳过空白和注释。
+// 注释为 // 开始直到行尾。
+func (s *scanner) skip() {
+	for s.off < len(s.src) {
+		r := s.peek()
+
+		switch {
+		case unicode.IsSpace(r):
+			s.step()
+		case r == '/' && s.peekByte(1) == '/':
+			for s.off < len(s.src) && s.peek() != '\n' {
+				s.step()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// 扫描下一个词法单元。
+// expr 为是否处于表达式内，此时斜线为除号而非正则式。
+func (s *scanner) next(expr bool) token {
+	s.skip()
+	t := token{pos: Pos{s.line, s.col}, off: s.off}
+
+	r := s.peek()
+	switch {
+	case r < 0:
+		t.kind = tokEOF
+	case isIdent(r):
+		t.kind, t.text = tokIdent, s.ident()
+		// 单独的下划线为通配符号
+		if t.text == "_" {
+			t.kind = tokPunct
+		}
+	case '0' <= r && r <= '9':
+		t.kind, t.text = s.number()
+	case r == '"' || r == '`':
+		t.kind, t.text = tokString, s.quoted(t.pos)
+	case r == '\'':
+		t.kind, t.text = tokChar, s.char(t.pos)
+	case r == '/' && !expr:
+		t.kind = tokRegexp
+		t.text, t.flag = s.regexp(t.pos)
+	case expr && s.operator() != "":
+		op := s.operator()
+		s.off += len(op)
+		s.col += len(op)
+		t.kind, t.text = tokPunct, op
+	case r == '.':
+		if s.peekByte(1) != '.' || s.peekByte(2) != '.' {
+			fail(t.pos, _T("无效的符号：%q"), r)
+		}
+		s.off += 3
+		s.col += 3
+		t.kind, t.text = tokPunct, "..."
+	case strings.ContainsRune(puncts, r):
+		s.step()
+		t.kind, t.text = tokPunct, string(r)
+	default:
+		fail(t.pos, _T("无效的字符：%q"), r)
+	}
+	t.end = s.off
+	return t
+}
+
+// 单字符符号集。
+const puncts = "@~$#&?!(){},*/+-"
+
+// 表达式运算符别名。
+// 多字符者在前，以便优先匹配。
+var exprOpers = []string{"**", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||", "%", "<", ">", "!"}
+
+// 匹配表达式运算符别名。
+// 未匹配时返回空串。
+func (s *scanner) operator() string {
+	for _, op := range exprOpers {
+		if strings.HasPrefix(string(s.src[s.off:min(s.off+2, len(s.src))]), op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// 扫描标识符。
+func (s *scanner) ident() string {
+	i := s.off
+	for isIdent(s.peek()) || isDigit(s.peek()) {
+		s.step()
+	}
+	return string(s.src[i:s.off])
+}
+
+// 扫描数值。
+// 十六进制形式（0x...）总是视为整数。
+func (s *scanner) number() (int, string) {
+	i := s.off
+	kind := tokInt
+	hex := s.peekByte(0) == '0' && (s.peekByte(1) == 'x' || s.peekByte(1) == 'X')
+
+	for {
+		r := s.peek()
+
+		switch {
+		case isDigit(r) || isIdent(r):
+			if !hex && (r == 'e' || r == 'E') {
+				kind = tokFloat
+				s.step()
+				// 指数符号
+				if c := s.peek(); c == '+' || c == '-' {
+					s.step()
+				}
+				continue
+			}
+		case r == '.' && !hex:
+			kind = tokFloat
+		default:
+			return kind, string(s.src[i:s.off])
+		}
+		s.step()
+	}
+}
+
+// 扫描字符串。
+// 支持双引号（转义）和反引号（原始）两种形式。
+func (s *scanner) quoted(pos Pos) string {
+	i := s.off
+	q := s.step()
+
+	for {
+		r := s.peek()
+
+		switch {
+		case r < 0 || (r == '\n' && q == '"'):
+			fail(pos, _T("字符串没有结束"))
+		case r == '\\' && q == '"':
+			s.step()
+			if s.peek() < 0 {
+				fail(pos, _T("字符串没有结束"))
+			}
+		case r == q:
+			s.step()
+			v, err := strconv.Unquote(string(s.src[i:s.off]))
+			if err != nil {
+				fail(pos, _T("无效的字符串：%s"), err)
+			}
+			return v
+		}
+		s.step()
+	}
+}
+
+// 扫描字符。
+// 返回值为字符本身的文本。
+func (s *scanner) char(pos Pos) string {
+	i := s.off
+	s.step()
+
+	for {
+		r := s.peek()
+
+		switch {
+		case r < 0 || r == '\n':
+			fail(pos, _T("字符没有结束"))
+		case r == '\\':
+			s.step()
+		case r == '\'':
+			s.step()
+			v, err := strconv.Unquote(string(s.src[i:s.off]))
+			if err != nil || utf8.RuneCountInString(v) != 1 {
+				fail(pos, _T("无效的字符：%s"), s.src[i:s.off])
+			}
+			return v
+		}
+		s.step()
+	}
+}
+
+// 扫描正则表达式。
+// 格式：/.../[gG]，内部的斜线需要转义（\/）。
+// 返回正则式文本和后缀标记。
+func (s *scanner) regexp(pos Pos) (string, string) {
+	s.step()
+	var buf strings.Builder
+
+	for {
+		r := s.peek()
+
+		switch {
+		case r < 0 || r == '\n':
+			fail(pos, _T("正则表达式没有结束"))
+		case r == '\\' && s.peekByte(1) == '/':
+			s.step()
+		case r == '/':
+			s.step()
+			i := s.off
+			for c := s.peek(); c == 'g' || c == 'G'; c = s.peek() {
+				s.step()
+			}
+			return buf.String(), string(s.src[i:s.off])
+		}
+		buf.WriteRune(s.step())
+	}
+}
+
+// 是否为标识符字符。
+func isIdent(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// 是否为十进制数字。
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
@@ -0,0 +1,185 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+// Package debug 脚本单步调试器。
+// 作为执行监视器（ibase.Monitor）挂接到执行器，在指令执行之前按断点或单步设置暂停，
+// 暂停时将执行器的状态快照交给外部的处理函数，由其决定后续的执行方式。
+//
+// 用法：
+//
+//	d := debug.New(func(f *debug.Frame) debug.Action {
+//		fmt.Println(f.Offset, f.Name, f.Stack)
+//		return debug.Step
+//	})
+//	d.BreakCode(icode.PASS)
+//	a.SetMonitor(d)
+//	inst.Execute(ctx, a)
+package debug
+
+import (
+	"errors"
+
+	"github.com/cxio/suite/locale"
+	"github.com/cxio/suite/script/ibase"
+	"github.com/cxio/suite/script/instor"
+)
+
+// 本地化文本获取。
+var _T = locale.GetText
+
+// 调试器中止执行。
+// 以 panic 抛出，由执行入口捕获（inst.Execute 中为一般运行错误）。
+var ErrStopped = errors.New(_T("调试器中止了执行"))
+
+// 执行方式。
+// 暂停处理函数的返回值，决定何时再次暂停。
+type Action int
+
+// 执行方式定义。
+const (
+	Continue Action = iota // 继续，直到下一个断点
+	Step                   // 单步，进入子块以及 GOTO/JUMP/EVAL 的代码
+	StepOver               // 单步，越过子块（块内不暂停）
+	StepOut                // 执行到当前块结束，在上层的下一条指令暂停
+	Stop                   // 中止执行
+)
+
+// 执行器状态快照。
+// 各集合皆为副本，修改不影响执行。
+type Frame struct {
+	ID      []byte      // 脚本标识
+	Offset  int         // 指令偏移（在所属脚本中）
+	Code    int         // 指令码
+	Name    string      // 指令名称
+	Depth   int         // 嵌套深度
+	Kind    int         // 执行域类型（ibase.FrameXXX）
+	Stack   []any       // 数据栈
+	Args    []any       // 实参区
+	Scope   []any       // 局部域
+	Loop    []any       // 循环变量（Value, Key, Data, Size），循环外为nil
+	Globals map[int]any // 全局变量
+	Ifs     *bool       // IF 状态值（nil 为未设置）
+	Gotos   int         // GOTO 计数
+	Jumps   int         // JUMP 计数
+	Expr    int         // 表达式嵌套深度
+	Gas     int64       // 已消耗成本
+}
+
+// 断点位置。
+type point struct {
+	id  string // 脚本标识，空串匹配任意脚本
+	off int    // 指令偏移
+}
+
+// 调试器。
+// 实现 ibase.Monitor 接口。
+// 注：
+// 一个调试器仅用于单个脚本的执行，非并发安全。
+type Debugger struct {
+	pause  func(*Frame) Action // 暂停处理
+	points map[point]bool      // 偏移断点
+	codes  [256]bool           // 指令码断点
+	mode   Action              // 当前执行方式
+	depth  int                 // 设置执行方式时的深度
+}
+
+// 新建一个调试器。
+// pause 为暂停时的处理函数，返回后续的执行方式。
+// 初始为 Step 方式，即在首条指令之前暂停。
+func New(pause func(*Frame) Action) *Debugger {
+	return &Debugger{
+		pause:  pause,
+		points: make(map[point]bool),
+		mode:   Step,
+	}
+}
+
+// 设置执行方式。
+// 通常在执行之前调用，如设置为 Continue 以直接运行到首个断点。
+func (d *Debugger) Resume(act Action) {
+	d.mode = act
+}
+
+// 设置偏移断点。
+// id 为目标脚本标识，nil 表示任意脚本（含 GOTO/JUMP 引入的）。
+func (d *Debugger) BreakAt(id []byte, off int) {
+	d.points[point{string(id), off}] = true
+}
+
+// 设置指令码断点。
+func (d *Debugger) BreakCode(c int) {
+	d.codes[c] = true
+}
+
+// 清除全部断点。
+func (d *Debugger) Clear() {
+	clear(d.points)
+	d.codes = [256]bool{}
+}
+
+// 指令执行前的检查。
+// 满足单步条件或命中断点时暂停。
+func (d *Debugger) Before(a *ibase.Actuator) {
+	depth := a.Depth()
+
+	if !d.stepped(depth) && !d.hit(a.Position()) {
+		return
+	}
+	act := d.pause(Snapshot(a))
+
+	if act == Stop {
+		panic(ErrStopped)
+	}
+	d.mode, d.depth = act, depth
+}
+
+// 是否满足单步暂停条件。
+func (d *Debugger) stepped(depth int) bool {
+	switch d.mode {
+	case Step:
+		return true
+	case StepOver:
+		return depth <= d.depth
+	case StepOut:
+		return depth < d.depth
+	}
+	return false
+}
+
+// 是否命中断点。
+func (d *Debugger) hit(pos ibase.Position) bool {
+	if d.codes[pos.Code] {
+		return true
+	}
+	return d.points[point{string(pos.ID), pos.Offset}] || d.points[point{"", pos.Offset}]
+}
+
+// 创建执行器的状态快照。
+// 当前指令为执行器最近标记的位置。
+func Snapshot(a *ibase.Actuator) *Frame {
+	pos := a.Position()
+	gotos, jumps := a.Counts()
+
+	f := &Frame{
+		ID:      pos.ID,
+		Offset:  pos.Offset,
+		Code:    pos.Code,
+		Name:    instor.CodeNames[pos.Code],
+		Depth:   a.Depth(),
+		Kind:    a.FrameKind(),
+		Stack:   a.StackData(),
+		Args:    a.ArgsData(),
+		Scope:   a.ScopeData(),
+		Loop:    a.LoopData(),
+		Globals: a.GlobalData(),
+		Gotos:   gotos,
+		Jumps:   jumps,
+		Expr:    a.ExprDepth(),
+		Gas:     a.GasUsed(),
+	}
+	if a.Ifs != nil {
+		v := *a.Ifs
+		f.Ifs = &v
+	}
+	return f
+}
@@ -0,0 +1,127 @@
+package debug_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cxio/suite/script/asm"
+	"github.com/cxio/suite/script/debug"
+	"github.com/cxio/suite/script/ibase"
+	"github.com/cxio/suite/script/icode"
+	"github.com/cxio/suite/script/inst"
+)
+
+// 汇编并创建执行器。
+func actuator(t *testing.T, src string) *inst.Actuator {
+	t.Helper()
+
+	code, err := asm.Assemble([]byte(src))
+	if err != nil {
+		t.Fatalf("Assemble(%q): %v", src, err)
+	}
+	return ibase.NewActuator([]byte("test"), code, nil, ibase.NewEnvs(nil, nil, 0), 1)
+}
+
+// 按动作序列运行，返回各暂停点的偏移。
+func run(t *testing.T, src string, setup func(*debug.Debugger), acts ...debug.Action) ([]int, error) {
+	var offs []int
+
+	d := debug.New(func(f *debug.Frame) debug.Action {
+		offs = append(offs, f.Offset)
+		if len(acts) == 0 {
+			return debug.Continue
+		}
+		act := acts[0]
+		acts = acts[1:]
+		return act
+	})
+	if setup != nil {
+		setup(d)
+	}
+	a := actuator(t, src)
+	a.SetMonitor(d)
+
+	_, err := inst.Execute(context.Background(), a)
+	return offs, err
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStep(t *testing.T) {
+	// 0:1  2:BLOCK{ 4:NOP 5:NOP } 6:true 7:PASS
+	const src = `1 BLOCK{ NOP NOP } true PASS`
+
+	tests := []struct {
+		acts []debug.Action
+		want []int
+	}{
+		{[]debug.Action{debug.Step, debug.Step, debug.Step, debug.Step, debug.Step, debug.Step}, []int{0, 2, 4, 5, 6, 7}},
+		{[]debug.Action{debug.Step, debug.StepOver, debug.StepOver, debug.StepOver}, []int{0, 2, 6, 7}},
+		{[]debug.Action{debug.Step, debug.Step, debug.StepOut, debug.Continue}, []int{0, 2, 4, 6}},
+		{[]debug.Action{debug.Continue}, []int{0}},
+	}
+	for i, tt := range tests {
+		offs, err := run(t, src, nil, tt.acts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equal(offs, tt.want) {
+			t.Errorf("#%d: paused at %v, want %v", i, offs, tt.want)
+		}
+	}
+}
+
+func TestBreakpoint(t *testing.T) {
+	const src = `1 BLOCK{ NOP 2 } true PASS`
+
+	offs, err := run(t, src, func(d *debug.Debugger) {
+		d.Resume(debug.Continue)
+		d.BreakAt(nil, 5)
+		d.BreakCode(icode.PASS)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(offs, []int{5, 8}) {
+		t.Errorf("breakpoints hit at %v", offs)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	var got *debug.Frame
+
+	d := debug.New(func(f *debug.Frame) debug.Action {
+		got = f
+		return debug.Continue
+	})
+	d.Resume(debug.Continue)
+	d.BreakCode(icode.PASS)
+
+	a := actuator(t, `1 2 true PASS`)
+	a.SetMonitor(d)
+
+	if _, err := inst.Execute(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Name != "PASS" || len(got.Stack) != 3 || got.Depth != 0 {
+		t.Errorf("snapshot: %+v", got)
+	}
+}
+
+func TestStop(t *testing.T) {
+	_, err := run(t, `1 2 true PASS`, nil, debug.Step, debug.Stop)
+	if !errors.Is(err, debug.ErrStopped) {
+		t.Errorf("expect stopped, got %v", err)
+	}
+}
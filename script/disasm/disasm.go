@@ -0,0 +1,439 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+// Package disasm 脚本反汇编器。
+// 将指令字节序列还原为带偏移注记的助记符清单，格式与 asm 包的源码语法兼容。
+//
+// 清单每行以4位十六进制偏移开头（相对于脚本起点），子语句块缩进显示。
+// 去除偏移栏后的文本可被 asm.Assemble 重新汇编为相同的字节序列，
+// 唯一的例外是模式区内局部通配（?(n)）之后的指令，它们的部分内容已被省略，
+// 因此按原始片段显示（省略部分为 _），仅供阅读。
+package disasm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cxio/suite/locale"
+	"github.com/cxio/suite/script/icode"
+	"github.com/cxio/suite/script/instor"
+)
+
+// 本地化文本获取。
+var _T = locale.GetText
+
+// 单层缩进。
+const indent = "  "
+
+// 偏移栏宽度（含间隔）。
+const gutter = 6
+
+// Fprint 输出脚本的反汇编清单。
+// 从脚本的当前位置开始，不改变脚本本身的状态。
+// 如果脚本格式错误（如截断），已输出的部分保留，并返回出错位置的错误信息。
+func Fprint(w io.Writer, s *instor.Script) error {
+	p := &printer{}
+	err := p.run(s.Bytes(), s.Offset())
+
+	if _, e := io.WriteString(w, p.buf.String()); e != nil {
+		return e
+	}
+	return err
+}
+
+// Text 获取脚本的反汇编清单。
+// code 为完整的脚本指令序列。
+func Text(code []byte) (string, error) {
+	var buf strings.Builder
+	err := Fprint(&buf, instor.NewScript(code))
+
+	return buf.String(), err
+}
+
+// 清单打印器。
+type printer struct {
+	buf   strings.Builder
+	depth int // 缩进层级
+	expr  int // 表达式嵌套
+	model int // 模式区嵌套
+	off   int // 当前指令偏移
+}
+
+// 执行反汇编。
+// 解析器对非法脚本抛出异常，这里转换为错误。
+func (p *printer) run(code []byte, base int) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf(_T("偏移 %d 处的指令解析失败：%v"), p.off, v)
+		}
+	}()
+	// 限制容量，截断的脚本不会越界读取
+	p.list(code[:len(code):len(code)], base)
+	return
+}
+
+// 输出一行。
+// off 为负值时偏移栏留空。
+func (p *printer) line(off int, text string) {
+	if off < 0 {
+		p.buf.WriteString(strings.Repeat(" ", gutter))
+	} else {
+		fmt.Fprintf(&p.buf, "%04x  ", off)
+	}
+	p.buf.WriteString(strings.Repeat(indent, p.depth))
+	p.buf.WriteString(text)
+	p.buf.WriteByte('\n')
+}
+
+// 反汇编指令序列。
+// base 为序列起点在脚本中的偏移。
+func (p *printer) list(code []byte, base int) {
+	for i := 0; i < len(code); {
+		p.off = base + i
+		ins := instor.Get(code[i:])
+
+		// 局部通配的目标指令为残缺形式
+		if ins.Code == icode.Wildpart && p.model > 0 && i+ins.Size < len(code) {
+			p.line(p.off, fmt.Sprintf("?(%d)", ins.Args[0].(int)))
+			i += ins.Size
+			p.off = base + i
+			n := p.wild(code[i:], byte(ins.Args[0].(int)))
+			i += n
+			continue
+		}
+		p.inst(ins, base+i)
+		i += ins.Size
+	}
+}
+
+// 输出单个指令。
+func (p *printer) inst(ins *instor.Insted, off int) {
+	c := ins.Code
+
+	switch c {
+	case icode.MODEL:
+		p.model++
+		p.block(off, modelHead(ins), "}", ins.Data.([]byte), off+ins.Size-len(ins.Data.([]byte)))
+		p.model--
+		return
+
+	case icode.Expr:
+		p.expr++
+		p.block(off, "(", ")", ins.Data.([]byte), off+ins.Size-len(ins.Data.([]byte)))
+		p.expr--
+		return
+
+	case icode.CODE:
+		data := ins.Data.(*instor.Script).Source()
+		p.block(off, name(c)+"{", "}", data, off+ins.Size-len(data))
+		return
+
+	case icode.IF, icode.ELSE, icode.SWITCH, icode.CASE, icode.DEFAULT,
+		icode.EACH, icode.BLOCK, icode.MAP, icode.FILTER:
+		data := ins.Data.([]byte)
+		p.block(off, name(c)+"{", "}", data, off+ins.Size-len(data))
+		return
+
+	case icode.Wildlist:
+		data := ins.Data.([]byte)
+		p.block(off, "?{", "}", data, off+ins.Size-len(data))
+		return
+	}
+	text, note := p.render(ins)
+
+	if note != "" {
+		text += "  // " + note
+	}
+	p.line(off, text)
+}
+
+// 输出子语句块。
+// 空块在单行内显示。
+func (p *printer) block(off int, head, tail string, data []byte, base int) {
+	if len(data) == 0 {
+		p.line(off, head+tail)
+		return
+	}
+	p.line(off, head)
+	p.depth++
+
+	// 块内不继承表达式状态
+	x := p.expr
+	if head != "(" {
+		p.expr = 0
+	}
+	p.list(data, base)
+
+	p.expr = x
+	p.depth--
+	p.line(-1, tail)
+}
+
+// 渲染非块类指令。
+// 返回指令文本和可选的注释说明。
+func (p *printer) render(ins *instor.Insted) (string, string) {
+	c := ins.Code
+
+	switch c {
+	case icode.NIL:
+		return "nil", ""
+	case icode.TRUE:
+		return "true", ""
+	case icode.FALSE:
+		return "false", ""
+
+	case icode.Uint8:
+		return strconv.FormatInt(ins.Data.(instor.Int), 10), ""
+	case icode.Uint8n:
+		return narrow(c, ins.Data.(instor.Int), -math.MaxUint8, -1), ""
+	case icode.Uint63:
+		return narrow(c, ins.Data.(instor.Int), math.MaxUint8+1, math.MaxInt64), ""
+	case icode.Uint63n:
+		return narrow(c, ins.Data.(instor.Int), math.MinInt64, -math.MaxUint8-1), ""
+	case icode.Byte:
+		return fmt.Sprintf("Byte{%d}", ins.Data.(instor.Byte)), ""
+	case icode.Rune:
+		return strconv.QuoteRune(ins.Data.(instor.Rune)), ""
+	case icode.Float32:
+		// 按 float64 显示，确保汇编时数值相同
+		return float(ins.Data.(instor.Float)), ""
+	case icode.Float64:
+		v := ins.Data.(instor.Float)
+		if float64(float32(v)) == v {
+			return "Float64{" + float(v) + "}", ""
+		}
+		return float(v), ""
+	case icode.DATE:
+		t := ins.Data.(time.Time)
+		return fmt.Sprintf("DATE{%d}", t.UnixMilli()), t.UTC().Format(time.RFC3339Nano)
+	case icode.BigInt:
+		return "BigInt{" + ins.Data.(*big.Int).String() + "}", ""
+	case icode.DATA8, icode.DATA16:
+		d := ins.Data.(instor.Bytes)
+		if c == icode.DATA16 && len(d) <= math.MaxUint8 {
+			return "DATA16{" + hexData(d) + "}", ""
+		}
+		return "DATA{" + hexData(d) + "}", ""
+	case icode.TEXT8, icode.TEXT16:
+		s := ins.Data.(instor.String)
+		if c == icode.TEXT16 && len(s) <= math.MaxUint8 {
+			return "TEXT16{" + strconv.Quote(s) + "}", ""
+		}
+		return strconv.Quote(s), ""
+	case icode.RegExp:
+		return "/" + slashed(ins.Data.(*regexp.Regexp).String()) + "/", ""
+
+	case icode.Capture:
+		return "@", ""
+	case icode.Bring:
+		return "~", ""
+	case icode.ScopeAdd:
+		return "$", ""
+	case icode.ScopeVal:
+		return fmt.Sprintf("$(%d)", ins.Args[0].(int)), ""
+	case icode.LoopVal:
+		return "$" + selector(instor.LoopNames, ins.Args[0].(int)), ""
+
+	case icode.Mul, icode.Div, icode.Add, icode.Sub, icode.MOD, icode.POW, icode.LMOV, icode.RMOV,
+		icode.EQUAL, icode.NEQUAL, icode.LT, icode.LTE, icode.GT, icode.GTE, icode.BOTH, icode.EITHER, icode.NOT:
+		if p.expr > 0 {
+			return __Opers[c], ""
+		}
+	case icode.GOTO, icode.JUMP:
+		return fmt.Sprintf("%s(%d, %d, %d)", name(c), ins.Args[0], ins.Args[1], ins.Args[2]), ""
+	case icode.OUT:
+		i, n := ins.Args[0].(int), ins.Args[1].(int)
+		return fmt.Sprintf("OUT{%d, %s}", i, member(instor.OutNames, n)), ""
+
+	// 模式指令
+	case icode.ValPick:
+		return fmt.Sprintf("#(%d)", ins.Args[0].(int)), ""
+	case icode.Wildcard:
+		return "_", ""
+	case icode.Wildnum:
+		return fmt.Sprintf("_(%d)", ins.Args[0].(int)), ""
+	case icode.Wildpart:
+		return fmt.Sprintf("?(%d)", ins.Args[0].(int)), ""
+	case icode.TypeIs:
+		return "!" + selector(instor.TypeNames, ins.Args[0].(int)), ""
+	case icode.WithinInt:
+		return fmt.Sprintf("!{%d, %d}", ins.Args[0], ins.Args[1]), ""
+	case icode.WithinFloat:
+		a := ins.Args
+		return fmt.Sprintf("!{%s, %s, %s}", float(a[0].(float64)), float(a[1].(float64)), float(a[2].(float64))), ""
+	case icode.RE:
+		return "RE{" + reText(ins) + "}", ""
+	case icode.RePick:
+		return fmt.Sprintf("&(%d)", ins.Args[0].(int)), ""
+	case icode.WildLump:
+		return "...", ""
+
+	// 扩展类
+	case icode.MO_X, icode.EX_INST, icode.EX_PRIV:
+		return exten(ins), ""
+	}
+	if names, ok := __Selectors[c]; ok {
+		return name(c) + selector(names, ins.Args[0].(int)), ""
+	}
+	if len(ins.Args) == 1 {
+		return fmt.Sprintf("%s(%d)", name(c), ins.Args[0].(int)), ""
+	}
+	return name(c), ""
+}
+
+//
+// 渲染辅助
+///////////////////////////////////////////////////////////////////////////////
+
+// 获取指令名称。
+// 未定义的指令码显示为 UNKNOWN(n)。
+func name(c int) string {
+	if s := instor.CodeNames[c]; s != "" {
+		return s
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", c)
+}
+
+// 整数值显示。
+// 值不在指令的规范范围时，显式标注指令名（非最窄编码）。
+func narrow(c int, v, min, max int64) string {
+	if v < min || v > max {
+		return fmt.Sprintf("%s{%d}", name(c), v)
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+// 浮点数显示。
+// 保证文本可被识别为浮点数（含小数点或指数）。
+func float(v float64) string {
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+
+	if !strings.ContainsAny(s, ".eEIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// 字节序列显示（十六进制）。
+func hexData(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("0x%x", b)
+}
+
+// 正则式中的斜线转义。
+func slashed(s string) string {
+	return strings.ReplaceAll(s, "/", `\/`)
+}
+
+// 名称选择器显示：{Name}
+// 首个名称为空（默认值）时显示为 {}。
+func selector(names []string, n int) string {
+	if n == 0 && len(names) > 0 && names[0] == "" {
+		return "{}"
+	}
+	return "{" + member(names, n) + "}"
+}
+
+// 获取成员名称。
+// 不在名称表中时显示为数值。
+func member(names []string, n int) string {
+	if n >= 0 && n < len(names) && names[n] != "" {
+		return names[n]
+	}
+	return strconv.Itoa(n)
+}
+
+// 模式区头部。
+func modelHead(ins *instor.Insted) string {
+	if ins.Args[0].(bool) {
+		return "MODEL(1){"
+	}
+	return "MODEL{"
+}
+
+// 正则匹配内容：[!]/.../[g|G]
+func reText(ins *instor.Insted) string {
+	f := ins.Args[0].(int)
+	var b strings.Builder
+
+	if f&0b1000_0000 != 0 {
+		b.WriteByte('!')
+	}
+	b.WriteString("/" + slashed(ins.Data.(*regexp.Regexp).String()) + "/")
+
+	if g := f &^ 0b1000_0000; g != 0 {
+		b.WriteByte(byte(g))
+	}
+	return b.String()
+}
+
+// 扩展类指令：NAME(i, m)
+// 扩展数据按大端整数显示。
+func exten(ins *instor.Insted) string {
+	i := ins.Args[0].(int)
+	d, _ := ins.Data.([]byte)
+
+	if len(d) == 0 {
+		return fmt.Sprintf("%s(%d)", name(ins.Code), i)
+	}
+	if len(d) > 8 {
+		return fmt.Sprintf("%s(%d, 0x%x)", name(ins.Code), i, d)
+	}
+	var buf [8]byte
+	copy(buf[8-len(d):], d)
+
+	return fmt.Sprintf("%s(%d, %d)", name(ins.Code), i, binary.BigEndian.Uint64(buf[:]))
+}
+
+// 表达式运算符显示。
+var __Opers = map[int]string{
+	icode.Mul:    "*",
+	icode.Div:    "/",
+	icode.Add:    "+",
+	icode.Sub:    "-",
+	icode.MOD:    "%",
+	icode.POW:    "**",
+	icode.LMOV:   "<<",
+	icode.RMOV:   ">>",
+	icode.EQUAL:  "==",
+	icode.NEQUAL: "!=",
+	icode.LT:     "<",
+	icode.LTE:    "<=",
+	icode.GT:     ">",
+	icode.GTE:    ">=",
+	icode.BOTH:   "&&",
+	icode.EITHER: "||",
+	icode.NOT:    "!",
+}
+
+// 名称选择器指令配置。
+// 指令码 => 名称表。
+var __Selectors = map[int][]string{
+	icode.ANYS:         instor.TypeNames,
+	icode.ENV:          instor.EnvNames,
+	icode.IN:           instor.InNames,
+	icode.INOUT:        instor.OutNames,
+	icode.XFROM:        instor.XFromNames,
+	icode.SYS_TIME:     instor.TimeNames,
+	icode.FN_CHECKSIG:  instor.SigNames,
+	icode.FN_MCHECKSIG: instor.SigNames,
+	icode.FN_HASH224:   instor.HashAlgo,
+	icode.FN_HASH256:   instor.HashAlgo,
+	icode.FN_HASH384:   instor.HashAlgo,
+	icode.FN_HASH512:   instor.HashAlgo,
+	icode.FN_X:         instor.FnXNames,
+	icode.MO_RE:        instor.MOREMethod,
+	icode.MO_TIME:      instor.MOTimeMethod,
+	icode.MO_MATH:      nil,
+	icode.MO_CRYPT:     nil,
+	icode.EX_FN:        instor.ExFnNames,
+}
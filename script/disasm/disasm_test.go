@@ -0,0 +1,105 @@
+package disasm_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cxio/suite/script/asm"
+	"github.com/cxio/suite/script/disasm"
+)
+
+// 去除清单的偏移栏。
+func strip(list string) string {
+	var b strings.Builder
+
+	for _, s := range strings.Split(list, "\n") {
+		if len(s) > 6 {
+			b.WriteString(s[6:])
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// 反汇编结果可重新汇编为相同的字节序列。
+var roundTrips = []string{
+	`0 255 -1 -255 256 -256 1000000 -9223372036854775808`,
+	`BigInt{18446744073709551616} Uint63{5} Uint8n{0}`,
+	`1.5 0.1 Float64{2.5} 'x' '中' Byte{7} DATE{1700000000000}`,
+	`"hello\n" TEXT16{"x"} DATA{0xdeadbeef} DATA{} DATA16{0x01}`,
+	`/a\/b+/ nil true false`,
+	`@ ~ $ $(-2) ${Key} SHIFT(3) SUBSTR(300) GOTO(1, 2, 3) JUMP(0, 0, 0)`,
+	`ENV{Height} OUT{2, Receiver} IN{Amount} INOUT{Timestamp} XFROM{Source} SYS_TIME{} SYS_TIME{Year}`,
+	`FN_HASH256{blake2} FN_CHECKSIG{All} ANYS{String} MO_X(1, 2) EX_INST(300, 1) EX_PRIV(5)`,
+	`IF{ ENV{Height} 100 GT } ELSE{ FAIL } SWITCH{ CASE{1} DEFAULT{} } BLOCK{ EACH{ ${Value} PRINT } }`,
+	`MAP{ (${Value} * 2 + 1) } FILTER{ ${Value} } CODE{ PASS }`,
+	`(1 - (2 / 3)) Mul Div`,
+	`(1 << 2 >= 3 && !(2 ** 3 % 5 != 3) || "a" < "b") MOD NOT`,
+	`MODEL(1){ _ _(2) #(1) !{Int} !{1, 5} !{1.5, 2.5, 0.5} RE{!/x+/g} &(1) ?{ NOP } ... }`,
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, src := range roundTrips {
+		code, err := asm.Assemble([]byte(src))
+		if err != nil {
+			t.Fatalf("Assemble(%q): %v", src, err)
+		}
+		list, err := disasm.Text(code)
+		if err != nil {
+			t.Fatalf("Text(%q): %v", src, err)
+		}
+		back, err := asm.Assemble([]byte(strip(list)))
+		if err != nil {
+			t.Fatalf("re-assemble %q:\n%s\n%v", src, list, err)
+		}
+		if !bytes.Equal(code, back) {
+			t.Errorf("round trip %q:\n%s\ngot  %x\nwant %x", src, list, back, code)
+		}
+	}
+}
+
+func TestListing(t *testing.T) {
+	code, _ := asm.Assemble([]byte(`IF{ ENV{Height} } PASS`))
+	want := "" +
+		"0000  IF{\n" +
+		"0002    ENV{Height}\n" +
+		"      }\n" +
+		"0004  PASS\n"
+
+	list, err := disasm.Text(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list != want {
+		t.Errorf("listing:\n%s\nwant:\n%s", list, want)
+	}
+}
+
+// 局部通配的目标指令按残缺形式显示。
+func TestWildpart(t *testing.T) {
+	code, _ := asm.Assemble([]byte(`MODEL{ ?(2) OUT{1, Amount} ?(64) DATA{0x01} }`))
+
+	list, err := disasm.Text(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"OUT(_, 0)", "DATA8(1, _)"} {
+		if !strings.Contains(list, s) {
+			t.Errorf("listing lacks %q:\n%s", s, list)
+		}
+	}
+}
+
+// 截断的脚本返回错误，已解析的部分保留。
+func TestTruncated(t *testing.T) {
+	code, _ := asm.Assemble([]byte(`PASS GOTO(1, 2, 3)`))
+
+	list, err := disasm.Text(code[:5])
+	if err == nil {
+		t.Fatal("expect error for truncated script")
+	}
+	if !strings.HasPrefix(list, "0000  PASS\n") {
+		t.Errorf("partial listing lost: %q", list)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package disasm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/cxio/suite/script/instor"
+)
+
+//
+// 局部通配目标指令
+// 模式区内 ?(n) 之后的指令按通配标识省略了部分附参或数据，
+// 规则与模式匹配的捡取器相同（参考 inst/model 包）。
+///////////////////////////////////////////////////////////////////////////////
+
+// 通配标识位。
+const (
+	wildData = 0b0100_0000 // 关联数据通配
+	wildHash = 0b1000_0000 // 哈希匹配
+)
+
+// 哈希匹配时的数据长度。
+const hashSize = 20
+
+// 输出局部通配目标指令。
+// 返回指令占用的字节数。
+func (p *printer) wild(code []byte, flag byte) int {
+	c := int(code[0])
+	lay := instor.LayoutOf(c)
+	at := 1
+
+	// 读取一个附参。
+	take := func(n int) []byte {
+		if n == instor.SizeVarint {
+			_, n = binary.Uvarint(code[at:])
+		}
+		b := code[at : at+n]
+		at += n
+		return b
+	}
+	omit := func(n int) bool { return flag&(1<<n) != 0 }
+	parts := make([]string, 0, len(lay.Size)+1)
+
+	if lay.Pattern {
+		panic(fmt.Sprintf(_T("模式指令 %s 被局部通配修饰"), name(c)))
+	}
+	switch lay.Kind {
+	case instor.LayoutValue:
+		if flag&wildHash != 0 {
+			parts = append(parts, fmt.Sprintf("#0x%x", take(hashSize)))
+			break
+		}
+		if omit(1) || flag&wildData != 0 {
+			parts = append(parts, "_")
+			break
+		}
+		take(lay.Size[0])
+		return p.whole(code[:at])
+
+	case instor.LayoutBytes, instor.LayoutExten:
+		if lay.Kind == instor.LayoutBytes && flag&wildHash != 0 {
+			parts = append(parts, fmt.Sprintf("#0x%x", take(hashSize)))
+			break
+		}
+		if omit(1) {
+			parts = append(parts, "_")
+			break
+		}
+		a := take(lay.Size[0])
+		if flag&wildData != 0 {
+			parts = append(parts, fmt.Sprint(instor.DataLen(c, a)), "_")
+			break
+		}
+		// 完整指令
+		return p.whole(code)
+
+	case instor.LayoutArgs:
+		for i, n := range lay.Size {
+			if omit(i + 1) {
+				parts = append(parts, "_")
+				continue
+			}
+			parts = append(parts, fmt.Sprint(uintValue(take(n))))
+		}
+	default:
+		return p.whole(code)
+	}
+	p.line(p.off, fmt.Sprintf("%s(%s)", name(c), strings.Join(parts, ", ")))
+
+	return at
+}
+
+// 输出完整的指令。
+// 返回指令占用的字节数。
+func (p *printer) whole(code []byte) int {
+	ins := instor.Get(code)
+	p.inst(ins, p.off)
+	return ins.Size
+}
+
+// 大端字节序列的无符号整数值。
+func uintValue(b []byte) uint64 {
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v
+}
@@ -7,11 +7,12 @@ package ibase
 
 import (
 	"bytes"
-	"crypto/ed25519"
 	"errors"
 	"fmt"
 
 	"github.com/cxio/suite/cbase/paddr"
+	"github.com/cxio/suite/cbase/sigs"
+	"github.com/cxio/suite/cbase/tx"
 	"github.com/cxio/suite/locale"
 	"github.com/cxio/suite/script/instor"
 	"golang.org/x/tools/container/intsets"
@@ -33,7 +34,6 @@ const (
 	StackMax = 256 // 数据栈大小
 	GotoMax  = 3   // 跳转次数限额（包含）
 	JumpMax  = 9   // 嵌入次数限额（包含）
-	ExprEnd  = -1  // 表达式结束标志
 )
 
 // 3个存值区标识值。
@@ -45,8 +45,8 @@ const (
 
 // 提示信息定义。
 var (
-	jumpsOver  = errors.New(_T("JUMP 嵌入次数超出上限"))
-	gotosOver  = errors.New(_T("GOTO 跳转次数超出上限"))
+	jumpsOver  = &LimitError{_T("JUMP 嵌入次数超出上限")}
+	gotosOver  = &LimitError{_T("GOTO 跳转次数超出上限")}
 	argsAmount = errors.New(_T("实参区数据量与指令需求不匹配"))
 )
 
@@ -57,8 +57,9 @@ type Script = instor.Script
 // 注：用于 MULSIG 指令。
 type SigIdSet = intsets.Sparse
 
-// 公钥类型引用。
-type PubKey = ed25519.PublicKey
+// 公钥类型。
+// 编码格式由签名方案（按脚本版本）决定，见 sigs 包。
+type PubKey = []byte
 
 // 中间数据体
 // 脚本内数据（缓存）和外部世界的中间媒介。
@@ -73,6 +74,7 @@ type Middler struct {
 // - 缓存环境指令需要的数据，惰性载入。
 // - 设置环境基础条件。
 type Envs struct {
+	prov    EnvProvider   // 链数据提供者
 	env     map[int]any   // ENV
 	outs    []map[int]any // OUTs
 	in      map[int]any   // IN
@@ -83,12 +85,18 @@ type Envs struct {
 
 // 创建一个环境对象。
 // 初始化内部存储空间，部分成员默认值即可。
+// prov 为链数据提供者，nil 表示无外部数据（条目皆为nil）。
 // size 为当前交易输出集大小。
 // 注记：
 // 简单数据初始即赋值，复杂数据惰性处理。
-func NewEnvs(pkaddr []byte, size int) *Envs {
+func NewEnvs(prov EnvProvider, pkaddr []byte, size int) *Envs {
+	env := map[int]any{
+		instor.EnvLimitStack: instor.Int(StackMax),
+		instor.EnvLimitScope: instor.Int(ScopeMax),
+	}
 	return &Envs{
-		env:    make(map[int]any),
+		prov:   prov,
+		env:    env,
 		outs:   make([]map[int]any, size),
 		in:     make(map[int]any),
 		inout:  make(map[int]any),
@@ -105,14 +113,28 @@ func (e *Envs) PubKeyAddr() []byte {
 // n 为条目标识值（0-255）。
 // 注：条目值惰性获取。
 func (e *Envs) EnvItem(n int) any {
+	v, err := e.envItem(n)
+	if err != nil {
+		panic(lookupError("ENV", instor.EnvNames, n, err))
+	}
+	return v
+}
+
+// 环境变量条目获取。
+// 同 EnvItem，但出错时返回错误而非抛出恐慌。
+func (e *Envs) envItem(n int) (any, error) {
 	v, ok := e.env[n]
 	if ok {
-		return v
+		return v, nil
 	}
-	// 未完待续
-	// 即时调用系统接口获取
-	// e.env[n] = v
-	return v
+	if e.prov != nil {
+		var err error
+		if v, err = e.prov.Env(n); err != nil {
+			return nil, err
+		}
+	}
+	e.env[n] = v
+	return v, nil
 }
 
 // 获取交易输出信息。
@@ -120,6 +142,9 @@ func (e *Envs) EnvItem(n int) any {
 // n 为输出项成员标识值。
 // 注：条目值惰性获取。
 func (e *Envs) TxOutItem(i, n int) any {
+	if i >= len(e.outs) {
+		panic(&LookupError{Target: fmt.Sprintf("OUT[%d]", i)})
+	}
 	out := e.outs[i]
 
 	if out == nil {
@@ -130,9 +155,13 @@ func (e *Envs) TxOutItem(i, n int) any {
 	if ok {
 		return v
 	}
-	// 未完待续
-	// 即时调用系统接口获取
-	// outs[n] = v
+	if e.prov != nil {
+		var err error
+		if v, err = e.prov.TxOut(i, n); err != nil {
+			panic(lookupError("OUT", instor.OutNames, n, err))
+		}
+	}
+	out[n] = v
 	return v
 }
 
@@ -144,9 +173,13 @@ func (e *Envs) TxInItem(n int) any {
 	if ok {
 		return v
 	}
-	// 未完待续
-	// 即时调用系统接口获取
-	// e.in[n] = v
+	if e.prov != nil {
+		var err error
+		if v, err = e.prov.TxIn(n); err != nil {
+			panic(lookupError("IN", instor.InNames, n, err))
+		}
+	}
+	e.in[n] = v
 	return v
 }
 
@@ -158,9 +191,13 @@ func (e *Envs) TxInOutItem(n int) any {
 	if ok {
 		return v
 	}
-	// 未完待续
-	// 即时调用系统接口获取
-	// e.inout[n] = v
+	if e.prov != nil {
+		var err error
+		if v, err = e.prov.TxInOut(n); err != nil {
+			panic(lookupError("INOUT", instor.OutNames, n, err))
+		}
+	}
+	e.inout[n] = v
 	return v
 }
 
@@ -238,6 +275,10 @@ type Actuator struct {
 	inExpr   *int        // 在表达式内（增减表达深度）
 	xfrom    map[int]any // 来源脚本信息集
 	global   map[int]any // 全局变量区（VAR/SETVAR 指令用）
+	base     int         // 代码段在所属脚本中的偏移
+	depth    int         // 嵌套深度（顶层为0）
+	frame    int         // 执行域类型
+	*runtime             // 执行期共享区
 }
 
 // 创建全新执行器
@@ -249,14 +290,15 @@ type Actuator struct {
 func NewActuator(id, code []byte, ch chan Middler, envs *Envs, ver int) *Actuator {
 	// 部分成员零值即可。
 	return &Actuator{
-		Ver:    ver,
-		ID:     id,
-		Script: *newScript(code),
-		Envs:   envs,
-		spaces: &spaces{Ch: ch},
-		countx: newCountx(),
-		inExpr: new(int),
-		global: make(map[int]any),
+		Ver:     ver,
+		ID:      id,
+		Script:  *newScript(code),
+		Envs:    envs,
+		spaces:  &spaces{Ch: ch},
+		countx:  newCountx(),
+		inExpr:  new(int),
+		global:  make(map[int]any),
+		runtime: newRuntime(id),
 		// xfrom: nil,
 	}
 }
@@ -289,9 +331,13 @@ func (a *Actuator) BlockNew(code []byte) *Actuator {
 		global:  a.global,
 		xfrom:   a.xfrom,
 		loopVar: a.loopVar,
+		runtime: a.runtime,
 		// 重置：
 		Script: *newScript(code),
+		depth:  a.depth + 1,
+		frame:  FrameBlock,
 		inExpr: new(int),
+		base:   a.baseOf(code),
 	}
 }
 
@@ -309,10 +355,14 @@ func (a *Actuator) SwitchNew(code []byte, target any, cases []any) *Actuator {
 		global:  a.global,
 		loopVar: a.loopVar,
 		xfrom:   a.xfrom,
+		runtime: a.runtime,
 		// 重置：
 		Script:  *newScript(code),
+		depth:   a.depth + 1,
+		frame:   FrameSwitch,
 		switchX: newSwitch(target, cases),
 		inExpr:  new(int),
+		base:    a.baseOf(code),
 	}
 }
 
@@ -327,10 +377,14 @@ func (a *Actuator) CaseNew(code []byte) *Actuator {
 		global:  a.global,
 		loopVar: a.loopVar,
 		xfrom:   a.xfrom,
+		runtime: a.runtime,
 		// 重置：
 		Script:  *newScript(code),
+		depth:   a.depth + 1,
+		frame:   FrameCase,
 		switchX: a.switchX.caseIn(),
 		inExpr:  new(int),
+		base:    a.baseOf(code),
 	}
 }
 
@@ -342,16 +396,20 @@ func (a *Actuator) CaseNew(code []byte) *Actuator {
 // - 禁止 GOTO 跳转和 JUMP 嵌入。
 func (a *Actuator) ScopeNew(code []byte) *Actuator {
 	return &Actuator{
-		Ver:    a.Ver,
-		ID:     a.ID,
-		Envs:   a.Envs,
-		global: a.global,
-		xfrom:  a.xfrom,
+		Ver:     a.Ver,
+		ID:      a.ID,
+		Envs:    a.Envs,
+		global:  a.global,
+		xfrom:   a.xfrom,
+		runtime: a.runtime,
 		// 重置：
-		Script: *newScript(code),
-		spaces: a.spaces.scopeNew(),
-		inExpr: new(int),
-		// loopVar:  nil,
+		Script:  *newScript(code),
+		depth:   a.depth + 1,
+		frame:   FrameScope,
+		base:    a.baseOf(code),
+		spaces:  a.spaces.scopeNew(),
+		inExpr:  new(int),
+		loopVar: new(loopVar), // MAP, FILTER 迭代用
 		// countx:  nil,
 	}
 }
@@ -363,17 +421,21 @@ func (a *Actuator) ScopeNew(code []byte) *Actuator {
 // - 初始化循环迭代变量空间（[4]any）。
 func (a *Actuator) LoopNew(code []byte) *Actuator {
 	return &Actuator{
-		Ver:    a.Ver,
-		ID:     a.ID,
-		Envs:   a.Envs,
-		spaces: a.spaces,
-		global: a.global,
-		xfrom:  a.xfrom,
+		Ver:     a.Ver,
+		ID:      a.ID,
+		Envs:    a.Envs,
+		spaces:  a.spaces,
+		global:  a.global,
+		xfrom:   a.xfrom,
+		runtime: a.runtime,
 		// 重置：
 		Script:  *newScript(code),
+		depth:   a.depth + 1,
+		frame:   FrameLoop,
 		countx:  a.jumpNew(),
 		loopVar: new(loopVar),
 		inExpr:  new(int),
+		base:    a.baseOf(code),
 	}
 }
 
@@ -383,12 +445,15 @@ func (a *Actuator) LoopNew(code []byte) *Actuator {
 // 数据栈、实参区、全局变量区独立。
 func (a *Actuator) ScriptNew(id []byte, code []byte) *Actuator {
 	return &Actuator{
-		Ver:    a.Ver,
-		Envs:   a.Envs,
-		countx: a.countx,
+		Ver:     a.Ver,
+		Envs:    a.Envs,
+		countx:  a.countx,
+		runtime: a.runtime,
 		// 重置：
 		ID:     id,
 		Script: *newScript(code),
+		depth:  a.depth + 1,
+		frame:  FrameGoto,
 		spaces: a.spaces.scopeNew(),
 		inExpr: new(int),
 		global: make(map[int]any),
@@ -403,14 +468,17 @@ func (a *Actuator) ScriptNew(id []byte, code []byte) *Actuator {
 // - 不支持引用所在循环的迭代变量。
 func (a *Actuator) EmbedNew(id []byte, code []byte) *Actuator {
 	return &Actuator{
-		Ver:    a.Ver,
-		Envs:   a.Envs,
-		spaces: a.spaces,
-		countx: a.countx,
-		global: a.global,
+		Ver:     a.Ver,
+		Envs:    a.Envs,
+		spaces:  a.spaces,
+		countx:  a.countx,
+		global:  a.global,
+		runtime: a.runtime,
 		// 重置：
 		ID:     id,
 		Script: *newScript(code),
+		depth:  a.depth + 1,
+		frame:  FrameJump,
 		inExpr: new(int),
 		xfrom:  a.fromScript(a.Script),
 		// loopVar:  nil,
@@ -426,11 +494,15 @@ func (a *Actuator) EmbedNew(id []byte, code []byte) *Actuator {
 // 只能是源脚本中的 CODE{} 创建，故id不变。
 func (a *Actuator) EvalNew(code []byte) *Actuator {
 	return &Actuator{
-		Ver:  a.Ver,
-		ID:   a.ID,
-		Envs: a.Envs,
+		Ver:     a.Ver,
+		ID:      a.ID,
+		Envs:    a.Envs,
+		runtime: a.runtime,
 		// 重置：
 		Script: *newScript(code),
+		depth:  a.depth + 1,
+		frame:  FrameEval,
+		base:   a.baseOf(code),
 		spaces: a.spaces.scopeNew(),
 		inExpr: new(int),
 		global: make(map[int]any),
@@ -454,8 +526,12 @@ func (a *Actuator) ExprNew(code []byte) *Actuator {
 		inExpr:  a.inExpr,
 		global:  a.global,
 		xfrom:   a.xfrom,
+		runtime: a.runtime,
 		// 重置：
 		Script: *newScript(code),
+		depth:  a.depth + 1,
+		frame:  FrameExpr,
+		base:   a.baseOf(code),
 		// countx:  nil,
 	}
 }
@@ -518,10 +594,22 @@ func (a *Actuator) Arguments(n int) []any {
 }
 
 // 构造签名消息。
-// flag 签名消息类别。
+// flag 签名消息类别（instor.SigXXX）。
+// 交易数据由环境的提供者给出（需实现 TxProvider），消息绑定顶层脚本的标识和版本，
+// 构造方式见 tx.SigHash。
+// 无交易数据时以外部查询错误结束，类别或输入序位无效时为一般错误。
 func (a *Actuator) SpentMsg(flag int) []byte {
-	// 未完待续
-	return nil
+	p, ok := a.Envs.prov.(TxProvider)
+	if !ok {
+		panic(&LookupError{Target: "SpentMsg", Err: errNoTx})
+	}
+	h, b, in := p.Tx()
+
+	msg, err := tx.SigHash(a.Ver, a.runtime.id, h, b, in, flag)
+	if err != nil {
+		panic(err)
+	}
+	return msg
 }
 
 // 返回值放置。
@@ -578,24 +666,16 @@ func CheckAward(h int) int {
 }
 
 // 单签名验证。
-// ver 为版本值。便于安全升级。
-// 当前采用ed25519签名认证。
+// ver 为脚本版本，决定签名方案（见 sigs 包），未登记的版本视为验证失败。
 func CheckSig(ver int, pubkey PubKey, msg, sig []byte) bool {
-	// ver: 1
-	return ed25519.Verify(pubkey, msg, sig)
+	return sigs.Verify(ver, pubkey, msg, sig)
 }
 
 // 多签名验证。
-// ver 为版本值。便于安全升级。
-// 当前采用ed25519签名认证。
-func CheckSigs(ver int, pubkeys []PubKey, msg []byte, sigs [][]byte) bool {
-	// ver: 1
-	for i, pk := range pubkeys {
-		if !ed25519.Verify(pk, msg, sigs[i]) {
-			return false
-		}
-	}
-	return true
+// ver 为脚本版本，全部签名采用同一方案。
+// 签名由工作池并行验证（见 sigs.Batch）。
+func CheckSigs(ver int, pubkeys []PubKey, msg []byte, ss [][]byte) bool {
+	return sigs.VerifyAll(ver, pubkeys, msg, ss) == nil
 }
 
 // 系统内置验证（单签名）。
@@ -609,14 +689,33 @@ func CheckSigs(ver int, pubkeys []PubKey, msg []byte, sigs [][]byte) bool {
 // 需要对比目标公钥地址和计算出来的是否相同。
 // 不含金额的合法性检查，它们在前阶环节执行。
 func SingleCheck(ver int, pubkey PubKey, msg, sig, pkaddr []byte) bool {
-	pka := paddr.Hash([]byte(pubkey), nil)
-
-	if !bytes.Equal(pka, pkaddr) {
+	if !singleAddr(ver, pubkey, pkaddr) {
 		return false
 	}
 	return CheckSig(ver, pubkey, msg, sig)
 }
 
+// 系统内置验证（单签名），签名延后批量验证。
+// 同 SingleCheck，但仅检查公钥和地址，签名条目登记到 b，
+// 由调用者在收集完整个交易或区块后统一执行 b.Verify。
+// 返回假表示公钥或地址不符，此时不登记。
+func SingleCheckBatch(b *sigs.Batch, ver int, pubkey PubKey, msg, sig, pkaddr []byte) bool {
+	if !singleAddr(ver, pubkey, pkaddr) {
+		return false
+	}
+	b.Add(ver, pubkey, msg, sig)
+	return true
+}
+
+// 检查单签名公钥和地址。
+func singleAddr(ver int, pubkey PubKey, pkaddr []byte) bool {
+	s, err := sigs.Get(ver)
+	if err != nil || s.CheckKey(pubkey) != nil {
+		return false
+	}
+	return bytes.Equal(s.Address(pubkey), pkaddr)
+}
+
 // 系统内置验证（多重签名）。
 // 公钥条目和公钥地址条目都已前置1字节的序位值（在公钥地址清单中的位置）。
 // 解锁数据：
@@ -629,22 +728,50 @@ func SingleCheck(ver int, pubkey PubKey, msg, sig, pkaddr []byte) bool {
 // - env 环境对象引用（添加信息）。
 // 注记：
 // 需要先对比两个来源的公钥地址是否相同。
+// 公钥地址皆为 paddr.Hash 构造，与签名方案无关。
 // 不含金额的合法性检查。
 func MultiCheck(ver int, msg []byte, sigs, pks, pkhs [][]byte, pkaddr []byte, env *Envs) (bool, error) {
+	_pks, ok, err := multiAddr(pks, pkhs, pkaddr, env)
+	if !ok {
+		return false, err
+	}
+	return CheckSigs(ver, _pks, msg, sigs), nil
+}
+
+// 系统内置验证（多重签名），签名延后批量验证。
+// 同 MultiCheck，但签名条目登记到 b，由调用者统一执行 b.Verify。
+// 返回假表示地址不符或签名数量与公钥不一致，此时不登记。
+func MultiCheckBatch(b *sigs.Batch, ver int, msg []byte, ss, pks, pkhs [][]byte, pkaddr []byte, env *Envs) (bool, error) {
+	if len(ss) != len(pks) {
+		return false, nil
+	}
+	_pks, ok, err := multiAddr(pks, pkhs, pkaddr, env)
+	if !ok {
+		return false, err
+	}
+	for i, pk := range _pks {
+		b.Add(ver, pk, msg, ss[i])
+	}
+	return true, nil
+}
+
+// 检查多重签名地址并登记序位。
+// 返回去除序位标识的公钥集。
+func multiAddr(pks, pkhs [][]byte, pkaddr []byte, env *Envs) ([]PubKey, bool, error) {
 	pka, err := paddr.MulHash(pks, pkhs)
 
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
 	// 已含前置n/T配比对比。
 	if !bytes.Equal(pka, pkaddr) {
-		return false, nil
+		return nil, false, nil
 	}
 	ids, _pks := MulPubKeys(pks)
 	// 环境赋值
 	env.SetMulSig(ids...)
 
-	return CheckSigs(ver, _pks, msg, sigs), nil
+	return _pks, true, nil
 }
 
 //
@@ -662,7 +789,7 @@ func (s *stack) push(vs ...any) {
 	n := len(*s) + len(vs)
 
 	if n > StackMax {
-		panic(fmt.Errorf(_T("%d 超出数据栈高度限制（<=%d）"), n, StackMax))
+		panic(limitErrorf(_T("%d 超出数据栈高度限制（<=%d）"), n, StackMax))
 	}
 	*s = append(*s, vs...)
 }
@@ -786,7 +913,7 @@ func (s *scope) add(vs ...any) {
 	n := len(*s) + len(vs)
 
 	if n > ScopeMax {
-		panic(fmt.Errorf(_T("%d 超出局部域范围（<=%d）"), n, ScopeMax))
+		panic(limitErrorf(_T("%d 超出局部域范围（<=%d）"), n, ScopeMax))
 	}
 	*s = append(*s, vs...)
 }
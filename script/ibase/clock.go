@@ -0,0 +1,139 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package ibase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	mrand "math/rand/v2"
+	"time"
+
+	"github.com/cxio/suite/script/instor"
+)
+
+//
+// 时钟与熵源
+// 时间和随机数相关的指令（SYS_TIME, RANDOM, QRANDOM, SRAND）由此获取数据。
+// 默认为系统时钟和安全随机源，适用于非共识的场合（如调试、离线工具）。
+// 共识模式下时间取自交易环境（UTC），随机数为确定的伪随机流，
+// 种子由交易ID和脚本标识决定，各验证节点对同一脚本的执行结果一致。
+///////////////////////////////////////////////////////////////////////////////
+
+// 时钟。
+type Clock func() time.Time
+
+// 熵源。
+// 即 math/rand/v2 的 Source 接口。
+type Entropy = mrand.Source
+
+// 安全随机源。
+type cryptoSource struct{}
+
+func (cryptoSource) Uint64() uint64 {
+	var b [8]byte
+	rand.Read(b[:])
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// 设置时钟。
+// 传递nil恢复为系统时钟。
+// 注：共识模式下设置无效。
+func (a *Actuator) SetClock(c Clock) {
+	if a.Consensus() {
+		return
+	}
+	a.runtime.clk = c
+}
+
+// 设置熵源。
+// 传递nil恢复为安全随机源。
+// 注：共识模式下设置无效。
+func (a *Actuator) SetEntropy(src Entropy) {
+	if a.Consensus() {
+		return
+	}
+	a.runtime.src = src
+	a.runtime.rnd = nil
+}
+
+// 启用共识模式。
+// 应当在顶层执行器上、脚本执行之前调用，其标识（ID）即为随机种子的构成部分。
+// 时间取自环境条目 ENV{Time}（理想块时间戳），不可用时取 ENV{Timestamp}（交易时间戳）。
+// 随机种子为交易ID（ENV{TxID}）与脚本标识的哈希。
+// 两者都在首次使用时获取，缺少数据时以外部查询错误（*LookupError）结束执行。
+func (a *Actuator) SetConsensus() {
+	a.runtime.seed = append([]byte{}, a.ID...)
+	a.runtime.clk = a.envTime
+	a.runtime.src = nil
+	a.runtime.rnd = nil
+}
+
+// 是否为共识模式。
+func (a *Actuator) Consensus() bool {
+	return a.runtime.seed != nil
+}
+
+// 获取当前时间。
+func (a *Actuator) Now() time.Time {
+	if a.runtime.clk == nil {
+		return time.Now()
+	}
+	return a.runtime.clk()
+}
+
+// 获取随机数生成器。
+// 由全部子执行器共享，共识模式下为确定的伪随机流。
+func (a *Actuator) Rand() *mrand.Rand {
+	if a.runtime.rnd != nil {
+		return a.runtime.rnd
+	}
+	src := a.runtime.src
+
+	switch {
+	case a.Consensus():
+		src = mrand.NewChaCha8(a.envSeed())
+	case src == nil:
+		src = cryptoSource{}
+	}
+	a.runtime.rnd = mrand.New(src)
+
+	return a.runtime.rnd
+}
+
+// 共识时钟。
+// 时间戳为毫秒数，转为UTC时间。
+func (a *Actuator) envTime() time.Time {
+	v, err := a.envItem(instor.EnvTime)
+
+	if err != nil || v == nil {
+		v = a.EnvItem(instor.EnvTimestamp)
+	}
+	ms, ok := v.(instor.Int)
+	if !ok {
+		panic(lookupError("ENV", instor.EnvNames, instor.EnvTimestamp, errNoItem))
+	}
+	return time.UnixMilli(ms).UTC()
+}
+
+// 共识随机种子。
+// 交易ID缺失时仅由脚本标识决定，仍然是确定的。
+func (a *Actuator) envSeed() [32]byte {
+	h := sha256.New()
+
+	switch id := a.EnvItem(instor.EnvTxID).(type) {
+	case nil:
+	case []byte:
+		h.Write(id)
+	default:
+		fmt.Fprint(h, id)
+	}
+	h.Write(a.runtime.seed)
+
+	var seed [32]byte
+	h.Sum(seed[:0])
+
+	return seed
+}
@@ -0,0 +1,216 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package ibase
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cxio/suite/cbase/paddr"
+	"github.com/cxio/suite/cbase/tx"
+	"github.com/cxio/suite/script/instor"
+)
+
+// 链数据提供者。
+// 为环境指令（ENV/OUT/IN/INOUT）提供数据，取值结果由 Envs 缓存，
+// 因此同一条目在一次脚本执行中仅获取一次。
+// n 为条目标识值，对应 instor 中的 EnvXXX、OutXXX 和 InXXX 定义。
+// 出错时脚本以外部查询错误（*LookupError）结束。
+type EnvProvider interface {
+	// 环境条目（ENV）。
+	Env(n int) (any, error)
+
+	// 当前交易第 i 个输出的条目（OUT）。
+	TxOut(i, n int) (any, error)
+
+	// 当前输入的条目（IN）。
+	TxIn(n int) (any, error)
+
+	// 当前输入的源输出的条目（INOUT）。
+	TxInOut(n int) (any, error)
+}
+
+// 交易数据提供者。
+// EnvProvider 的可选扩展，用于构造签名消息（Actuator.SpentMsg）。
+// 返回当前交易的头、体和当前输入的序位。
+type TxProvider interface {
+	Tx() (h *tx.Header, b *tx.Body, in int)
+}
+
+var (
+	// 条目不可用。
+	errNoItem = errors.New(_T("条目不可用"))
+
+	// 无交易数据。
+	errNoTx = errors.New(_T("环境未提供交易数据"))
+)
+
+// 创建环境条目查询错误。
+// kind 为指令名，names 为条目名称集。
+func lookupError(kind string, names []string, n int, err error) *LookupError {
+	name := fmt.Sprint(n)
+	if n < len(names) {
+		name = names[n]
+	}
+	return &LookupError{Target: kind + "{" + name + "}", Err: err}
+}
+
+// 交易数据环境。
+// EnvProvider 的内存实现，由交易数据直接构建，用于测试或离线执行脚本。
+// 交易数据之外的条目（如区块高度、签名数量）由补充集提供，
+// 补充集中的条目优先，都没有时查询出错。
+// 条目值类型：
+// - 金额、数量、时间戳为 Int。
+// - 公钥地址、脚本、描述等为 Bytes，文本地址为 String。
+type TxEnv struct {
+	Header  *tx.Header  // 交易头
+	Body    *tx.Body    // 交易体
+	Index   int         // 当前输入的序位
+	Sources []tx.Vout   // 各输入的源输出，与输入集一一对应
+	Prefix  string      // 文本地址前缀
+	Envs    map[int]any // ENV 补充条目
+	Ins     map[int]any // IN 补充条目
+	InOuts  map[int]any // INOUT 补充条目
+}
+
+// 交易数据。
+func (t *TxEnv) Tx() (*tx.Header, *tx.Body, int) {
+	return t.Header, t.Body, t.Index
+}
+
+// 环境条目。
+func (t *TxEnv) Env(n int) (any, error) {
+	if v, ok := t.Envs[n]; ok {
+		return v, nil
+	}
+	switch n {
+	case instor.EnvTimestamp:
+		return instor.Int(t.Header.Timestamp), nil
+	case instor.EnvInSize:
+		return instor.Int(len(t.Body.Vins())), nil
+	case instor.EnvInAmout:
+		return amounts(t.Sources), nil
+	case instor.EnvOutSize:
+		return instor.Int(len(t.Body.Vouts())), nil
+	case instor.EnvOutAmount:
+		return amounts(t.Body.Vouts()), nil
+	}
+	return nil, errNoItem
+}
+
+// 输出条目。
+func (t *TxEnv) TxOut(i, n int) (any, error) {
+	outs := t.Body.Vouts()
+	if i >= len(outs) {
+		return nil, errNoItem
+	}
+	return outItem(&outs[i], n), nil
+}
+
+// 当前输入条目。
+func (t *TxEnv) TxIn(n int) (any, error) {
+	if v, ok := t.Ins[n]; ok {
+		return v, nil
+	}
+	src, err := t.source()
+	if err != nil {
+		return nil, err
+	}
+	switch n {
+	case instor.InIndex:
+		return instor.Int(t.Index), nil
+	case instor.InAmount:
+		return outItem(src, instor.OutAmount), nil
+	case instor.InAccount:
+		return outItem(src, instor.OutReceiver), nil
+	case instor.InAddress:
+		pka, ok := outItem(src, instor.OutReceiver).(instor.Bytes)
+		if !ok {
+			return nil, nil
+		}
+		return paddr.Encode(pka, t.Prefix), nil
+	case instor.InPayType:
+		return instor.Int(src.Type()), nil
+	case instor.InSource:
+		return outItem(src, instor.OutSource), nil
+	}
+	return nil, errNoItem
+}
+
+// 当前输入的源输出条目。
+func (t *TxEnv) TxInOut(n int) (any, error) {
+	if v, ok := t.InOuts[n]; ok {
+		return v, nil
+	}
+	if n == instor.OutTimestamp {
+		return nil, errNoItem
+	}
+	src, err := t.source()
+	if err != nil {
+		return nil, err
+	}
+	return outItem(src, n), nil
+}
+
+// 当前输入的源输出。
+func (t *TxEnv) source() (*tx.Vout, error) {
+	if t.Index >= len(t.Sources) {
+		return nil, errNoItem
+	}
+	return &t.Sources[t.Index], nil
+}
+
+// 输出项条目取值。
+// 不适用于该类输出的条目返回nil。
+func outItem(v *tx.Vout, n int) any {
+	if c := v.Coin(); c != nil {
+		switch n {
+		case instor.OutAmount:
+			return instor.Int(c.Amount)
+		case instor.OutReceiver:
+			return instor.Bytes(c.Receiver)
+		case instor.OutSource:
+			return instor.Bytes(c.Script)
+		}
+	}
+	if c := v.Credit(); c != nil {
+		switch n {
+		case instor.OutReceiver:
+			return instor.Bytes(c.Receiver)
+		case instor.OutCreator:
+			return instor.Bytes(c.Creator)
+		case instor.OutDescription:
+			return instor.Bytes(c.Description)
+		case instor.OutAttachment:
+			return instor.Bytes(c.Attachment)
+		case instor.OutSource:
+			return instor.Bytes(c.Script)
+		}
+	}
+	if e := v.Evidence(); e != nil {
+		switch n {
+		case instor.OutTitle:
+			return instor.Bytes(e.Title)
+		case instor.OutContent:
+			return instor.Bytes(e.Content)
+		case instor.OutAttachment:
+			return instor.Bytes(e.Attachment)
+		case instor.OutSource:
+			return instor.Bytes(e.Script)
+		}
+	}
+	return nil
+}
+
+// 币金总额。
+func amounts(outs []tx.Vout) instor.Int {
+	var sum instor.Int
+
+	for i := range outs {
+		if c := outs[i].Coin(); c != nil {
+			sum += instor.Int(c.Amount)
+		}
+	}
+	return sum
+}
@@ -0,0 +1,309 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package ibase
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+)
+
+//
+// 执行期共享区
+// 顶层执行器创建时构建，所有子执行器共享同一实例，
+// 记录执行上下文、当前指令位置和执行成本，供上层获取出错时的现场。
+///////////////////////////////////////////////////////////////////////////////
+
+// 成本超出预算。
+var ErrGas = &LimitError{_T("执行成本超出预算")}
+
+// 执行域类型。
+// 标识执行器的创建来源，顶层脚本为 FrameTop。
+const (
+	FrameTop    = iota // 顶层脚本
+	FrameBlock         // 普通子块（IF, ELSE, BLOCK 及迭代体）
+	FrameSwitch        // SWITCH 块
+	FrameCase          // CASE/DEFAULT 块
+	FrameScope         // 私有域（MAP, FILTER）
+	FrameLoop          // 循环块（EACH）
+	FrameGoto          // GOTO 跳转的脚本
+	FrameJump          // JUMP 嵌入的脚本
+	FrameEval          // EVAL 执行的代码
+	FrameExpr          // 表达式
+)
+
+// 执行监视器。
+// 在每条指令执行之前调用，此时指令位置已记录，但尚未步进。
+// 监视器可通过执行器的查看接口获取当前状态，也可以抛出恐慌中止执行。
+type Monitor interface {
+	Before(a *Actuator)
+}
+
+// 执行跟踪器。
+// 在监视器的基础上，接收指令的调用结果和执行域的进出通知。
+//   - After 在指令正常返回后调用，to 为返回值的存放区（StackFlag 等），
+//     args 为指令取得的实参，rets 为返回值。中断执行流的指令不会调用。
+//   - Enter/Leave 在执行器运行代码的开始和结束时调用，异常结束时也会调用 Leave。
+type Tracer interface {
+	Monitor
+	After(a *Actuator, to int, args, rets []any)
+	Enter(a *Actuator)
+	Leave(a *Actuator)
+}
+
+// 指令位置。
+// Offset 为指令在所属脚本（ID）中的偏移，子块内的指令亦同。
+// 注：
+// EVAL 执行的代码为 CODE{} 的副本，其偏移相对于该代码段。
+type Position struct {
+	ID     []byte // 脚本标识
+	Offset int    // 指令偏移
+	Code   int    // 指令码
+}
+
+// 执行期共享信息。
+type runtime struct {
+	id   []byte          // 顶层脚本标识
+	ctx  context.Context // 执行上下文
+	done <-chan struct{} // 取消通知（缓存）
+	pos  Position        // 当前指令位置
+	gas  int64           // 已消耗成本
+	max  int64           // 成本预算，零值表示不限
+	mon  Monitor         // 执行监视器
+	trc  Tracer          // 执行跟踪器（监视器兼任时）
+	clk  Clock           // 时钟，nil 为系统时钟
+	src  Entropy         // 熵源，nil 为安全随机源
+	rnd  *rand.Rand      // 随机数生成器（惰性创建）
+	seed []byte          // 共识模式的种子标识，nil 为非共识模式
+}
+
+// 新建一个共享区。
+// id 为顶层脚本的标识。
+func newRuntime(id []byte) *runtime {
+	return &runtime{id: id, ctx: context.Background()}
+}
+
+// 设置执行上下文。
+// 应当在脚本执行之前设置，执行中途的取消会在下一条指令前生效。
+func (a *Actuator) SetContext(ctx context.Context) {
+	a.runtime.ctx = ctx
+	a.runtime.done = ctx.Done()
+}
+
+// 获取执行上下文。
+func (a *Actuator) Context() context.Context {
+	return a.runtime.ctx
+}
+
+// 标记当前指令。
+// 记录当前指令的位置，检查执行上下文是否已取消，然后通知监视器（如果有）。
+// 注：
+// 在指令调用之前执行，取消时以上下文的错误值抛出恐慌。
+func (a *Actuator) Mark() {
+	a.runtime.pos = Position{
+		ID:     a.ID,
+		Offset: a.base + a.Script.Offset(),
+		Code:   a.Script.Code(),
+	}
+	if a.runtime.done != nil {
+		select {
+		case <-a.runtime.done:
+			panic(a.runtime.ctx.Err())
+		default:
+		}
+	}
+	if a.runtime.mon != nil {
+		a.runtime.mon.Before(a)
+	}
+}
+
+// 设置执行监视器。
+// 若监视器同时实现了 Tracer 接口，也会接收跟踪通知。
+// 传递nil清除监视器。
+func (a *Actuator) SetMonitor(m Monitor) {
+	a.runtime.mon = m
+	a.runtime.trc, _ = m.(Tracer)
+}
+
+// 是否处于跟踪状态。
+func (a *Actuator) Traced() bool {
+	return a.runtime.trc != nil
+}
+
+// 跟踪：指令调用结果。
+func (a *Actuator) TraceAfter(to int, args, rets []any) {
+	if a.runtime.trc != nil {
+		a.runtime.trc.After(a, to, args, rets)
+	}
+}
+
+// 跟踪：进入执行域。
+func (a *Actuator) TraceEnter() {
+	if a.runtime.trc != nil {
+		a.runtime.trc.Enter(a)
+	}
+}
+
+// 跟踪：离开执行域。
+func (a *Actuator) TraceLeave() {
+	if a.runtime.trc != nil {
+		a.runtime.trc.Leave(a)
+	}
+}
+
+// 获取最近执行的指令位置。
+func (a *Actuator) Position() Position {
+	return a.runtime.pos
+}
+
+// 设置成本预算。
+// 预算由全部子执行器（含 GOTO/JUMP 引入的脚本）共享，零值表示不限。
+func (a *Actuator) SetGasLimit(n int64) {
+	a.runtime.max = n
+}
+
+// 获取已消耗的成本。
+func (a *Actuator) GasUsed() int64 {
+	return a.runtime.gas
+}
+
+// 计入执行成本。
+// 超出预算时抛出 ErrGas 恐慌，已消耗量保留超出时的值。
+func (a *Actuator) Charge(n int) {
+	a.runtime.gas += int64(n)
+
+	if a.runtime.max > 0 && a.runtime.gas > a.runtime.max {
+		panic(ErrGas)
+	}
+}
+
+//
+// 状态查看
+// 供调试器等外部工具使用，返回的集合均为副本。
+///////////////////////////////////////////////////////////////////////////////
+
+// 获取嵌套深度。
+// 顶层脚本为0，每进入一个子执行器加1（含 GOTO/JUMP/EVAL）。
+func (a *Actuator) Depth() int {
+	return a.depth
+}
+
+// 获取代码段在所属脚本中的偏移。
+func (a *Actuator) Base() int {
+	return a.base
+}
+
+// 获取执行域类型（FrameXXX）。
+func (a *Actuator) FrameKind() int {
+	return a.frame
+}
+
+// 获取表达式嵌套深度。
+func (a *Actuator) ExprDepth() int {
+	return *a.inExpr
+}
+
+// 获取实参区成员。
+func (a *Actuator) ArgsData() []any {
+	buf := make([]any, len(a.spaces.args))
+	copy(buf, a.spaces.args)
+	return buf
+}
+
+// 获取局部域成员。
+func (a *Actuator) ScopeData() []any {
+	buf := make([]any, len(a.scope))
+	copy(buf, a.scope)
+	return buf
+}
+
+// 获取循环变量。
+// 不在循环内时返回nil。
+func (a *Actuator) LoopData() []any {
+	if a.loopVar == nil {
+		return nil
+	}
+	buf := make([]any, len(a.loopVar))
+	copy(buf, a.loopVar[:])
+	return buf
+}
+
+// 获取全局变量集。
+func (a *Actuator) GlobalData() map[int]any {
+	buf := make(map[int]any, len(a.global))
+	for k, v := range a.global {
+		buf[k] = v
+	}
+	return buf
+}
+
+// 获取跳转和嵌入计数。
+// 禁止跳转或嵌入的环境（如私有域）中对应的计数为0。
+func (a *Actuator) Counts() (gotos, jumps int) {
+	if a.countx == nil {
+		return
+	}
+	if a.countx.gotos != nil {
+		gotos = *a.countx.gotos
+	}
+	if a.countx.jumps != nil {
+		jumps = *a.countx.jumps
+	}
+	return
+}
+
+//
+// 私有辅助
+///////////////////////////////////////////////////////////////////////////////
+
+// 子块代码在所属脚本中的偏移。
+// 子块代码为当前脚本源码的子切片，由容量之差计算相对位置。
+// 若非子切片（如 CODE{} 的副本），视为独立代码段，返回0。
+func (a *Actuator) baseOf(code []byte) int {
+	src := a.Script.Source()
+	n := cap(src) - cap(code)
+
+	if n < 0 || n >= len(src) || len(code) == 0 || &src[n] != &code[0] {
+		return 0
+	}
+	return a.base + n
+}
+
+//
+// 分类错误
+// 由指令执行时以 panic 抛出，供上层识别错误类别。
+///////////////////////////////////////////////////////////////////////////////
+
+// 资源限额错误。
+// 数据栈、局部域、跳转和嵌入次数等超出上限。
+type LimitError struct {
+	msg string
+}
+
+func (e *LimitError) Error() string {
+	return e.msg
+}
+
+// 创建资源限额错误。
+func limitErrorf(format string, a ...any) *LimitError {
+	return &LimitError{fmt.Sprintf(format, a...)}
+}
+
+// 外部查询错误。
+// 外部数据源（如第三方脚本池）获取目标失败。
+type LookupError struct {
+	Target string // 查询目标
+	Err    error  // 原始错误，可能为nil
+}
+
+func (e *LookupError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf(_T("外部数据 %s 获取失败"), e.Target)
+	}
+	return fmt.Sprintf(_T("外部数据 %s 获取失败：%v"), e.Target, e.Err)
+}
+
+func (e *LookupError) Unwrap() error {
+	return e.Err
+}
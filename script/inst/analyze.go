@@ -0,0 +1,724 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package inst
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/cxio/suite/script/ibase"
+	"github.com/cxio/suite/script/icode"
+	"github.com/cxio/suite/script/inst/expr"
+	"github.com/cxio/suite/script/instor"
+)
+
+//
+// 静态分析
+// 不执行脚本，按指令配置的实参数量（Instx.Argn）和各指令的栈效应抽象解释代码，
+// 跟踪数据栈深度、实参区（@ ~ 设置）、局部域大小和块结构，
+// 报告执行时必然出现的错误和可疑的用法，供钱包在广播前拒绝有缺陷的锁定脚本。
+//
+// 数量的跟踪：
+// 每个存储区记录一个下限值和是否确切。条件分支汇合后取较小者，
+// 循环、返回值数量不定的指令（INPUT、SPREAD、扩展指令等）使数量不再确切。
+// 仅在数量确切（或下限已超出上限）时报告错误，不确定的情况报告为警告或忽略，
+// 因此没有错误并不保证执行成功（如类型错误、除零等不在分析之列）。
+//
+// 注记：
+// 顶层脚本假定初始数据栈为空，即锁定脚本单独执行的情形。
+// CODE 代码块不一定被执行（EVAL），其中的错误降为警告。
+///////////////////////////////////////////////////////////////////////////////
+
+// 问题级别。
+const (
+	LevelWarning = iota // 警告：可疑或无效的用法
+	LevelError          // 错误：执行到此必然失败
+)
+
+// 分析问题说明。
+var (
+	errUnderflow  = _T("数据栈条目不足")
+	errStackMax   = _T("数据栈超出上限")
+	errScopeMax   = _T("局部域超出上限")
+	errScopeIndex = _T("局部域下标越界")
+	errArgsAmount = _T("实参区条目数与指令需求不符")
+	errArgsEmpty  = _T("实参区为空，缺少目标集")
+	errArgsSource = _T("实参来源不确定（实参区可能为空）")
+	errArgsUnused = _T("脚本结束时实参区仍有未使用的值")
+	errNoLoop     = _T("循环域取值不在迭代块内")
+	errNoIf       = _T("ELSE 之前没有对应的 IF")
+	errNoSwitch   = _T("分支不在 SWITCH 块内")
+	errNoCase     = _T("FALLTHROUGH 不在 CASE 块内")
+	errNoBreak    = _T("不在 EACH 或 SWITCH 块内")
+	errNoReturn   = _T("RETURN 只能用于 MAP 或 FILTER 块内")
+	errNoExit     = _T("MAP 或 FILTER 块内不能 EXIT")
+	errNoGoto     = _T("当前环境不支持 GOTO 跳转")
+	errNoJump     = _T("当前环境不支持 JUMP 嵌入")
+	errExprMulti  = _T("表达式内指令的返回值太多")
+	errUseless    = _T("取值标记后的指令没有返回值")
+	errBring      = _T("实参直取标记后的指令无需定量实参")
+	errDead       = _T("不可到达的代码")
+)
+
+// 分析问题。
+type Issue struct {
+	Offset int    // 指令偏移（相对于脚本起点）
+	Code   int    // 指令码，脚本整体的问题为-1
+	Level  int    // 问题级别
+	Reason string // 问题说明
+}
+
+func (i Issue) String() string {
+	lv := _T("警告")
+	if i.Level == LevelError {
+		lv = _T("错误")
+	}
+	if i.Code < 0 {
+		return fmt.Sprintf(_T("%s：偏移 %d 处%s"), lv, i.Offset, i.Reason)
+	}
+	return fmt.Sprintf(_T("%s：偏移 %d 处（%s）%s"), lv, i.Offset, instor.CodeNames[i.Code], i.Reason)
+}
+
+// 分析报告。
+type Report struct {
+	Issues   []Issue // 问题清单（按偏移排序）
+	MaxStack int     // 可确定的最大栈深度（下限）
+}
+
+// 错误清单。
+func (r *Report) Errors() []Issue {
+	var buf []Issue
+
+	for _, i := range r.Issues {
+		if i.Level == LevelError {
+			buf = append(buf, i)
+		}
+	}
+	return buf
+}
+
+// 是否没有错误。
+// 警告不影响结果。
+func (r *Report) Ok() bool {
+	return len(r.Errors()) == 0
+}
+
+// 分析脚本。
+// code 为脚本代码，需先通过格式校验（instor.Validate），否则返回其错误。
+func Analyze(code []byte) (*Report, error) {
+	if err := instor.Validate(code); err != nil {
+		return nil, err
+	}
+	z := &analyzer{rep: new(Report)}
+	c := &actx{gotos: true, jumps: true, exit: true}
+
+	s := z.list(code, 0, freshState(), c)
+
+	if !s.dead && s.args.n > 0 {
+		z.issue(c, LevelWarning, len(code), -1, errArgsUnused)
+	}
+	slices.SortStableFunc(z.rep.Issues, func(a, b Issue) int {
+		return cmp.Compare(a.Offset, b.Offset)
+	})
+	return z.rep, nil
+}
+
+/*
+ * 抽象状态
+ ******************************************************************************
+ */
+
+// 条目数量。
+// n 为下限值，exact 表示确切。
+type count struct {
+	n     int
+	exact bool
+}
+
+// 增加条目。
+// k 为负表示数量不定。
+func (c count) add(k int) count {
+	if k < 0 {
+		return count{c.n, false}
+	}
+	return count{c.n + k, c.exact}
+}
+
+// 减少条目。
+// 下限不足时归零，数量不再确切。
+func (c count) sub(k int) count {
+	if c.n < k {
+		return count{0, false}
+	}
+	return count{c.n - k, c.exact}
+}
+
+// 汇合两条路径的数量。
+func (c count) join(x count) count {
+	return count{min(c.n, x.n), c.exact && x.exact && c.n == x.n}
+}
+
+// 循环后的数量。
+// c 为进入循环时的数量，x 为一次迭代后的数量。
+// 迭代使数量减少时，多次迭代后的下限归零。
+func (c count) loop(x count) count {
+	if c == x && c.exact {
+		return c
+	}
+	if x.n < c.n {
+		return count{}
+	}
+	return count{c.n, false}
+}
+
+// 抽象执行状态。
+type astate struct {
+	stack count // 数据栈
+	args  count // 实参区
+	scope count // 局部域
+	dead  bool  // 路径已终止（EXIT、BREAK 等）
+}
+
+// 空的初始状态。
+func freshState() astate {
+	return astate{stack: count{0, true}, args: count{0, true}, scope: count{0, true}}
+}
+
+// 汇合两条路径。
+// 已终止的路径不参与。
+func join(a, b astate) astate {
+	if a.dead {
+		return b
+	}
+	if b.dead {
+		return a
+	}
+	return astate{
+		stack: a.stack.join(b.stack),
+		args:  a.args.join(b.args),
+		scope: a.scope.join(b.scope),
+	}
+}
+
+// 分析环境。
+// 各子块按执行器的创建方式（BlockNew、LoopNew、ScopeNew 等）继承或重置。
+type actx struct {
+	loop  bool      // 有循环变量（EACH/MAP/FILTER 内）
+	expr  bool      // 表达式内
+	gotos bool      // 可 GOTO 跳转
+	jumps bool      // 可 JUMP 嵌入
+	ret   bool      // 可 RETURN（MAP/FILTER 内）
+	exit  bool      // 可 EXIT
+	soft  bool      // 错误降为警告
+	swit  bool      // SWITCH 块的分支层
+	fall  *bool     // FALLTHROUGH 标记（CASE 内）
+	brk   *[]astate // BREAK 的去向状态
+	cont  *[]astate // CONTINUE 的去向状态
+}
+
+// 分析器。
+type analyzer struct {
+	rep *Report
+}
+
+// 记录问题。
+func (z *analyzer) issue(c *actx, level, off, code int, reason string) {
+	if c.soft {
+		level = LevelWarning
+	}
+	z.rep.Issues = append(z.rep.Issues, Issue{off, code, level, reason})
+}
+
+/*
+ * 代码分析
+ ******************************************************************************
+ */
+
+// 前置标记状态。
+// 即 @ ~ $ 的设置，仅作用于随后的一条指令。
+type aflag struct {
+	to   int  // 返回值去向
+	from bool // 实参直取
+}
+
+// 分析指令序列。
+// base 为代码段在脚本中的偏移，s 为进入时的状态。
+// 返回代码段结束时的状态。
+func (z *analyzer) list(code []byte, base int, s astate, c *actx) astate {
+	var f aflag
+	// 紧邻 ELSE 时使用
+	var ifPre, ifThen astate
+	ifEnd := -1
+
+	for i := 0; i < len(code); {
+		off := base + i
+		d := decode(code[i:])
+
+		if d.fail != nil {
+			z.issue(c, LevelError, off, int(code[i]), fmt.Sprint(d.fail))
+			return astate{}
+		}
+		ins := d.ins
+		data := d.data(code, i)
+
+		if s.dead {
+			z.issue(c, LevelWarning, off, ins.Code, errDead)
+			return s
+		}
+		switch ins.Code {
+		case icode.Capture:
+			f = aflag{ibase.ArgsFlag, false}
+		case icode.Bring:
+			f.from = true
+		case icode.ScopeAdd:
+			f = aflag{ibase.ScopeFlag, false}
+
+		case icode.IF:
+			z.take(&s, d.argn, f.from, off, ins.Code, c)
+			ifPre = s
+			ifThen = z.block(data, off, ins, s, c)
+			ifEnd = i + ins.Size
+			s = join(s, ifThen)
+			f = aflag{}
+
+		case icode.ELSE:
+			switch {
+			case ifEnd < 0:
+				z.issue(c, LevelError, off, ins.Code, errNoIf)
+			case ifEnd == i:
+				s = join(ifThen, z.block(data, off, ins, ifPre, c))
+			default:
+				s = join(s, z.block(data, off, ins, s, c))
+			}
+			ifEnd = -1
+			f = aflag{}
+
+		default:
+			z.inst(&s, d, data, off, f, c)
+			f = aflag{}
+		}
+		i += ins.Size
+	}
+	return s
+}
+
+// 分析单条指令。
+// data 为指令的关联数据，f 为前置标记。
+func (z *analyzer) inst(s *astate, d *decoded, data any, off int, f aflag, c *actx) {
+	ins := d.ins
+
+	if f.from && d.argn <= 0 {
+		z.issue(c, LevelWarning, off, ins.Code, errBring)
+	}
+	n := z.take(s, d.argn, f.from, off, ins.Code, c)
+	k := z.effect(s, ins, data, n, off, c)
+
+	if k == 0 && f.to != ibase.StackFlag {
+		z.issue(c, LevelWarning, off, ins.Code, errUseless)
+	}
+	// 表达式内直接参与计算
+	if c.expr {
+		if k > 1 {
+			z.issue(c, LevelError, off, ins.Code, errExprMulti)
+		}
+		return
+	}
+	z.put(s, f.to, k, off, ins.Code, c)
+}
+
+// 提取实参。
+// 规则同 Actuator.Arguments，返回提取的实参数量。
+func (z *analyzer) take(s *astate, argn int, from bool, off, code int, c *actx) count {
+	switch {
+	case argn == 0:
+		return count{0, true}
+	case argn < 0:
+		n := s.args
+		s.args = count{0, true}
+		return n
+	case from:
+		z.pop(s, argn, off, code, c)
+		return count{argn, true}
+	case s.args.exact && s.args.n == 0:
+		z.pop(s, argn, off, code, c)
+	case s.args.n > argn || s.args.exact && s.args.n != argn:
+		z.issue(c, LevelError, off, code, errArgsAmount)
+	case s.args.n == 0:
+		// 可能取自数据栈
+		z.issue(c, LevelWarning, off, code, errArgsSource)
+		s.stack = s.stack.join(s.stack.sub(argn))
+	}
+	s.args = count{0, true}
+	return count{argn, true}
+}
+
+// 弹出栈顶条目。
+func (z *analyzer) pop(s *astate, n, off, code int, c *actx) {
+	z.need(s, n, off, code, c)
+	s.stack = s.stack.sub(n)
+}
+
+// 检查栈条目数量。
+func (z *analyzer) need(s *astate, n, off, code int, c *actx) {
+	if s.stack.exact && s.stack.n < n {
+		z.issue(c, LevelError, off, code, errUnderflow)
+	}
+}
+
+// 放置返回值。
+// k 为返回值数量，负值表示不定。
+func (z *analyzer) put(s *astate, to, k, off, code int, c *actx) {
+	switch to {
+	case ibase.StackFlag:
+		z.push(s, k, off, code, c)
+	case ibase.ArgsFlag:
+		s.args = s.args.add(k)
+	case ibase.ScopeFlag:
+		if s.scope = s.scope.add(k); s.scope.n > ibase.ScopeMax {
+			z.issue(c, LevelError, off, code, errScopeMax)
+		}
+	}
+}
+
+// 数据栈添加条目。
+// 下限超出即为错误，无论数量是否确切。
+func (z *analyzer) push(s *astate, k, off, code int, c *actx) {
+	if s.stack = s.stack.add(k); s.stack.n > ibase.StackMax {
+		z.issue(c, LevelError, off, code, errStackMax)
+	}
+	if s.stack.n > z.rep.MaxStack {
+		z.rep.MaxStack = s.stack.n
+	}
+}
+
+// 指令效应。
+// 处理特殊的栈操作、流程控制和子块，n 为已提取的实参数量。
+// 返回指令的返回值数量，负值表示不定。
+func (z *analyzer) effect(s *astate, ins *Insted, data any, n count, off int, c *actx) int {
+	code := ins.Code
+
+	switch code {
+	case icode.SHIFT:
+		k := ins.Args[0].(int)
+		z.pop(s, k, off, code, c)
+		return k
+	case icode.CLONE:
+		k := ins.Args[0].(int)
+		z.need(s, k, off, code, c)
+		return k
+	case icode.POP:
+		z.pop(s, 1, off, code, c)
+	case icode.POPS:
+		if k := ins.Args[0].(int); k > 0 {
+			z.pop(s, k, off, code, c)
+		} else {
+			s.stack = count{0, true}
+		}
+	case icode.TOP:
+		z.need(s, 1, off, code, c)
+	case icode.TOPS:
+		z.need(s, ins.Args[0].(int), off, code, c)
+	case icode.DUP:
+		return ins.Args[0].(int)
+	case icode.KEYVAL:
+		if ins.Args[0].(int) == 0 {
+			return 2
+		}
+	case icode.DIVMOD:
+		return 2
+	case icode.INPUT, icode.SPREAD:
+		return -1
+
+	case icode.PUSH:
+		z.push(s, n.n, off, code, c)
+		if !n.exact {
+			s.stack.exact = false
+		}
+		return 0
+
+	case icode.ScopeVal:
+		z.scopeVal(s, ins.Args[0].(int), off, code, c)
+		return value(s, c)
+	case icode.LoopVal:
+		if !c.loop {
+			z.issue(c, LevelError, off, code, errNoLoop)
+		}
+		return value(s, c)
+
+	case icode.NOP, icode.OUTPUT, icode.BUFDUMP, icode.PRINT, icode.PASS, icode.FAIL,
+		icode.SETVAR, icode.SYS_NULL, icode.FN_PRINTF:
+		return 0
+
+	case icode.EXIT:
+		if !c.exit {
+			z.issue(c, LevelError, off, code, errNoExit)
+		}
+		s.dead = true
+		return 0
+	case icode.RETURN:
+		if !c.ret {
+			z.issue(c, LevelError, off, code, errNoReturn)
+		}
+		s.dead = true
+		return 0
+	case icode.CONTINUE, icode.BREAK:
+		z.leave(s, n, off, code, c)
+		return 0
+	case icode.FALLTHROUGH:
+		if c.fall == nil {
+			z.issue(c, LevelError, off, code, errNoCase)
+		} else {
+			*c.fall = true
+		}
+		return 0
+
+	case icode.GOTO:
+		// 目标脚本有独立的数据栈
+		if !c.gotos {
+			z.issue(c, LevelError, off, code, errNoGoto)
+		}
+		return 0
+	case icode.JUMP:
+		if !c.jumps {
+			z.issue(c, LevelError, off, code, errNoJump)
+		}
+		// 嵌入代码共享数据栈和实参区
+		s.stack, s.args = count{}, count{}
+		return 0
+
+	case icode.BLOCK:
+		*s = z.block(data, off, ins, *s, c)
+		return 0
+	case icode.SWITCH:
+		*s = z.switchOf(data, off, ins, *s, c)
+		return 0
+	case icode.CASE, icode.DEFAULT:
+		z.caseOf(s, data, off, ins, c)
+		return 0
+	case icode.EACH:
+		*s = z.each(data, off, ins, *s, c)
+		return 0
+	case icode.MAP, icode.FILTER:
+		z.scopeOf(data, n, off, ins, c)
+	case icode.CODE:
+		z.codeOf(data.(*Script).Source(), off, ins)
+	case icode.Expr:
+		b := data.([]byte)
+		z.expr(s, b, bodyAt(off, ins, b), false, c)
+
+	default:
+		if code >= icode.FN_X {
+			return -1
+		}
+	}
+	return 1
+}
+
+// 取值指令的返回数量。
+// 表达式内返回单值，否则直接进入实参区。
+func value(s *astate, c *actx) int {
+	if c.expr {
+		return 1
+	}
+	s.args = s.args.add(1)
+	return 0
+}
+
+// 局部域取值检查。
+// 表达式的执行器另有局部域，不做检查。
+func (z *analyzer) scopeVal(s *astate, i, off, code int, c *actx) {
+	if c.expr || !s.scope.exact {
+		return
+	}
+	if i < 0 {
+		i += s.scope.n
+	}
+	if i < 0 || i >= s.scope.n {
+		z.issue(c, LevelError, off, code, errScopeIndex)
+	}
+}
+
+// 跳出或跳入下一迭代。
+// 有实参时为条件跳转，路径延续。
+func (z *analyzer) leave(s *astate, n count, off, code int, c *actx) {
+	to := c.brk
+	if code == icode.CONTINUE {
+		to = c.cont
+	}
+	if to == nil {
+		z.issue(c, LevelError, off, code, errNoBreak)
+		return
+	}
+	if n.n > 1 {
+		z.issue(c, LevelError, off, code, errArgsAmount)
+	}
+	*to = append(*to, *s)
+
+	if n.exact && n.n == 0 {
+		s.dead = true
+	}
+}
+
+/*
+ * 子块分析
+ ******************************************************************************
+ */
+
+// 子块在脚本中的偏移。
+// 关联数据位于指令末尾。
+func bodyAt(off int, ins *Insted, body []byte) int {
+	return off + ins.Size - len(body)
+}
+
+// 分析子块代码。
+// 子块有新的局部域，结束后恢复上级的局部域。
+func (z *analyzer) body(data any, off int, ins *Insted, s astate, c *actx) astate {
+	code := data.([]byte)
+	scope := s.scope
+
+	s.scope = count{0, true}
+	s = z.list(code, bodyAt(off, ins, code), s, c)
+	s.scope = scope
+
+	return s
+}
+
+// 普通子块。
+// 适用：IF, ELSE, BLOCK
+func (z *analyzer) block(data any, off int, ins *Insted, s astate, c *actx) astate {
+	c2 := *c
+	c2.swit = false
+
+	return z.body(data, off, ins, s, &c2)
+}
+
+// 分支选择块。
+// 各分支的出口状态与无分支匹配时的状态汇合。
+// 注：SWITCH 内的 CONTINUE 同 BREAK。
+func (z *analyzer) switchOf(data any, off int, ins *Insted, s astate, c *actx) astate {
+	var exits []astate
+
+	c2 := *c
+	c2.swit, c2.fall, c2.ret = true, nil, false
+	c2.brk, c2.cont = &exits, &exits
+
+	x := z.body(data, off, ins, s, &c2)
+
+	for _, v := range exits {
+		x = join(x, v)
+	}
+	x.scope = s.scope
+	return x
+}
+
+// 条件分支。
+// CASE 不匹配时状态不变，匹配后跳出 SWITCH（除非 FALLTHROUGH）。
+// DEFAULT 总是跳出。
+func (z *analyzer) caseOf(s *astate, data any, off int, ins *Insted, c *actx) {
+	if !c.swit {
+		z.issue(c, LevelError, off, ins.Code, errNoSwitch)
+		return
+	}
+	var fall bool
+
+	c2 := *c
+	c2.swit, c2.fall = false, &fall
+
+	x := z.body(data, off, ins, *s, &c2)
+
+	switch {
+	case ins.Code == icode.DEFAULT:
+		*c.brk = append(*c.brk, x)
+		s.dead = true
+	case fall:
+		*s = join(*s, x)
+	default:
+		*c.brk = append(*c.brk, x)
+	}
+}
+
+// 迭代块。
+// 子块分析一次，据此推算多次迭代（含零次）后的状态。
+func (z *analyzer) each(data any, off int, ins *Insted, s astate, c *actx) astate {
+	var brk, cont []astate
+
+	c2 := *c
+	c2.loop, c2.gotos, c2.ret, c2.swit, c2.fall = true, false, false, false, nil
+	c2.brk, c2.cont = &brk, &cont
+
+	x := z.body(data, off, ins, s, &c2)
+
+	for _, v := range cont {
+		x = join(x, v)
+	}
+	r := s
+	if !x.dead {
+		r.stack = s.stack.loop(x.stack)
+		r.args = s.args.loop(x.args)
+	}
+	for _, v := range brk {
+		r = join(r, v)
+	}
+	r.scope = s.scope
+	return r
+}
+
+// 私有域块。
+// 适用：MAP, FILTER
+// n 为提取的实参数量，首个为目标集，其余为私有数据栈的初始条目。
+func (z *analyzer) scopeOf(data any, n count, off int, ins *Insted, c *actx) {
+	if n.exact && n.n == 0 {
+		z.issue(c, LevelError, off, ins.Code, errArgsEmpty)
+	}
+	s := freshState()
+	s.stack = n.sub(1)
+
+	c2 := &actx{loop: true, ret: true, soft: c.soft}
+	z.body(data, off, ins, s, c2)
+}
+
+// 代码块。
+// 由 EVAL 在独立的环境中执行，数据栈初始为空。
+func (z *analyzer) codeOf(code []byte, off int, ins *Insted) {
+	c := &actx{exit: true, soft: true}
+	z.list(code, bodyAt(off, ins, code), freshState(), c)
+}
+
+// 表达式。
+// 逐个分析操作数指令，嵌套的分组递归处理。
+// 逻辑运算（&& ||）之后的指令可能被短路，其问题降为警告，状态与未执行时汇合。
+func (z *analyzer) expr(s *astate, code []byte, base int, cond bool, c *actx) {
+	c2 := *c
+	c2.expr = true
+
+	for i := 0; i < len(code); {
+		d := decode(code[i:])
+
+		if d.fail != nil {
+			z.issue(c, LevelError, base+i, int(code[i]), fmt.Sprint(d.fail))
+			return
+		}
+		ins := d.ins
+		data := d.data(code, i)
+
+		switch op := ins.Code; {
+		case op == icode.BOTH || op == icode.EITHER:
+			cond = true
+		case expr.Operator(op) || __exprLits[op]:
+		case op == icode.Expr:
+			b := data.([]byte)
+			z.expr(s, b, bodyAt(base+i, ins, b), cond, c)
+		case cond:
+			c3 := c2
+			c3.soft = true
+			x := *s
+			z.inst(&x, d, data, base+i, aflag{}, &c3)
+			*s = join(*s, x)
+		default:
+			z.inst(s, d, data, base+i, aflag{}, &c2)
+		}
+		i += ins.Size
+	}
+}
@@ -0,0 +1,117 @@
+package inst_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cxio/suite/script/asm"
+	"github.com/cxio/suite/script/inst"
+)
+
+// 汇编并分析脚本。
+func analyze(t *testing.T, src string) *inst.Report {
+	t.Helper()
+
+	code, err := asm.Assemble([]byte(src))
+	if err != nil {
+		t.Fatalf("Assemble(%q): %v", src, err)
+	}
+	r, err := inst.Analyze(code)
+	if err != nil {
+		t.Fatalf("Analyze(%q): %v", src, err)
+	}
+	return r
+}
+
+func TestAnalyzeErrors(t *testing.T) {
+	tests := []struct {
+		src    string
+		offset int
+	}{
+		{`MUL`, 0},
+		{`@ 1 2 ~ MUL`, 6},
+		{`@ 1 2 3 MUL`, 7},
+		{strings.Repeat("1 ", 257), 512},
+		{strings.Repeat("$ 1 ", 129), 385},
+		{`$(0)`, 0},
+		{`1 RETURN`, 2},
+		{`BREAK`, 0},
+		{`${Value}`, 0},
+		{`0 1 RANGE(3) EACH{ POP }`, 9},
+		{`(POP + 1)`, 2},
+		{`ELSE{ 1 }`, 0},
+	}
+	for _, tt := range tests {
+		errs := analyze(t, tt.src).Errors()
+
+		if len(errs) != 1 || errs[0].Offset != tt.offset {
+			t.Errorf("%.32s: errors %v, want 1 at %d", tt.src, errs, tt.offset)
+		}
+	}
+}
+
+func TestAnalyzeClean(t *testing.T) {
+	tests := []struct {
+		src string
+		max int
+	}{
+		{`1 2 MUL`, 2},
+		{`1 (POP + 1)`, 1},
+		{`$ 1 $(0) NOT`, 1},
+		{`true IF{ 1 } ELSE{ 2 } 3 MUL`, 2},
+		{`0 1 RANGE(3) EACH{ 1 } POP`, 2},
+		{`0 1 RANGE(5) @ POP MAP{ (${Value} * 2 + 1) RETURN }`, 2},
+		{`1 2 SWITCH{ CASE{ 1 } DEFAULT{ 2 } }`, 2},
+	}
+	for _, tt := range tests {
+		r := analyze(t, tt.src)
+
+		if len(r.Issues) != 0 || r.MaxStack != tt.max {
+			t.Errorf("%s: issues %v, max %d, want none and %d", tt.src, r.Issues, r.MaxStack, tt.max)
+		}
+	}
+}
+
+func TestAnalyzeWarnings(t *testing.T) {
+	for _, src := range []string{
+		`1 EXIT 2`,           // 不可到达
+		`@ NOP`,              // 无返回值
+		`~ NOP`,              // 无需定量实参
+		`@ 1`,                // 实参未使用
+		`CODE{ MUL }`,        // 代码块内降级
+		`(true || POP == 1)`, // 可能被短路
+	} {
+		r := analyze(t, src)
+
+		if !r.Ok() || len(r.Issues) != 1 || r.Issues[0].Level != inst.LevelWarning {
+			t.Errorf("%s: issues %v, want 1 warning", src, r.Issues)
+		}
+	}
+}
+
+// 分析出的错误与执行时的出错位置一致。
+func TestAnalyzeRuntime(t *testing.T) {
+	for _, src := range []string{
+		`@ 1 2 ~ MUL`,
+		`@ 1 2 3 MUL`,
+		`1 2 MUL SHIFT(2)`,
+		`0 1 RANGE(3) EACH{ POP }`,
+	} {
+		errs := analyze(t, src).Errors()
+		if len(errs) == 0 {
+			t.Errorf("%s: no errors", src)
+			continue
+		}
+		r, err := inst.Execute(context.Background(), actuator(t, src))
+		if err == nil || r.Pos.Offset != errs[0].Offset {
+			t.Errorf("%s: runtime %v at %d, analyzed at %d", src, err, r.Pos.Offset, errs[0].Offset)
+		}
+	}
+}
+
+func TestAnalyzeInvalid(t *testing.T) {
+	if _, err := inst.Analyze([]byte{255}); err == nil {
+		t.Error("Analyze: want validation error")
+	}
+}
@@ -0,0 +1,91 @@
+package inst_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/cxio/suite/script/inst"
+	"github.com/cxio/suite/script/inst/expr"
+)
+
+func TestArith(t *testing.T) {
+	big1, _ := new(big.Int).SetString("9223372036854775808", 10) // 2^63
+
+	tests := []struct {
+		src  string
+		want any
+	}{
+		{"3 4 ADD", int64(7)},
+		{"3 4 SUB", int64(-1)},
+		{"7 2 DIV", int64(3)},
+		{"-7 2 DIV", int64(-3)},
+		{"2 62 POW", int64(1) << 62},
+		{"2 -1 POW", 0.5},
+		{"3 0.5 MUL", 1.5},
+		{"'a' 1 ADD", int64('b')},
+		{"9223372036854775807 9007199254740993 SUB", int64(9223372036854775807 - 9007199254740993)},
+		{"9223372036854775808 1 SUB", big.NewInt(9223372036854775807)},
+		{"9223372036854775808 2 DIV", big.NewInt(1 << 62)},
+		{"9223372036854775808 2 MUL", new(big.Int).Lsh(big1, 1)},
+		{"9223372036854775808 0.5 MUL", 4611686018427387904.0},
+		{"(1 + 2 * 3)", int64(7)},
+		{"(7 / 2)", int64(3)},
+		{"(7.0 / 2)", 3.5},
+		{"(1 - -2)", int64(3)},
+		{"(9223372036854775808 - 1)", big.NewInt(9223372036854775807)},
+		{"((2 + 3) * 4)", int64(20)},
+		{"5 NEG", int64(-5)},
+		{"1.5 NEG", -1.5},
+		{"9223372036854775808 NEG", new(big.Int).Neg(big1)},
+	}
+	for _, tt := range tests {
+		r, err := runEnv(t, tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(r.Stack) != 1 {
+			t.Errorf("%s: stack %v", tt.src, r.Stack)
+			continue
+		}
+		switch w := tt.want.(type) {
+		case *big.Int:
+			if g, ok := r.Stack[0].(*big.Int); !ok || g.Cmp(w) != 0 {
+				t.Errorf("%s = %#v, want %v", tt.src, r.Stack[0], w)
+			}
+		default:
+			if r.Stack[0] != tt.want {
+				t.Errorf("%s = %#v, want %#v", tt.src, r.Stack[0], tt.want)
+			}
+		}
+	}
+}
+
+func TestArithFail(t *testing.T) {
+	tests := []struct {
+		src string
+		err error
+	}{
+		{"9223372036854775807 1 ADD", expr.ErrOverflow},
+		{"-9223372036854775807 2 SUB", expr.ErrOverflow},
+		{"4294967296 4294967296 MUL", expr.ErrOverflow},
+		{"2 63 POW", expr.ErrOverflow},
+		{"1 0 DIV", expr.ErrDivZero},
+		{"9223372036854775808 0 DIV", expr.ErrDivZero},
+		{"9223372036854775808 200 POW", expr.ErrOverflow},
+		{"(9223372036854775807 + 1)", expr.ErrOverflow},
+		{"-9223372036854775807 1 SUB NEG", expr.ErrOverflow},
+	}
+	for _, tt := range tests {
+		_, err := runEnv(t, tt.src)
+		var ee *inst.ExecError
+
+		if !errors.As(err, &ee) || !errors.Is(err, tt.err) {
+			t.Errorf("%s: %v", tt.src, err)
+		}
+	}
+	if _, err := runEnv(t, `"a" 1 SUB`); !errors.As(err, new(*inst.ExecError)) || err.(*inst.ExecError).Kind != inst.KindType {
+		t.Errorf("string operand: %v", err)
+	}
+}
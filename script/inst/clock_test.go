@@ -0,0 +1,97 @@
+package inst_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cxio/suite/script/asm"
+	"github.com/cxio/suite/script/ibase"
+	"github.com/cxio/suite/script/inst"
+	"github.com/cxio/suite/script/instor"
+)
+
+// 共识模式的执行器。
+func consensus(t *testing.T, src string, txid []byte, stamp int64) *inst.Actuator {
+	t.Helper()
+
+	code, err := asm.Assemble([]byte(src))
+	if err != nil {
+		t.Fatalf("Assemble(%q): %v", src, err)
+	}
+	env := &ibase.TxEnv{
+		Envs: map[int]any{
+			instor.EnvTxID:      txid,
+			instor.EnvTimestamp: instor.Int(stamp),
+		},
+	}
+	a := ibase.NewActuator([]byte("test"), code, nil, ibase.NewEnvs(env, nil, 0), 1)
+	a.SetConsensus()
+
+	return a
+}
+
+func TestConsensusRandom(t *testing.T) {
+	const src = `@ 1000000 RANDOM QRANDOM DATA{0x0102030405060708} SRAND`
+
+	run := func(txid string) []any {
+		r, err := inst.Execute(context.Background(), consensus(t, src, []byte(txid), 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.Stack
+	}
+	s1, s2 := run("tx-1"), run("tx-1")
+
+	if len(s1) != 3 {
+		t.Fatalf("stack: %v", s1)
+	}
+
+	for i := range s1 {
+		if !equal(s1[i], s2[i]) {
+			t.Errorf("stack[%d]: %v != %v", i, s1[i], s2[i])
+		}
+	}
+	if s3 := run("tx-2"); equal(s1[0], s3[0]) && equal(s1[1], s3[1]) {
+		t.Errorf("different txid, same randoms: %v", s3)
+	}
+}
+
+func TestConsensusTime(t *testing.T) {
+	stamp := time.Date(2024, 2, 29, 23, 30, 0, 0, time.UTC).UnixMilli()
+	a := consensus(t, `SYS_TIME{Year} SYS_TIME{Hour} SYS_TIME{Stamp}`, nil, stamp)
+
+	r, err := inst.Execute(context.Background(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []any{instor.Int(2024), instor.Int(23), stamp}
+
+	for i, v := range want {
+		if r.Stack[i] != v {
+			t.Errorf("stack[%d]: %v, want %v", i, r.Stack[i], v)
+		}
+	}
+}
+
+// 非共识模式可注入时钟。
+func TestClock(t *testing.T) {
+	a := actuator(t, `SYS_TIME{Month}`)
+	a.SetClock(func() time.Time { return time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC) })
+
+	r, err := inst.Execute(context.Background(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Stack[0] != instor.Int(7) {
+		t.Errorf("month: %v", r.Stack[0])
+	}
+}
+
+// 简单值或字节序列比较。
+func equal(x, y any) bool {
+	if b, ok := x.([]byte); ok {
+		return string(b) == string(y.([]byte))
+	}
+	return x == y
+}
@@ -0,0 +1,213 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package inst
+
+import "github.com/cxio/suite/script/icode"
+
+//
+// 执行成本
+// 每条指令在调用前计入成本，由基础成本和数据量相关的附加成本构成。
+// 预算由执行器设置（Actuator.SetGasLimit），超出时以 ibase.ErrGas 结束执行。
+///////////////////////////////////////////////////////////////////////////////
+
+// 成本基准。
+const (
+	gasBase  = 1   // 普通指令
+	gasBlock = 2   // 子块执行（IF, EACH, MAP 等）
+	gasItem  = 1   // 集合成员（每项）
+	gasWord  = 1   // 数据处理（每32字节）
+	gasRegex = 10  // 正则匹配
+	gasHash  = 20  // 哈希运算
+	gasSig   = 200 // 签名验证（每个）
+	gasJump  = 50  // 外部脚本载入（GOTO/JUMP）
+)
+
+// 附加成本计算器。
+// ins 为指令信息包，vs 为指令的实参序列。
+type coster func(ins *Insted, vs []any) int
+
+// 指令基础成本集。
+var __gasBase [256]int
+
+// 指令附加成本集。
+// 仅数据量相关的指令需要设置。
+var __gasSize = make(map[int]coster)
+
+// 计算指令成本。
+// c 为指令码（扩展类指令按主指令码计）。
+func instCost(c int, ins *Insted, vs []any) int {
+	n := __gasBase[c]
+
+	if f := __gasSize[c]; f != nil {
+		n += f(ins, vs)
+	}
+	return n
+}
+
+// 数据大小。
+// 字节序列和文本串为字节数，集合为成员数，其它为0。
+func sizeOf(v any) int {
+	switch x := v.(type) {
+	case Bytes:
+		return len(x)
+	case String:
+		return len(x)
+	case Runes:
+		return len(x)
+	case []any:
+		return len(x)
+	case []Int:
+		return len(x)
+	case []Float:
+		return len(x)
+	case []String:
+		return len(x)
+	case Dict:
+		return len(x)
+	}
+	return 0
+}
+
+// 按字计量的数据量（32字节一字，不足计1）。
+func words(n int) int {
+	return (n + 31) / 32
+}
+
+// 首个实参的数据量（按字）。
+func costWords(_ *Insted, vs []any) int {
+	if len(vs) == 0 {
+		return 0
+	}
+	return words(sizeOf(vs[0])) * gasWord
+}
+
+// 首个实参的成员数。
+func costItems(_ *Insted, vs []any) int {
+	if len(vs) == 0 {
+		return 0
+	}
+	return sizeOf(vs[0]) * gasItem
+}
+
+// 深层复制的成员数。
+// 与 deepCopy 的复制范围一致：[]any 成员递归计入，其它切片计其成员数。
+func costDeep(_ *Insted, vs []any) int {
+	if len(vs) == 0 {
+		return 0
+	}
+	return deepSize(vs[0]) * gasItem
+}
+
+// 递归的成员数。
+func deepSize(v any) int {
+	x, ok := v.([]any)
+	if !ok {
+		return sizeOf(v)
+	}
+	n := len(x)
+	for _, v := range x {
+		n += deepSize(v)
+	}
+	return n
+}
+
+// 全部实参的成员数。
+func costAllItems(_ *Insted, vs []any) int {
+	n := 0
+	for _, v := range vs {
+		n += sizeOf(v)
+	}
+	return n * gasItem
+}
+
+// 粘合的数据量。
+// 实参为切片，成员为字节、字符或序列。
+func costGlue(_ *Insted, vs []any) int {
+	if len(vs) == 0 {
+		return 0
+	}
+	x, ok := vs[0].([]any)
+	if !ok {
+		return words(sizeOf(vs[0])) * gasWord
+	}
+	n := len(x)
+	for _, v := range x {
+		n += sizeOf(v)
+	}
+	return words(n) * gasWord
+}
+
+// 多重签名验证，按签名数量计。
+func costSigs(_ *Insted, vs []any) int {
+	if len(vs) == 0 {
+		return 0
+	}
+	return sizeOf(vs[0]) * gasSig
+}
+
+// 序列生成，按附参的成员数计。
+func costRange(ins *Insted, _ []any) int {
+	return ins.Args[0].(int) * gasItem
+}
+
+// 模式匹配，按实参脚本和模式代码的长度计。
+// 模式区内正则匹配（RE）的目标为脚本中的指令数据，因此以脚本的每字计一次正则成本，
+// 作为其上限。
+func costModel(ins *Insted, vs []any) int {
+	if len(vs) == 0 {
+		return 0
+	}
+	n := words(len(scriptCode(vs[0]))) * gasRegex
+
+	// 附参[1]为模式代码长度
+	return n + words(ins.Args[1].(int))*gasWord
+}
+
+// 正则表达式编译，按源码长度计。
+func costRegExp(ins *Insted, _ []any) int {
+	return words(ins.Size) * gasWord
+}
+
+func init() {
+	for i := range __gasBase {
+		__gasBase[i] = gasBase
+	}
+	set := func(n int, cs ...int) {
+		for _, c := range cs {
+			__gasBase[c] = n
+		}
+	}
+	sized := func(f coster, cs ...int) {
+		for _, c := range cs {
+			__gasSize[c] = f
+		}
+	}
+	set(gasBlock,
+		icode.IF, icode.ELSE, icode.SWITCH, icode.CASE, icode.DEFAULT,
+		icode.EACH, icode.BLOCK, icode.MAP, icode.FILTER, icode.EVAL, icode.Expr,
+	)
+	set(gasRegex, icode.RegExp, icode.MATCH, icode.REPLACE, icode.RE, icode.MODEL)
+	set(gasHash,
+		icode.FN_HASH224, icode.FN_HASH256, icode.FN_HASH384, icode.FN_HASH512,
+		icode.FN_PUBHASH, icode.FN_MPUBHASH, icode.FN_MCHECKSIG,
+	)
+	set(gasSig, icode.FN_CHECKSIG)
+	set(gasJump, icode.GOTO, icode.JUMP)
+
+	// 数据量相关
+	sized(costWords,
+		icode.FN_HASH224, icode.FN_HASH256, icode.FN_HASH384, icode.FN_HASH512,
+		icode.MATCH, icode.REPLACE, icode.FN_BASE58, icode.FN_BASE32, icode.FN_BASE64,
+	)
+	sized(costItems,
+		icode.EACH, icode.MAP, icode.FILTER, icode.COPY, icode.REVERSE, icode.SPREAD,
+	)
+	sized(costDeep, icode.DCOPY)
+	sized(costAllItems, icode.MERGE, icode.EXPAND)
+	sized(costGlue, icode.GLUE)
+	sized(costSigs, icode.FN_MCHECKSIG)
+	sized(costRange, icode.RANGE)
+	sized(costRegExp, icode.RegExp)
+	sized(costModel, icode.MODEL)
+}
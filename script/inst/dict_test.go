@@ -0,0 +1,93 @@
+package inst_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cxio/suite/script/asm"
+	"github.com/cxio/suite/script/ibase"
+	"github.com/cxio/suite/script/inst"
+)
+
+// 字典构造代码（DICT 的实参）。
+// 键名为 k9...k0（逆序），值为对应的序号。
+func dictSource() string {
+	var ks, vs strings.Builder
+
+	for i := 9; i >= 0; i-- {
+		fmt.Fprintf(&ks, `"k%d" `, i)
+		fmt.Fprintf(&vs, `%d `, i)
+	}
+	return ks.String() + "POPS(10) " + vs.String() + "POPS(10) "
+}
+
+// 执行并返回结果的文本表示（含导出区数据）。
+func dictRun(t *testing.T, src string) string {
+	t.Helper()
+
+	code, err := asm.Assemble([]byte(src))
+	if err != nil {
+		t.Fatalf("Assemble(%q): %v", src, err)
+	}
+	ch := make(chan ibase.Middler, 1)
+	a := ibase.NewActuator([]byte("test"), code, ch, ibase.NewEnvs(nil, nil, 0), 1)
+
+	r, err := inst.Execute(context.Background(), a)
+	if err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	out := fmt.Sprintf("%#v", r.Stack)
+
+	if strings.Contains(src, "BUFDUMP") {
+		out += fmt.Sprintf(" %#v", (<-ch).Data)
+	}
+	return out
+}
+
+// 字典迭代的结果在多次执行中完全相同，且按键名升序。
+func TestDictOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"EACH", `DICT EACH{ @ ${Key} OUTPUT } BUFDUMP(1)`, `{"k0", "k1", "k2"`},
+		{"KEYVAL", `DICT KEYVAL(0)`, `[]string{"k0", "k1", "k2"`},
+		{"MAP", `@ DICT MAP{ ${Key} RETURN }`, `{"k0", "k1", "k2"`},
+		{"FILTER", `@ DICT FILTER{ true RETURN } KEYVAL(1)`, `[]string{"k0", "k1", "k2"`},
+	}
+	for _, tt := range tests {
+		src := dictSource() + tt.src
+		first := dictRun(t, src)
+
+		if !strings.Contains(first, tt.want) {
+			t.Errorf("%s: unsorted result %s", tt.name, first)
+		}
+		for range 20 {
+			if got := dictRun(t, src); got != first {
+				t.Fatalf("%s: result changed:\n%s\n%s", tt.name, first, got)
+			}
+		}
+	}
+}
+
+// 切片上的 MAP/FILTER。
+// 每次迭代的私有域有独立的循环变量（ScopeNew 曾缺少它而在首次迭代时崩溃）。
+func TestScopeSlice(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"MAP", `0 1 RANGE(5) @ POP MAP{ ${Value} ${Value} MUL RETURN }`, `[]interface {}{[]interface {}{0, 1, 4, 9, 16}}`},
+		{"FILTER", `10 1 RANGE(6) @ POP FILTER{ (${Value} % 2 == 0) RETURN }`, `[]interface {}{[]interface {}{10, 12, 14}}`},
+		{"nested", `0 1 RANGE(3) @ POP MAP{ 0 1 RANGE(2) @ POP FILTER{ true RETURN } RETURN }`, `[]interface {}{[]interface {}{[]interface {}{0, 1}, []interface {}{0, 1}, []interface {}{0, 1}}}`},
+	}
+	for _, tt := range tests {
+		if got := dictRun(t, tt.src); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
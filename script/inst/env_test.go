@@ -0,0 +1,119 @@
+package inst_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cxio/suite/cbase/sigs"
+	"github.com/cxio/suite/cbase/tx"
+	"github.com/cxio/suite/script/asm"
+	"github.com/cxio/suite/script/ibase"
+	"github.com/cxio/suite/script/inst"
+	"github.com/cxio/suite/script/instor"
+)
+
+// 交易数据环境。
+func txEnv() *ibase.TxEnv {
+	body := tx.NewBody(
+		[]tx.Vin{{1}},
+		[]tx.Vout{
+			tx.CoinOut(&tx.Coin{Receiver: []byte("receiver"), Amount: 300}),
+			tx.EvidenceOut(&tx.Evidence{Title: []byte("title")}),
+		},
+	)
+	return &ibase.TxEnv{
+		Header:  &tx.Header{Timestamp: 1700000000000},
+		Body:    body,
+		Sources: []tx.Vout{tx.CoinOut(&tx.Coin{Amount: 500})},
+		Envs:    map[int]any{instor.EnvHeight: instor.Int(100)},
+	}
+}
+
+// 以交易数据环境执行脚本。
+func runEnv(t *testing.T, src string) (*inst.Result, error) {
+	t.Helper()
+	return runEnvVer(t, src, 1)
+}
+
+// 以交易数据环境和指定的脚本版本执行脚本。
+func runEnvVer(t *testing.T, src string, ver int) (*inst.Result, error) {
+	t.Helper()
+
+	code, err := asm.Assemble([]byte(src))
+	if err != nil {
+		t.Fatalf("Assemble(%q): %v", src, err)
+	}
+	envs := ibase.NewEnvs(txEnv(), nil, 2)
+	a := ibase.NewActuator([]byte("test"), code, nil, envs, ver)
+
+	return inst.Execute(context.Background(), a)
+}
+
+func TestTxEnv(t *testing.T) {
+	r, err := runEnv(t, `
+		ENV{OutAmount} 300 EQUAL PASS
+		ENV{InAmout} 500 EQUAL PASS
+		ENV{Height} 100 EQUAL PASS
+		IN{Amount} 500 EQUAL PASS
+		OUT{0, Amount} OUT{0, Amount} EQUAL PASS
+		@ OUT{1, Title} EXIT`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := r.Exit.(instor.Bytes); !ok || !bytes.Equal(b, []byte("title")) {
+		t.Errorf("exit data: %v", r.Exit)
+	}
+}
+
+func TestTxEnvMissing(t *testing.T) {
+	for _, src := range []string{`ENV{TxID}`, `OUT{5, Amount}`} {
+		_, err := runEnv(t, src)
+
+		e, ok := err.(*inst.ExecError)
+		if !ok || e.Kind != inst.KindLookup {
+			t.Errorf("%s: expect lookup error, got %v", src, err)
+		}
+	}
+}
+
+// 签名验证使用与钱包端相同的签名消息，方案由脚本版本决定。
+func TestCheckSig(t *testing.T) {
+	env := txEnv()
+	seed := bytes.Repeat([]byte{7}, 32)
+
+	for _, ver := range []int{sigs.Ed25519, sigs.ECDSAP256, sigs.Schnorr25519} {
+		s, _ := sigs.Get(ver)
+		key, err := s.NewKey(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := tx.SigHash(ver, []byte("test"), env.Header, env.Body, env.Index, instor.SigSingle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := key.Sign(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range []struct {
+			flag string
+			ver  int
+			want bool
+		}{
+			{"Single", ver, true},
+			{"All", ver, false},
+			{"Single", ver%3 + 1, false}, // 其它方案
+		} {
+			src := fmt.Sprintf(`DATA{0x%x} DATA{0x%x} FN_CHECKSIG{%s}`, sig, key.Public(), tt.flag)
+			r, err := runEnvVer(t, src, tt.ver)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Stack[0] != tt.want {
+				t.Errorf("%s, ver %d (key ver %d): %v, want %v", tt.flag, tt.ver, ver, r.Stack[0], tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package inst
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/cxio/suite/script/ibase"
+	"github.com/cxio/suite/script/instor"
+)
+
+//
+// 结构化执行
+// 指令内部以 panic 中断执行流，此处统一捕获并转换为结果和分类错误，
+// 外部无需自行 recover 或匹配出错消息。
+///////////////////////////////////////////////////////////////////////////////
+
+// 指令位置引用。
+type Position = ibase.Position
+
+// 错误类别。
+type ErrKind int
+
+// 错误类别定义。
+const (
+	KindRuntime  ErrKind = iota // 一般运行错误
+	KindInvalid                 // 脚本格式无效（执行前校验）
+	KindVerify                  // 验证未通过（通关检查、模式匹配等）
+	KindType                    // 实参类型不符
+	KindLimit                   // 资源限额超出
+	KindLookup                  // 外部数据获取失败
+	KindCanceled                // 执行被取消或超时
+)
+
+// 错误类别名称。
+var __kindNames = []string{
+	KindRuntime:  _T("运行错误"),
+	KindInvalid:  _T("格式无效"),
+	KindVerify:   _T("验证失败"),
+	KindType:     _T("类型错误"),
+	KindLimit:    _T("超出限额"),
+	KindLookup:   _T("外部查询失败"),
+	KindCanceled: _T("执行取消"),
+}
+
+func (k ErrKind) String() string {
+	if k < 0 || int(k) >= len(__kindNames) {
+		return fmt.Sprintf("ErrKind(%d)", int(k))
+	}
+	return __kindNames[k]
+}
+
+// 执行错误。
+// 携带错误类别和出错指令的位置，原始错误可由 errors.Is/As 检视。
+type ExecError struct {
+	Kind ErrKind  // 错误类别
+	Pos  Position // 出错指令位置
+	Err  error    // 原始错误
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf(_T("%s：偏移 %d 处的指令（%d）：%v"), e.Kind, e.Pos.Offset, e.Pos.Code, e.Err)
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// 执行结果。
+type Result struct {
+	Pass  bool     // 是否通过
+	Exit  any      // EXIT 携带的数据
+	Stack []any    // 结束时的数据栈（顶层脚本）
+	Pos   Position // 最后执行的指令位置，出错时即出错指令
+	Gas   int64    // 消耗的执行成本
+}
+
+// 执行脚本。
+// 先校验脚本格式，然后运行顶层代码，捕获执行中的全部中断。
+// 正常结束或 EXIT 退出视为通过，返回的错误为nil。
+// 否则返回 *ExecError，结果中的 Pass 为假。
+// ctx 可用于取消执行，取消会在下一条指令之前生效。
+// 成本预算需预先设置（a.SetGasLimit），超出时的错误类别为 KindLimit。
+// 注：
+// 结果总是有效（非nil），失败时也包含当时的数据栈。
+func Execute(ctx context.Context, a *Actuator) (r *Result, err error) {
+	r = new(Result)
+
+	if e := instor.Validate(a.Script.Source()); e != nil {
+		var ce *instor.CodeError
+		if errors.As(e, &ce) {
+			r.Pos = Position{ID: a.ID, Offset: ce.Offset, Code: ce.Code}
+		}
+		return r, &ExecError{Kind: KindInvalid, Pos: r.Pos, Err: e}
+	}
+	a.SetContext(ctx)
+
+	defer func() {
+		v := recover()
+		r.Stack = a.StackData()
+		r.Pos = a.Position()
+		r.Gas = a.GasUsed()
+
+		switch x := v.(type) {
+		case nil:
+			r.Pass = true
+			return
+		case Leave:
+			if x.Kind == EXIT {
+				r.Pass, r.Exit = true, x.Data
+				return
+			}
+			v = neverToHere // 主体代码禁止 RETURN
+		}
+		k, e := classify(v)
+		err = &ExecError{Kind: k, Pos: r.Pos, Err: e}
+	}()
+	codeRun(a)
+
+	return
+}
+
+// 中断值分类。
+// 返回错误类别和对应的错误值。
+func classify(v any) (ErrKind, error) {
+	var err error
+
+	switch x := v.(type) {
+	case error:
+		err = x
+	case string:
+		err = errors.New(x)
+	default:
+		// 如 CONTINUE/BREAK 越出了所属的块
+		err = fmt.Errorf(_T("非预期的中断：%v"), x)
+	}
+	var (
+		le *ibase.LimitError
+		ke *ibase.LookupError
+		te *runtime.TypeAssertionError
+	)
+	switch {
+	case errors.Is(err, NotPass), errors.Is(err, ErrModel):
+		return KindVerify, err
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return KindCanceled, err
+	case errors.As(err, &le):
+		return KindLimit, err
+	case errors.As(err, &ke):
+		return KindLookup, err
+	case errors.As(err, &te):
+		return KindType, err
+	}
+	return KindRuntime, err
+}
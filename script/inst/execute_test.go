@@ -0,0 +1,252 @@
+package inst_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cxio/suite/script/asm"
+	"github.com/cxio/suite/script/ibase"
+	"github.com/cxio/suite/script/inst"
+	"github.com/cxio/suite/script/instor"
+	"github.com/cxio/suite/script/xpool"
+)
+
+// 汇编并创建执行器。
+func actuator(t *testing.T, src string) *inst.Actuator {
+	t.Helper()
+
+	code, err := asm.Assemble([]byte(src))
+	if err != nil {
+		t.Fatalf("Assemble(%q): %v", src, err)
+	}
+	return ibase.NewActuator([]byte("test"), code, nil, ibase.NewEnvs(nil, nil, 0), 1)
+}
+
+func TestExecutePass(t *testing.T) {
+	r, err := inst.Execute(context.Background(), actuator(t, `1 2 true PASS`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Pass || len(r.Stack) != 2 {
+		t.Errorf("result: %+v", r)
+	}
+	r, err = inst.Execute(context.Background(), actuator(t, `1 @ "ok" EXIT 3`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Pass || r.Exit != "ok" || len(r.Stack) != 1 {
+		t.Errorf("exit result: %+v", r)
+	}
+}
+
+func TestExecuteFail(t *testing.T) {
+	tests := []struct {
+		src    string
+		kind   inst.ErrKind
+		offset int
+	}{
+		{`true PASS false PASS`, inst.KindVerify, 3},
+		{`NOP 1 PASS`, inst.KindType, 3},
+		{`BLOCK{ NOP false PASS }`, inst.KindVerify, 4},
+		{`IF{ Mul }`, inst.KindInvalid, 2},
+		{strings.Repeat("1 ", ibase.StackMax+1), inst.KindLimit, 2 * ibase.StackMax},
+		{`GOTO(1, 2, 3)`, inst.KindLookup, 0},
+	}
+	for _, tt := range tests {
+		r, err := inst.Execute(context.Background(), actuator(t, tt.src))
+
+		var e *inst.ExecError
+		if !errors.As(err, &e) {
+			t.Errorf("%.20q: expect *ExecError, got %v", tt.src, err)
+			continue
+		}
+		if r.Pass || e.Kind != tt.kind || e.Pos.Offset != tt.offset {
+			t.Errorf("%.20q: got %v at %d, want %v at %d", tt.src, e.Kind, e.Pos.Offset, tt.kind, tt.offset)
+		}
+	}
+}
+
+func TestExecuteCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := inst.Execute(ctx, actuator(t, `true PASS`))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expect canceled, got %v", err)
+	}
+}
+
+func TestExecuteGas(t *testing.T) {
+	a := actuator(t, `1 2 3 true PASS`)
+
+	r, err := inst.Execute(context.Background(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Gas != 5 {
+		t.Errorf("gas used: %d, want 5", r.Gas)
+	}
+	a = actuator(t, `1 2 3 true PASS`)
+	a.SetGasLimit(3)
+
+	r, err = inst.Execute(context.Background(), a)
+	if !errors.Is(err, ibase.ErrGas) {
+		t.Fatalf("expect out of gas, got %v", err)
+	}
+	if r.Pos.Offset != 6 || r.Gas != 4 {
+		t.Errorf("out of gas at %d (gas %d), want 6 (gas 4)", r.Pos.Offset, r.Gas)
+	}
+}
+
+// 数据量相关的成本随实参增长。
+func TestExecuteGasSized(t *testing.T) {
+	small := actuator(t, `DATA{0x01} FN_HASH256{sha2}`)
+	large := actuator(t, `DATA{0x`+strings.Repeat("ab", 200)+`} FN_HASH256{sha2}`)
+
+	r1, err := inst.Execute(context.Background(), small)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := inst.Execute(context.Background(), large)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r2.Gas-r1.Gas != 6 {
+		t.Errorf("sized gas: %d vs %d", r1.Gas, r2.Gas)
+	}
+}
+
+// 深层复制按全部层级的成员计，模式匹配按脚本长度计。
+func TestExecuteGasDeep(t *testing.T) {
+	gas := func(src string) int64 {
+		t.Helper()
+		r, err := inst.Execute(context.Background(), actuator(t, src))
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		return r.Gas
+	}
+	nested := `0 1 RANGE(100) 0 1 RANGE(100) POPS(2) `
+	if d := gas(nested+"DCOPY") - gas(nested+"COPY"); d != 200 {
+		t.Errorf("DCOPY over COPY: %d, want 200", d)
+	}
+	text := func(n int) string {
+		code, err := asm.Assemble([]byte(`"` + strings.Repeat("a", n) + `"`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprintf("DATA{0x%x} MODEL{ RE{/a/} }", code)
+	}
+	// 2字节和202字节的脚本，相差6字，每字计一次正则成本
+	if d := gas(text(200)) - gas(text(0)); d != 6*10 {
+		t.Errorf("MODEL: %d, want %d", d, 6*10)
+	}
+}
+
+// 预算由循环等子块共享。
+func TestExecuteGasLoop(t *testing.T) {
+	a := actuator(t, `0 1 RANGE(1000) EACH{ NOP }`)
+	a.SetGasLimit(2500)
+
+	r, err := inst.Execute(context.Background(), a)
+	if !errors.Is(err, ibase.ErrGas) {
+		t.Fatalf("expect out of gas, got %v (gas %d)", err, r.Gas)
+	}
+}
+
+// 跨脚本跳转由注册的解析器提供目标脚本。
+func TestExecuteGoto(t *testing.T) {
+	dir := t.TempDir()
+	code, err := asm.Assemble([]byte(`@ "far" EXIT`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, xpool.FileName(300, 1, 0)), code, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	xpool.SetResolver(xpool.DirResolver(dir))
+	defer xpool.SetResolver(nil)
+
+	r, err := inst.Execute(context.Background(), actuator(t, `GOTO(300, 1, 0)`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Exit != "far" {
+		t.Errorf("goto exit: %v", r.Exit)
+	}
+	_, err = inst.Execute(context.Background(), actuator(t, `JUMP(300, 1, 1)`))
+
+	var e *inst.ExecError
+	if !errors.As(err, &e) || e.Kind != inst.KindLookup || !errors.Is(err, xpool.ErrNotFound) {
+		t.Errorf("missing script: %v", err)
+	}
+	// 截断的脚本在执行前被校验拒绝
+	if err := os.WriteFile(filepath.Join(dir, xpool.FileName(300, 1, 2)), code[:len(code)-2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = inst.Execute(context.Background(), actuator(t, `GOTO(300, 1, 2)`))
+
+	var ce *instor.CodeError
+	if !errors.As(err, &e) || e.Kind != inst.KindLookup || !errors.As(err, &ce) {
+		t.Errorf("invalid script: %v", err)
+	}
+}
+
+// 内容相同的子块在不同脚本中共享预解码缓存，出错位置各自独立。
+func TestExecuteShared(t *testing.T) {
+	for _, tt := range []struct {
+		src    string
+		offset int
+	}{
+		{`BLOCK{ NOP DATA{0x01} false PASS }`, 7},
+		{`NOP NOP BLOCK{ NOP DATA{0x01} false PASS }`, 9},
+		{`0 1 RANGE(3) EACH{ NOP DATA{0x01} false PASS }`, 14},
+	} {
+		r, err := inst.Execute(context.Background(), actuator(t, tt.src))
+
+		var e *inst.ExecError
+		if !errors.As(err, &e) || e.Kind != inst.KindVerify || r.Pos.Offset != tt.offset {
+			t.Errorf("%s: got %v at %d, want %d", tt.src, err, r.Pos.Offset, tt.offset)
+		}
+		if len(r.Stack) == 0 || fmt.Sprint(r.Stack[len(r.Stack)-1]) != "[1]" {
+			t.Errorf("%s: stack %v", tt.src, r.Stack)
+		}
+	}
+}
+
+// 循环密集的脚本，子块和表达式在首次执行后不再解析。
+// nocache 为停用缓存的对比（每次执行都重新解析）。
+func BenchmarkLoop(b *testing.B) {
+	tests := []struct{ name, src string }{
+		{"EACH", `0 1 RANGE(100) EACH{ NOP NOP NOP NOP }`},
+		{"MAP", `0 1 RANGE(100) @ POP MAP{ (${Value} * 2 + 1) RETURN }`},
+		{"FILTER", `0 1 RANGE(100) @ POP FILTER{ (${Value} % 3 == 0 || ${Value} > 90) RETURN }`},
+	}
+	for _, tt := range tests {
+		code, err := asm.Assemble([]byte(tt.src))
+		if err != nil {
+			b.Fatal(err)
+		}
+		run := func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				a := ibase.NewActuator([]byte("test"), code, nil, ibase.NewEnvs(nil, nil, 0), 1)
+				if _, err := inst.Execute(context.Background(), a); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		b.Run(tt.name, run)
+
+		b.Run(tt.name+"/nocache", func(b *testing.B) {
+			inst.SetCodeCache(false)
+			defer inst.SetCodeCache(true)
+			run(b)
+		})
+	}
+}
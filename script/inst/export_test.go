@@ -0,0 +1,7 @@
+package inst
+
+// 启用或停用预解码和表达式的缓存。
+// 用于基准测试对比缓存的效果。
+func SetCodeCache(on bool) {
+	codeCacheOff = !on
+}
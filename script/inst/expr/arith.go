@@ -0,0 +1,328 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package expr
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+//
+// 数值运算
+// 保持类型的四则运算、求模和幂运算，供表达式和 MUL/DIV/ADD/SUB/MOD/POW 指令共用。
+//
+// 类型提升（Byte、Rune 视同 Int）：
+//
+//	         Int        *BigInt    Float
+//	Int      Int        *BigInt    Float
+//	*BigInt  *BigInt    *BigInt    Float
+//	Float    Float      Float      Float
+//
+// 规则：
+// - Int 运算溢出时抛出 ErrOverflow，不会静默回绕或转为浮点数。
+// - 整数除法向零截断，除数为零时抛出 ErrDivZero，求模同理（结果与被除数同号）。
+// - 整数幂的指数为负时，结果为 Float。
+// - 大整数的乘积和幂超出 MaxBits 位时抛出 ErrOverflow。
+// - 结果不做缩减，即 *BigInt 运算的结果总是 *BigInt。
+///////////////////////////////////////////////////////////////////////////////
+
+// 大整数运算结果的位数上限。
+const MaxBits = 8192
+
+var (
+	// 整数运算溢出。
+	ErrOverflow = errors.New(_T("整数运算溢出"))
+
+	// 整数除零。
+	ErrDivZero = errors.New(_T("整数除数为零"))
+)
+
+// 数值类别。
+const (
+	kindInt = iota
+	kindBig
+	kindFloat
+)
+
+// 乘法。
+func Mul(x, y any) any { return arith(_Mul, x, y) }
+
+// 除法。
+func Div(x, y any) any { return arith(_Div, x, y) }
+
+// 加法。
+func Add(x, y any) any { return arith(_Add, x, y) }
+
+// 减法。
+func Sub(x, y any) any { return arith(_Sub, x, y) }
+
+// 求模。
+func Mod(x, y any) any { return arith(_Mod, x, y) }
+
+// 幂运算。
+func Pow(x, y any) any { return arith(_Pow, x, y) }
+
+// 取负。
+func Neg(x any) any {
+	switch v := normal(x).(type) {
+	case int64:
+		if v == math.MinInt64 {
+			panic(ErrOverflow)
+		}
+		return -v
+	case *big.Int:
+		return new(big.Int).Neg(v)
+	}
+	return -x.(float64)
+}
+
+// 双操作数运算。
+// op 为表达式运算符码（_Mul 等）。
+func arith(op int, x, y any) any {
+	x, y = normal(x), normal(y)
+
+	switch max(kindOf(x), kindOf(y)) {
+	case kindInt:
+		return intArith(op, x.(int64), y.(int64))
+	case kindBig:
+		return bigArith(op, toBig(x), toBig(y))
+	}
+	return floatArith(op, toFloat(x), toFloat(y))
+}
+
+// 规范化数值。
+// Byte、Rune 转为 Int，其它类型原样返回。
+func normal(v any) any {
+	switch x := v.(type) {
+	case byte:
+		return int64(x)
+	case rune:
+		return int64(x)
+	}
+	return v
+}
+
+// 数值类别。
+// 非数值类型引发类型断言错误。
+func kindOf(v any) int {
+	switch v.(type) {
+	case int64:
+		return kindInt
+	case *big.Int:
+		return kindBig
+	}
+	_ = v.(float64)
+	return kindFloat
+}
+
+// 转为大整数。
+// 注：大整数原样返回，调用者不应修改。
+func toBig(v any) *big.Int {
+	if x, ok := v.(*big.Int); ok {
+		return x
+	}
+	return big.NewInt(v.(int64))
+}
+
+// 转为浮点数。
+func toFloat(v any) float64 {
+	switch x := v.(type) {
+	case int64:
+		return float64(x)
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(x).Float64()
+		return f
+	}
+	return v.(float64)
+}
+
+// 浮点数运算。
+func floatArith(op int, x, y float64) float64 {
+	switch op {
+	case _Mul:
+		return x * y
+	case _Div:
+		return x / y
+	case _Add:
+		return x + y
+	case _Sub:
+		return x - y
+	case _Mod:
+		return math.Mod(x, y)
+	case _Pow:
+		return math.Pow(x, y)
+	}
+	panic(errOperator(op))
+}
+
+// 整数运算（检查溢出）。
+func intArith(op int, x, y int64) any {
+	switch op {
+	case _Mul:
+		return mulInt(x, y)
+	case _Div:
+		if y == 0 {
+			panic(ErrDivZero)
+		}
+		if x == math.MinInt64 && y == -1 {
+			panic(ErrOverflow)
+		}
+		return x / y
+	case _Add:
+		z := x + y
+		if (x^z)&(y^z) < 0 {
+			panic(ErrOverflow)
+		}
+		return z
+	case _Sub:
+		z := x - y
+		if (x^y)&(x^z) < 0 {
+			panic(ErrOverflow)
+		}
+		return z
+	case _Mod:
+		if y == 0 {
+			panic(ErrDivZero)
+		}
+		return x % y
+	case _Pow:
+		if y < 0 {
+			return math.Pow(float64(x), float64(y))
+		}
+		return powInt(x, y)
+	}
+	panic(errOperator(op))
+}
+
+// 整数乘法（检查溢出）。
+func mulInt(x, y int64) int64 {
+	if x == 0 || y == 0 {
+		return 0
+	}
+	z := x * y
+	if z/y != x || (x == -1 && y == math.MinInt64) || (y == -1 && x == math.MinInt64) {
+		panic(ErrOverflow)
+	}
+	return z
+}
+
+// 整数幂（检查溢出）。
+// y 非负。
+func powInt(x, y int64) int64 {
+	z := int64(1)
+
+	for y > 0 {
+		if y&1 == 1 {
+			z = mulInt(z, x)
+		}
+		if y >>= 1; y > 0 {
+			x = mulInt(x, x)
+		}
+	}
+	return z
+}
+
+// 大整数运算。
+func bigArith(op int, x, y *big.Int) any {
+	z := new(big.Int)
+
+	switch op {
+	case _Mul:
+		if x.BitLen()+y.BitLen() > MaxBits+1 {
+			panic(ErrOverflow)
+		}
+		z.Mul(x, y)
+	case _Div:
+		if y.Sign() == 0 {
+			panic(ErrDivZero)
+		}
+		z.Quo(x, y)
+	case _Mod:
+		if y.Sign() == 0 {
+			panic(ErrDivZero)
+		}
+		z.Rem(x, y)
+	case _Add:
+		z.Add(x, y)
+	case _Sub:
+		z.Sub(x, y)
+	case _Pow:
+		if y.Sign() < 0 {
+			return math.Pow(toFloat(x), toFloat(y))
+		}
+		// |x| > 1 时结果至少 (BitLen-1)*y+1 位
+		if x.CmpAbs(big.NewInt(1)) > 0 {
+			if !y.IsInt64() || y.Int64() >= MaxBits || int64(x.BitLen()-1)*y.Int64() >= MaxBits {
+				panic(ErrOverflow)
+			}
+		}
+		z.Exp(x, y, nil)
+	default:
+		panic(errOperator(op))
+	}
+	if z.BitLen() > MaxBits {
+		panic(ErrOverflow)
+	}
+	return z
+}
+
+// 移位运算。
+// 操作数皆为 Int，移位数不可为负。
+// 左移丢失有效位时抛出 ErrOverflow，右移为算术移位。
+func shift(op int, x, y any) int64 {
+	v, n := normal(x).(int64), normal(y).(int64)
+
+	if n < 0 {
+		panic(fmt.Sprintf(_T("移位数为负：%d"), n))
+	}
+	if op == _Rmov {
+		return v >> n
+	}
+	z := v << n
+	if n > 63 || z>>n != v {
+		panic(ErrOverflow)
+	}
+	return z
+}
+
+// 值比较。
+// 数值按类型提升后比较，字符串和字节序列按字节序比较，其它类型引发类型断言错误。
+// ok 为假表示不可排序（含 NaN 的浮点数比较）。
+func compare(x, y any) (n int, ok bool) {
+	switch a := x.(type) {
+	case string:
+		return strings.Compare(a, y.(string)), true
+	case []byte:
+		return bytes.Compare(a, y.([]byte)), true
+	}
+	x, y = normal(x), normal(y)
+
+	switch max(kindOf(x), kindOf(y)) {
+	case kindInt:
+		return cmp.Compare(x.(int64), y.(int64)), true
+	case kindBig:
+		return toBig(x).Cmp(toBig(y)), true
+	}
+	a, b := toFloat(x), toFloat(y)
+
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return 0, false
+	}
+	return cmp.Compare(a, b), true
+}
+
+// 值相等。
+// 数值按类型提升后比较，布尔值、字符串和字节序列需同类型。
+func equal(x, y any) bool {
+	if a, ok := x.(bool); ok {
+		return a == y.(bool)
+	}
+	n, ok := compare(x, y)
+	return ok && n == 0
+}
@@ -3,15 +3,18 @@
 
 // Package expr 表达式指令的实现。
 // 作为脚本系统的一个子指令，与上级指令处理有着较为紧密的关系，
-// 因此这里直接抛出异常而非返回错误（包编码规约）。
+// 因此计算时直接抛出异常而非返回错误（包编码规约），
+// 但编译（语法检查）返回类型化的错误，以便在执行前校验。
 // 而作为一个子包实现，是为了尽可能分离逻辑耦合，优化编码条理。
 package expr
 
 import (
+	"bytes"
 	"fmt"
+	"math/big"
+	"slices"
 
 	"github.com/cxio/suite/locale"
-	"github.com/cxio/suite/script/ibase"
 	"github.com/cxio/suite/script/icode"
 )
 
@@ -19,206 +22,267 @@ import (
 var _T = locale.GetText
 
 // 操作符指令码配置。
+// 四则运算为专用的符号指令，其它借用同名功能的普通指令码，
+// 它们在表达式内视为运算符，不作为指令执行。
 const (
-	_Mul = icode.Mul // *
-	_Div = icode.Div // /
-	_Add = icode.Add // +
-	_Sub = icode.Sub // -
+	_Mul    = icode.Mul    // *
+	_Div    = icode.Div    // /
+	_Add    = icode.Add    // +
+	_Sub    = icode.Sub    // -
+	_Mod    = icode.MOD    // %
+	_Pow    = icode.POW    // **
+	_Lmov   = icode.LMOV   // <<
+	_Rmov   = icode.RMOV   // >>
+	_Equal  = icode.EQUAL  // ==
+	_Nequal = icode.NEQUAL // !=
+	_Lt     = icode.LT     // <
+	_Lte    = icode.LTE    // <=
+	_Gt     = icode.GT     // >
+	_Gte    = icode.GTE    // >=
+	_Both   = icode.BOTH   // &&
+	_Either = icode.EITHER // ||
+	_Not    = icode.NOT    // !
 )
 
-// 表达式结束标志。
-const exprEnd = ibase.ExprEnd
+// 幂运算的优先级。
+// 高于一元操作符（-2 ** 2 为 -4），右结合。
+const precPow = 6
 
-// 表达式单元接口
-// 统一采用float64计算以保持精度。
-type _Expr interface {
-	Eval() float64
+// 二元操作符优先级。
+// 值越大结合越紧密，同级左结合（幂运算除外）。
+var __precedence = map[int]int{
+	_Either: 1,
+	_Both:   2,
+	_Equal:  3, _Nequal: 3, _Lt: 3, _Lte: 3, _Gt: 3, _Gte: 3,
+	_Add: 4, _Sub: 4,
+	_Mul: 5, _Div: 5, _Mod: 5, _Lmov: 5, _Rmov: 5,
+	_Pow: precPow,
 }
 
-// 单操作数操作
-// op: + - 码值
-type unary struct {
-	op int
-	x  _Expr
+// 是否为运算符指令。
+// 运算符只是表达式的语法成分，没有执行逻辑。
+func Operator(code int) bool {
+	_, ok := __precedence[code]
+	return ok || code == _Not
 }
 
-func (u unary) Eval() float64 {
-	switch u.op {
-	case _Add: // +
-		return +u.x.Eval()
-	case _Sub: // -
-		return -u.x.Eval()
-	}
-	panic(fmt.Sprintf(_T("不被支持的一元操作符: %q"), u.op))
-}
-
-// 双操作数四则运算
-// op: * / + - 码值
-type binary struct {
-	op   int
-	x, y _Expr
-}
-
-func (b binary) Eval() float64 {
-	switch b.op {
-	case _Mul:
-		return b.x.Eval() * b.y.Eval()
-	case _Div:
-		return b.x.Eval() / b.y.Eval()
-	case _Add:
-		return b.x.Eval() + b.y.Eval()
-	case _Sub:
-		return b.x.Eval() - b.y.Eval()
-	}
-	panic(fmt.Sprintf(_T("不被支持的二元操作符: %q"), b.op))
+// 不支持的操作符。
+func errOperator(op int) string {
+	return fmt.Sprintf(_T("不被支持的二元操作符: %q"), op)
 }
 
-// 储值操作。
-// 普通指令调用后的返回值存储。
-type value float64
-
-func (v value) Eval() float64 {
-	return float64(v)
+// 优先级权重。
+// 非二元操作符返回0。
+func precedence(op int) int {
+	return __precedence[op]
 }
 
 /*
- * 解析执行器
- * 用法：
- * 每一段小括号封装的表达式（或优先级分组）都需创建一个执行器并执行（.Run()）。
- * 表达式内的优先级分组返回的值自然参与计算。
- * 顶级根表达式的运算结果则返回到执行流（上级调用者）。
+ * 表达式计算
+ * 表达式先编译为语法树（见 Compile），然后可多次计算。
+ * 小括号的优先级分组为嵌套的表达式指令，编译为语法树的子树。
+ * 顶级根表达式的运算结果返回到执行流（上级调用者）。
  ******************************************************************************
  */
 
-// 表达式执行器。
-// call() 会步进执行每一个指令，返回指令码值和指令执行后的原始返回值。
-// 当表达式执行完后，再次调用 call() 返回 (-1, nil)。
+// 计算表达式。
+// run 执行叶节点的操作数指令，返回指令的原始返回值，
+// charge 计入字面值和分组指令的成本（操作数指令由 run 自行计入）。
+// 计算：
+// - 数值运算以运算链为单位，链内任一操作数为 Float 时，全部操作数转为 Float 计算（浮点模式），
+// 否则按本包的类型提升规则逐步进行，Int 溢出时抛出 ErrOverflow。
+// - 运算链即相连的 + - * / % ** 运算（含一元正负号），
+// 分组、比较、逻辑和移位运算的结果作为链的操作数，不受链的模式影响。
+// - 比较运算返回 Bool，数值按类型提升后比较，字符串和字节序列按字节序比较。
+// - 逻辑运算（&& || !）的操作数需为 Bool，&& 和 || 短路求值，被短路的指令不会执行。
 // 注记：
-// 如果表达式内调用的指令返回nil或空值，则这里的值存储为0。
+// 如果表达式内调用的指令返回nil或空值，则这里的值存储为 Int(0)。
 // 如果表达式内指令返回多于1个值，则抛出错误。
-type Calculor struct {
-	call func() (int, []any) // 指令调用器
-	n    int                 // 指令码
-	v    float64             // 指令返回值
+func (t *Tree) Eval(run func(*Inst) []any, charge func(int)) any {
+	e := evaluator{run, charge}
+	return e.eval(t.root)
 }
 
-// 创建一个计算器。
-// expr 为待执行的指令序列片段（小括号包围的部分）。
-// call 为上级提供的指令调用步进器。
-func Calculator(call func() (int, []any)) *Calculor {
-	return &Calculor{call: call}
+// 计算器。
+type evaluator struct {
+	run    func(*Inst) []any
+	charge func(int)
 }
 
-// 执行器运行。
-func (c *Calculor) Calc() float64 {
-	c.next()
-	e := parseExpr(c)
+// 计算节点。
+func (e *evaluator) eval(n *node) any {
+	if chained(n) {
+		var vs []any
+		// 操作数按代码顺序求值
+		operands(n, func(p **node) {
+			vs = append(vs, e.eval(*p))
+		})
+		return calc(n, vs)
+	}
+	switch n.kind {
+	case nodeConst:
+		e.charge(n.cost)
+		return clone(n.val)
+	case nodeLeaf:
+		return operand(e.run(&n.ins))
+	case nodeGroup:
+		e.charge(n.cost)
+		return e.eval(n.x)
+	case nodeUnary:
+		return unary(n.op, e.eval(n.x))
+	}
+	x := e.eval(n.x)
+	// 短路：右侧不求值
+	short := shortCircuit(n.op, x)
 
-	if c.code() != exprEnd {
-		panic(fmt.Sprintf(_T("未知语法错误: %q"), c.value()))
+	var y any
+	if !short {
+		y = e.eval(n.y)
 	}
-	return e.Eval()
+	return operate(n.op, x, y, short)
 }
 
-// 步进执行一个指令。
-// 如果步进已结束，置标指令码并返回false。
-func (c *Calculor) next() bool {
-	n, vs := c.call()
-
-	if n < 0 && vs == nil {
-		c.n = exprEnd
-		return false
+// 是否为运算链成员。
+// 即算术运算的二元节点和正负号节点。
+func chained(n *node) bool {
+	switch n.kind {
+	case nodeUnary:
+		return n.op == _Add || n.op == _Sub
+	case nodeBinary:
+		switch n.op {
+		case _Mul, _Div, _Add, _Sub, _Mod, _Pow:
+			return true
+		}
 	}
-	c.n, c.v = n, 0
+	return false
+}
 
-	if len(vs) > 1 {
-		panic(_T("表达式内指令的返回值太多"))
-	}
-	if len(vs) == 1 {
-		switch x := vs[0].(type) {
-		case float64:
-			c.v = x
-		case byte:
-			c.v = float64(x)
-		case rune:
-			c.v = float64(x)
-		case int64:
-			c.v = float64(x)
-		case float32:
-			c.v = float64(x)
+// 遍历运算链的操作数。
+// n 为链上节点，按代码顺序对每个操作数节点调用 f，f 可替换该节点。
+func operands(n *node, f func(**node)) {
+	for _, p := range []**node{&n.x, &n.y} {
+		switch {
+		case *p == nil:
+		case chained(*p):
+			operands(*p, f)
 		default:
-			panic(_T("表达式内指令的返回值类型无效"))
+			f(p)
 		}
 	}
-	return true
 }
 
-// 获取当前指令码。
-// 注：在 .next() 执行之后更新。
-func (c *Calculor) code() int {
-	return c.n
-}
+// 计算运算链。
+// vs 为链的操作数值（代码顺序），任一为 Float 时全部转为 Float 计算。
+func calc(n *node, vs []any) any {
+	flo := slices.ContainsFunc(vs, func(v any) bool {
+		_, ok := v.(float64)
+		return ok
+	})
+	var walk func(*node) any
 
-// 获取当前指令执行返回值。
-// 注：同上在 .next() 执行之后更新。
-func (c *Calculor) value() float64 {
-	return c.v
-}
+	walk = func(n *node) any {
+		if !chained(n) {
+			v := vs[0]
+			vs = vs[1:]
+			if flo {
+				v = toFloat(normal(v))
+			}
+			return v
+		}
+		x := walk(n.x)
 
-// 优先级权重。
-func precedence(op int) int {
-	switch op {
-	case _Mul, _Div:
-		return 2
-	case _Add, _Sub:
-		return 1
+		if n.kind == nodeUnary {
+			return unary(n.op, x)
+		}
+		return arith(n.op, x, walk(n.y))
 	}
-	return 0
+	return walk(n)
 }
 
-// 解析表达式。
-func parseExpr(a *Calculor) _Expr {
-	return parseBinary(a, 1)
+// 操作数取值。
+// vs 为指令的原始返回值，Byte、Rune 规范化为 Int。
+func operand(vs []any) any {
+	switch len(vs) {
+	case 0:
+		return int64(0)
+	case 1:
+	default:
+		panic(_T("表达式内指令的返回值太多"))
+	}
+	switch x := vs[0].(type) {
+	case float32:
+		return float64(x)
+	case byte, rune:
+		return normal(x)
+	case float64, int64, *big.Int, bool, string, []byte:
+		return x
+	}
+	panic(_T("表达式内指令的返回值类型无效"))
 }
 
-// 解析二元操作。
-// 注：修改自 gopl.io/ch7/eval/parse.go
-func parseBinary(a *Calculor, prec1 int) _Expr {
-	lhs := parseUnary(a)
-
-	for prec := precedence(a.code()); prec >= prec1; prec-- {
-		for precedence(a.code()) == prec {
-			op := a.code()
-			if !a.next() {
-				panic(_T("二元操作缺少跟随的操作数"))
-			}
-			rhs := parseBinary(a, prec+1)
-			lhs = binary{op, lhs, rhs}
-		}
+// 常量值副本。
+// 语法树为缓存共享，可变的值不能直接交给执行流。
+func clone(v any) any {
+	switch x := v.(type) {
+	case *big.Int:
+		return new(big.Int).Set(x)
+	case []byte:
+		return bytes.Clone(x)
 	}
-	return lhs
+	return v
 }
 
-// 解析一元操作。
-func parseUnary(a *Calculor) _Expr {
-	c := a.code()
+// 执行一元操作。
+// 一元操作符：+ - !
+func unary(op int, x any) any {
+	switch op {
+	case _Sub:
+		return Neg(x)
+	case _Not:
+		return !x.(bool)
+	}
+	kindOf(normal(x)) // 正号仅用于数值
+	return x
+}
 
-	if c == _Add || c == _Sub {
-		if !a.next() {
-			panic(_T("一元操作缺少跟随的操作数"))
-		}
-		return unary{c, parseUnary(a)}
+// 是否短路。
+// 左值已可确定 && 或 || 的结果时，右侧无需求值。
+func shortCircuit(op int, lhs any) bool {
+	switch op {
+	case _Both:
+		return !lhs.(bool)
+	case _Either:
+		return lhs.(bool)
 	}
-	return parsePrimary(a)
+	return false
 }
 
-// 解析主要操作。
-// 即执行非运算符的指令，可能为表达式内最后一个指令。
-func parsePrimary(a *Calculor) _Expr {
-	if a.code() != exprEnd {
-		v := a.value()
-		a.next()
-		return value(v)
+// 执行二元操作。
+// short 表示右侧已被短路（rhs 无效）。
+func operate(op int, x, y any, short bool) any {
+	switch op {
+	case _Both:
+		return !short && y.(bool)
+	case _Either:
+		return short || y.(bool)
+	case _Equal:
+		return equal(x, y)
+	case _Nequal:
+		return !equal(x, y)
+	case _Lt:
+		n, ok := compare(x, y)
+		return ok && n < 0
+	case _Lte:
+		n, ok := compare(x, y)
+		return ok && n <= 0
+	case _Gt:
+		n, ok := compare(x, y)
+		return ok && n > 0
+	case _Gte:
+		n, ok := compare(x, y)
+		return ok && n >= 0
+	case _Lmov, _Rmov:
+		return shift(op, x, y)
 	}
-	panic(_T("表达式已结束，不可继续执行"))
+	return arith(op, x, y)
 }
@@ -0,0 +1,313 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package expr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cxio/suite/script/icode"
+)
+
+//
+// 表达式编译
+// 将表达式的指令序列解析为语法树，可缓存后多次计算（如 EACH/MAP 循环内）。
+// 编译时检查语法，出错返回 *SyntaxError 而非抛出异常。
+//
+// 常量折叠：
+// 子树的叶节点全为字面值指令时，编译时即计算为常量，
+// 运算链（见 Eval）只整体折叠，以保持其浮点模式。
+// 计算出错（如除零）的子树保留原样，留待执行时报错。
+// 常量节点记录被折叠指令的成本，计算时照常计入。
+///////////////////////////////////////////////////////////////////////////////
+
+// 分组指令码。
+// 小括号封装的嵌套表达式。
+const _Group = icode.Expr
+
+var (
+	// 缺少操作数。
+	ErrOperand = errors.New(_T("缺少操作数"))
+
+	// 缺少二元运算符。
+	// 如两个操作数相邻，或一元运算符出现在操作数之后。
+	ErrOperator = errors.New(_T("缺少二元运算符"))
+)
+
+// 语法错误。
+// Offset 为出错指令相对于表达式代码起点的偏移，嵌套分组内的指令亦同，
+// 缺少末尾操作数时即为代码长度。
+type SyntaxError struct {
+	Offset int   // 指令偏移
+	Err    error // 错误原因
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf(_T("表达式偏移 %d 处%v"), e.Offset, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// 指令单元。
+// 由解码器从表达式代码中提取，Offset 由编译器设置。
+type Inst struct {
+	Code   int    // 指令码
+	Offset int    // 指令偏移（相对于表达式代码起点）
+	Size   int    // 指令占用总长
+	Cost   int    // 指令成本（仅字面值和分组指令需要）
+	Value  any    // 字面值
+	Lit    bool   // 是否为字面值指令
+	Sub    []byte // 分组指令的代码（位于指令末尾）
+	Ext    any    // 解码器的附加信息，编译器不使用
+}
+
+// 指令解码器。
+// 解析 code 起始处的指令，code 的格式已经过校验。
+// 字面值指令的值需为表达式可用的类型（数值、Bool、字符串或字节序列）。
+type Decoder func(code []byte) Inst
+
+// 节点类别。
+const (
+	nodeConst  = iota // 常量（字面值或折叠结果）
+	nodeLeaf          // 操作数指令，计算时执行
+	nodeGroup         // 分组
+	nodeUnary         // 一元操作
+	nodeBinary        // 二元操作
+)
+
+// 语法树节点。
+type node struct {
+	kind int
+	op   int   // 运算符
+	x, y *node // 操作数（一元和分组仅 x）
+	val  any   // 常量值
+	cost int   // 常量或分组的成本
+	ins  Inst  // 操作数指令
+}
+
+// 表达式语法树。
+// 编译后不再修改，可在多个执行流之间共享。
+type Tree struct {
+	root *node
+}
+
+// 编译表达式。
+// code 为表达式指令序列（不含外层的表达式指令本身），dec 为指令解码器。
+// 语法错误返回 *SyntaxError，可用 errors.Is 判断 ErrOperand 等原因。
+func Compile(code []byte, dec Decoder) (*Tree, error) {
+	n, err := compile(code, 0, dec)
+	if err != nil {
+		return nil, err
+	}
+	return &Tree{root: fold(n)}, nil
+}
+
+// 是否为常量。
+// 整个表达式被折叠时返回其值。
+func (t *Tree) Const() (any, bool) {
+	if t.root.kind != nodeConst {
+		return nil, false
+	}
+	return clone(t.root.val), true
+}
+
+// 编译指令序列。
+// base 为序列在顶层表达式代码中的偏移。
+func compile(code []byte, base int, dec Decoder) (*node, error) {
+	p := parser{dec: dec, end: base + len(code)}
+
+	for i := 0; i < len(code); {
+		ins := dec(code[i:])
+		ins.Offset = base + i
+		p.list = append(p.list, ins)
+		i += ins.Size
+	}
+	n, err := p.binary(1)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.list) {
+		return nil, p.fail(ErrOperator)
+	}
+	return n, nil
+}
+
+// 语法解析器。
+// 递归下降，优先级规则同计算说明（见 __precedence）。
+// 注：修改自 gopl.io/ch7/eval/parse.go
+type parser struct {
+	dec  Decoder
+	list []Inst // 指令序列
+	pos  int    // 当前指令下标
+	end  int    // 序列末尾的偏移
+}
+
+// 当前指令码。
+// 序列已结束时返回-1。
+func (p *parser) code() int {
+	if p.pos < len(p.list) {
+		return p.list[p.pos].Code
+	}
+	return -1
+}
+
+// 构造当前位置的语法错误。
+func (p *parser) fail(err error) error {
+	off := p.end
+	if p.pos < len(p.list) {
+		off = p.list[p.pos].Offset
+	}
+	return &SyntaxError{Offset: off, Err: err}
+}
+
+// 解析二元操作。
+// prec1 为起始优先级。
+func (p *parser) binary(prec1 int) (*node, error) {
+	lhs, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for prec := precedence(p.code()); prec >= prec1; prec-- {
+		for precedence(p.code()) == prec {
+			op := p.code()
+			p.pos++
+
+			rhs, err := p.binary(prec + 1)
+			if err != nil {
+				return nil, err
+			}
+			lhs = &node{kind: nodeBinary, op: op, x: lhs, y: rhs}
+		}
+	}
+	return lhs, nil
+}
+
+// 解析一元操作。
+// 一元操作符：+ - !
+func (p *parser) unary() (*node, error) {
+	op := p.code()
+
+	if op == _Add || op == _Sub || op == _Not {
+		p.pos++
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeUnary, op: op, x: x}, nil
+	}
+	return p.power()
+}
+
+// 解析幂运算。
+// 右结合，右操作数可带一元操作符（如 2 ** -1）。
+func (p *parser) power() (*node, error) {
+	x, err := p.primary()
+	if err != nil || p.code() != _Pow {
+		return x, err
+	}
+	p.pos++
+
+	y, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return &node{kind: nodeBinary, op: _Pow, x: x, y: y}, nil
+}
+
+// 解析主要操作。
+// 即非运算符的指令，分组递归编译为子树。
+func (p *parser) primary() (*node, error) {
+	if p.pos >= len(p.list) || Operator(p.code()) {
+		return nil, p.fail(ErrOperand)
+	}
+	ins := p.list[p.pos]
+	p.pos++
+
+	switch {
+	case ins.Lit:
+		return &node{kind: nodeConst, val: operand([]any{ins.Value}), cost: ins.Cost}, nil
+	case ins.Code == _Group:
+		x, err := compile(ins.Sub, ins.Offset+ins.Size-len(ins.Sub), p.dec)
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeGroup, x: x, cost: ins.Cost}, nil
+	}
+	return &node{kind: nodeLeaf, ins: ins}, nil
+}
+
+// 常量折叠。
+// 自下而上处理，操作数皆为常量，或左操作数为常量且已短路时，计算为常量节点。
+// 运算链整体折叠（浮点模式取决于链上全部操作数），部分常量的链不折叠。
+// 计算出错时保留原节点。
+func fold(n *node) *node {
+	switch {
+	case n.kind == nodeConst || n.kind == nodeLeaf:
+		return n
+	case chained(n):
+		return foldChain(n)
+	}
+	n.x = fold(n.x)
+	if n.y != nil {
+		n.y = fold(n.y)
+	}
+	return foldNode(n)
+}
+
+// 折叠运算链。
+func foldChain(n *node) (v *node) {
+	var vs []any
+	cost, ok := 0, true
+
+	operands(n, func(p **node) {
+		x := fold(*p)
+		*p = x
+		if x.kind != nodeConst {
+			ok = false
+			return
+		}
+		vs = append(vs, x.val)
+		cost += x.cost
+	})
+	if !ok {
+		return n
+	}
+	defer func() {
+		if recover() != nil {
+			v = n
+		}
+	}()
+	return &node{kind: nodeConst, val: calc(n, vs), cost: cost}
+}
+
+// 折叠单个节点。
+// 子节点已折叠，出错时返回原节点。
+func foldNode(n *node) (v *node) {
+	if n.x.kind != nodeConst {
+		return n
+	}
+	defer func() {
+		if recover() != nil {
+			v = n
+		}
+	}()
+	x := n.x.val
+
+	switch n.kind {
+	case nodeGroup:
+		return &node{kind: nodeConst, val: x, cost: n.cost + n.x.cost}
+	case nodeUnary:
+		return &node{kind: nodeConst, val: unary(n.op, x), cost: n.x.cost}
+	}
+	// 被短路的右侧不执行，也不计成本
+	if shortCircuit(n.op, x) {
+		return &node{kind: nodeConst, val: operate(n.op, x, nil, true), cost: n.x.cost}
+	}
+	if n.y.kind != nodeConst {
+		return n
+	}
+	return &node{kind: nodeConst, val: operate(n.op, x, n.y.val, false), cost: n.x.cost + n.y.cost}
+}
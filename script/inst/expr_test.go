@@ -0,0 +1,220 @@
+package inst_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cxio/suite/script/asm"
+	"github.com/cxio/suite/script/ibase"
+	"github.com/cxio/suite/script/inst"
+	"github.com/cxio/suite/script/inst/expr"
+	"github.com/cxio/suite/script/instor"
+)
+
+func TestExprOperators(t *testing.T) {
+	tests := []struct {
+		src  string
+		want any
+	}{
+		{"(7 % 3 + 1)", int64(2)},
+		{"(-7 % 3)", int64(-1)},
+		{"(7.5 % 2)", 1.5},
+		{"(2 ** 3 ** 2)", int64(512)},
+		{"(- 2 ** 2)", int64(-4)},
+		{"(-2 ** 2)", int64(4)}, // 负数字面量
+		{"(2 ** -1)", 0.5},
+		{"(1 << 4 >> 2)", int64(4)},
+		{"(1 + 2 << 3)", int64(17)},
+		{"(1 + 2 * 3 == 7)", true},
+		{"(2 < 3 && 3 <= 3)", true},
+		{"(1 > 2 || 2 >= 3)", false},
+		{"(1 < 1.5)", true},
+		{"(9223372036854775808 > 1)", true},
+		{"(!(1 == 1) || 1 != 2)", true},
+		{"(true || false && false)", true},
+		{`("abc" < "abd")`, true},
+		{"(((1 + 2)) * (3 - 1))", int64(6)},
+	}
+	for _, tt := range tests {
+		r, err := runEnv(t, tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(r.Stack) != 1 || r.Stack[0] != tt.want {
+			t.Errorf("%s = %#v, want %#v", tt.src, r.Stack, tt.want)
+		}
+	}
+}
+
+// 短路的右侧指令不执行。
+func TestExprShortCircuit(t *testing.T) {
+	for _, src := range []string{
+		"(false && (1 / 0) == 1)",
+		"(true || FAIL)",
+		"(1 > 2 && 1 < 2 && (1 % 0) == 0)",
+	} {
+		if _, err := runEnv(t, src); err != nil {
+			t.Errorf("%s: %v", src, err)
+		}
+	}
+	if _, err := runEnv(t, "(true && (1 / 0) == 1)"); !errors.Is(err, expr.ErrDivZero) {
+		t.Errorf("no short circuit: %v", err)
+	}
+	if _, err := runEnv(t, "(false || (1 % 0) == 0)"); !errors.Is(err, expr.ErrDivZero) {
+		t.Errorf("right side not run: %v", err)
+	}
+}
+
+func TestExprErrors(t *testing.T) {
+	for _, src := range []string{"(1 && true)", "(1 <<)", "(1 2)", "(1 << -1)", "(1 << 63)"} {
+		if _, err := inst.Execute(context.Background(), rawActuator(t, src)); err == nil {
+			t.Errorf("%s: no error", src)
+		}
+	}
+}
+
+// 汇编脚本，但略过表达式的语法检查。
+// 用于构造含无效表达式的代码。
+func assembleRaw(t *testing.T, src string) []byte {
+	t.Helper()
+
+	check := instor.ExprCheck
+	instor.ExprCheck = nil
+	defer func() { instor.ExprCheck = check }()
+
+	code, err := asm.Assemble([]byte(src))
+	if err != nil {
+		t.Fatalf("Assemble(%q): %v", src, err)
+	}
+	return code
+}
+
+// 创建执行器，脚本可含无效表达式。
+func rawActuator(t *testing.T, src string) *inst.Actuator {
+	t.Helper()
+	return ibase.NewActuator([]byte("test"), assembleRaw(t, src), nil, ibase.NewEnvs(nil, nil, 0), 1)
+}
+
+// 表达式代码（去除外层的表达式指令）。
+func exprCode(t *testing.T, src string) []byte {
+	t.Helper()
+	return assembleRaw(t, src)[2:]
+}
+
+// 浮点模式：运算链内任一操作数为 Float 时，全部操作数按 Float 计算。
+func TestExprFloatMode(t *testing.T) {
+	tests := []struct {
+		src  string
+		want any
+	}{
+		{"(7 / 2 + 0.5)", 4.0},
+		{"(0.5 + 7 / 2)", 4.0},
+		{"(-7 / 2 * 1.0)", -3.5},
+		{"((7 / 2) + 0.5)", 3.5},  // 分组自成一链
+		{"(7 / 2 == 3.5)", false}, // 比较的两侧各自成链
+		{"(7.0 / 2 == 3.5)", true},
+		{"(1 << 2 + 0.5)", 4.5}, // 移位结果为链的操作数
+		{"(7 / 2 + 1)", int64(4)},
+		{"0.5 (7 / 2 + POP)", 4.0}, // 执行时确定
+	}
+	for _, tt := range tests {
+		r, err := runEnv(t, tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if len(r.Stack) != 1 || r.Stack[0] != tt.want {
+			t.Errorf("%s = %#v, want %#v", tt.src, r.Stack, tt.want)
+		}
+	}
+}
+
+func TestExprCompile(t *testing.T) {
+	tests := []struct {
+		src  string
+		want any // nil 表示不可折叠
+	}{
+		{"(1 + 2 * 3)", int64(7)},
+		{"((1 + 2) * -(3))", int64(-9)},
+		{`(2 ** 3 > 7 && "a" < "b")`, true},
+		{"(false && ENV{Height} > 1)", false},
+		{"(ENV{Height} > 1 && false)", nil},
+		{"(1 / 0)", nil},
+		{"(7 / 2 + 0.5)", 4.0},
+		{"(7 / 2 + 0.5 + ENV{Height})", nil},
+	}
+	for _, tt := range tests {
+		tr, err := inst.ExprCompile(exprCode(t, tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		v, ok := tr.Const()
+		if ok != (tt.want != nil) || v != tt.want {
+			t.Errorf("%s: Const() = %v, %v, want %v", tt.src, v, ok, tt.want)
+		}
+	}
+}
+
+// 缓存的语法树不引用调用者的代码缓冲区。
+func TestExprCacheAlias(t *testing.T) {
+	buf := exprCode(t, `(DATA{0x616263})`)
+	if _, err := inst.ExprCompile(buf); err != nil {
+		t.Fatal(err)
+	}
+	buf[2] = 'X' // 调用者复用缓冲区
+
+	tr, err := inst.ExprCompile(exprCode(t, `(DATA{0x616263})`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := tr.Const(); fmt.Sprintf("%s", v) != "abc" {
+		t.Errorf("Const() = %s, want abc", v)
+	}
+}
+
+func TestExprSyntax(t *testing.T) {
+	tests := []struct {
+		src    string
+		err    error
+		offset int
+	}{
+		{"(1 +)", expr.ErrOperand, 3},
+		{"(1 2)", expr.ErrOperator, 2},
+		{"(1 !)", expr.ErrOperator, 2},
+		{"(* 2)", expr.ErrOperand, 0},
+		{"((1 *) + 2)", expr.ErrOperand, 5},
+	}
+	for _, tt := range tests {
+		_, err := inst.ExprCompile(exprCode(t, tt.src))
+
+		var se *expr.SyntaxError
+		if !errors.As(err, &se) || !errors.Is(err, tt.err) || se.Offset != tt.offset {
+			t.Errorf("%s: got %v, want %v at %d", tt.src, err, tt.err, tt.offset)
+		}
+	}
+}
+
+// 未执行分支内的语法错误也在执行前报告。
+func TestExprValidate(t *testing.T) {
+	r, err := inst.Execute(context.Background(), rawActuator(t, "false IF{ (1 2) }"))
+
+	var e *inst.ExecError
+	if !errors.As(err, &e) || e.Kind != inst.KindInvalid || r.Pos.Offset != 7 {
+		t.Errorf("got %v at %d", err, r.Pos.Offset)
+	}
+}
+
+// 循环内重复计算同一语法树。
+func TestExprLoop(t *testing.T) {
+	r, err := inst.Execute(context.Background(), actuator(t, "0 1 RANGE(5) @ POP MAP{ (${Value} * 2 + 1) RETURN }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(r.Stack) != "[[1 3 5 7 9]]" {
+		t.Errorf("stack: %v", r.Stack)
+	}
+}
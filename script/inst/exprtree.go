@@ -0,0 +1,116 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package inst
+
+import (
+	"fmt"
+
+	"github.com/cxio/suite/script/icode"
+	"github.com/cxio/suite/script/inst/expr"
+	"github.com/cxio/suite/script/instor"
+)
+
+//
+// 表达式编译缓存
+// 表达式指令的代码编译为语法树（expr.Tree）后缓存，以代码内容的哈希为键，
+// 同一表达式再次执行时（如 EACH/MAP 循环内）无需重复解析。
+// 编译出错的结果同样缓存。
+// 注：
+// 脚本校验（instor.Validate）时即编译顶层表达式，语法错误在执行前报告。
+///////////////////////////////////////////////////////////////////////////////
+
+// 字面值指令集。
+// 编译时直接取值，可参与常量折叠。
+var __exprLits = map[int]bool{
+	icode.TRUE:    true,
+	icode.FALSE:   true,
+	icode.Uint8n:  true,
+	icode.Uint8:   true,
+	icode.Uint63n: true,
+	icode.Uint63:  true,
+	icode.Byte:    true,
+	icode.Rune:    true,
+	icode.Float32: true,
+	icode.Float64: true,
+	icode.BigInt:  true,
+	icode.DATA8:   true,
+	icode.DATA16:  true,
+	icode.TEXT8:   true,
+	icode.TEXT16:  true,
+}
+
+// 编译结果。
+type exprEntry struct {
+	tree *expr.Tree
+	err  error
+}
+
+// 语法树缓存。
+var exprCache = newCodeCache[exprEntry]()
+
+// 编译表达式。
+// code 为表达式指令的关联数据，即小括号内的指令序列，格式需已通过校验。
+// 结果被缓存，语法错误返回 *expr.SyntaxError。
+func ExprCompile(code []byte) (*expr.Tree, error) {
+	e := exprCache.get(code, func(code []byte) exprEntry {
+		t, err := expr.Compile(code, exprDecode)
+		return exprEntry{t, err}
+	})
+	return e.tree, e.err
+}
+
+// 表达式指令解码。
+// 字面值和分组指令记录成本，其它指令附带预解码信息，在执行时计入成本。
+func exprDecode(code []byte) expr.Inst {
+	d := decode(code)
+	if d.fail != nil {
+		panic(d.fail)
+	}
+	c, ins := d.ins.Code, d.ins
+	x := expr.Inst{Code: c, Size: ins.Size, Ext: d}
+
+	switch {
+	case __exprLits[c]:
+		x.Lit, x.Value = true, d.data(code, 0)
+		x.Cost = instCost(c, ins, nil)
+		// 单指令无关联数据
+		if c == icode.TRUE || c == icode.FALSE {
+			x.Value = c == icode.TRUE
+		}
+	case c == icode.Expr:
+		x.Sub = d.data(code, 0).([]byte)
+		x.Cost = instCost(c, ins, nil)
+	}
+	return x
+}
+
+// 执行表达式的操作数指令。
+// a 为表达式的执行器，脚本即顶层表达式的代码。
+func exprRun(a *Actuator, x *expr.Inst) []any {
+	a.Script.Reset()
+	a.Script.Next(x.Offset)
+
+	return instRun(a, x.Ext.(*decoded))
+}
+
+// 表达式语法检查。
+// 供脚本校验使用，出错时返回错误指令在表达式内的偏移。
+// 解码中的异常（如未注册的扩展指令）视为表达式不完整。
+func exprCheck(code []byte) (off int, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			off, err = len(code), fmt.Errorf("%v", v)
+		}
+	}()
+	_, err = ExprCompile(code)
+
+	if se, ok := err.(*expr.SyntaxError); ok {
+		return se.Offset, se.Err
+	}
+	return 0, err
+}
+
+func init() {
+	instor.ExprCheck = exprCheck
+}
@@ -6,7 +6,6 @@ package inst
 
 import (
 	"bytes"
-	crand "crypto/rand"
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base32"
@@ -14,10 +13,12 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"maps"
 	"math"
 	"math/big"
-	"math/rand"
+	"math/rand/v2"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -134,6 +135,8 @@ type Itemer = instor.Itemer
 
 // 字典类型。
 // 注：与切片类型一起被归类为集合。
+// 迭代（EACH, MAP, FILTER, KEYVAL）按键名的字节序升序进行，
+// 因此结果与运行次数和节点无关。
 type Dict map[string]any
 
 // 退出类型：
@@ -285,7 +288,8 @@ func _DATE(a *Actuator, _ []any, data any, _ ...any) []any {
 // 返回：一个实例指针。
 func _BigInt(a *Actuator, _ []any, data any, _ ...any) []any {
 	a.Revert()
-	return []any{data.(*BigInt)}
+	// 信息包为缓存共享，返回副本
+	return []any{new(BigInt).Set(data.(*BigInt))}
 }
 
 // 指令：DATA{}(1-2) 短字节序列
@@ -317,7 +321,8 @@ func _RegExp(a *Actuator, _ []any, data any, _ ...any) []any {
 // 返回：*Script
 func _CODE(a *Actuator, _ []any, data any, _ ...any) []any {
 	a.Revert()
-	return []any{data.(*Script)}
+	// 同上，返回副本
+	return []any{data.(*Script).New()}
 }
 
 /*
@@ -916,8 +921,9 @@ func _GOTO(a *Actuator, aux []any, _ any, vs ...any) []any {
 	h := aux[0].(int)
 	n := aux[1].(int)
 	i := aux[2].(int)
-	code := xpool.Get(h, n, i)
-	a2 := a.ScriptNew(cbase.KeyID(h, n, i), code)
+	id := cbase.KeyID(h, n, i)
+	code := fetchScript(h, n, i)
+	a2 := a.ScriptNew(id, code)
 
 	if len(vs) > 0 {
 		// 新数据栈初始内容
@@ -942,8 +948,9 @@ func _JUMP(a *Actuator, aux []any, _ any, vs ...any) []any {
 	h := aux[0].(int)
 	n := aux[1].(int)
 	i := aux[2].(int)
-	code := xpool.Get(h, n, i)
-	a2 := a.EmbedNew(cbase.KeyID(h, n, i), code)
+	id := cbase.KeyID(h, n, i)
+	code := fetchScript(h, n, i)
+	a2 := a.EmbedNew(id, code)
 
 	a2.JumpIn()
 	runEmbed(a2)
@@ -951,6 +958,24 @@ func _JUMP(a *Actuator, aux []any, _ any, vs ...any) []any {
 	return nil
 }
 
+// 获取外部脚本（GOTO/JUMP）。
+// 脚本需通过格式校验，校验结果按代码内容缓存。
+// 获取或校验失败时以外部查询错误中断。
+func fetchScript(h, n, i int) []byte {
+	code, err := xpool.Get(h, n, i)
+
+	if err == nil {
+		err = validated.get(code, instor.Validate)
+	}
+	if err != nil {
+		panic(&ibase.LookupError{Target: xpool.FileName(h, n, i), Err: err})
+	}
+	return code
+}
+
+// 外部脚本的校验结果缓存。
+var validated = newCodeCache[error]()
+
 // 指令：EXIT 结束脚本
 // 实参：不定数量。
 // 返回：即实参，多个实参会打包为一个切片。
@@ -1592,19 +1617,30 @@ func _DICT(a *Actuator, _ []any, _ any, vs ...any) []any {
 // 指令：()(1) 表达式封装&优先级分组
 // 附参：1 byte，表达式长度。
 // 实参：无。
-// 返回：Float 类型单值。
+// 返回：数值或 Bool 类型单值。
 // 注：
-// 仅支持基本算术的四则运算（乘除加减）。
+// 支持四则运算、求模、幂、移位、比较和逻辑运算，优先级和短路求值详见 expr.Tree.Eval。
+// 整数运算保持类型（溢出失败），与浮点数混合时按浮点数计算。
+// 表达式编译为语法树后缓存（见 ExprCompile），嵌套的分组为子树，不单独执行。
 func _Expr(a *Actuator, _ []any, data any, _ ...any) []any {
 	a.Revert()
+	code := data.([]byte)
 
-	a2 := a.ExprNew(data.([]byte))
+	t, err := ExprCompile(code)
+	if err != nil {
+		panic(err)
+	}
+	a2 := a.ExprNew(code)
 	a2.ExprIn()
 
-	f := func() (int, []any) {
-		return exprNext(a2)
+	if a2.Traced() {
+		a2.TraceEnter()
+		defer a2.TraceLeave()
 	}
-	v := expr.Calculator(f).Calc()
+	f := func(x *expr.Inst) []any {
+		return exprRun(a2, x)
+	}
+	v := t.Eval(f, a.Charge)
 	a2.ExprOut()
 
 	return []any{v}
@@ -1624,23 +1660,27 @@ func _Expr(a *Actuator, _ []any, data any, _ ...any) []any {
 
 // 指令：乘
 // 实参：双实参，任意数值。
-// 返回：Float，单值
+// 返回：Int|BigInt|Float，单值
+// 注：
+// 结果类型按 expr 包的类型提升规则，Int 溢出时失败。
 func _MUL(a *Actuator, _ []any, _ any, vs ...any) []any {
 	a.Revert()
-	return []any{number(vs[0]) * number(vs[1])}
+	return []any{expr.Mul(vs[0], vs[1])}
 }
 
 // 指令：除
 // 实参：双实参，任意数值。
-// 返回：Float，单值
+// 返回：Int|BigInt|Float，单值
+// 注：
+// 整数相除向零截断，除数为零时失败。
 func _DIV(a *Actuator, _ []any, _ any, vs ...any) []any {
 	a.Revert()
-	return []any{number(vs[0]) / number(vs[1])}
+	return []any{expr.Div(vs[0], vs[1])}
 }
 
 // 指令：加&连接
 // 实参：双实参。任意数值、字符串、字节序列、字典类型。
-// 返回：同类型或Float单值
+// 返回：同类型或数值单值（同 MUL）
 // 注：
 // 支持数值加、字符串和字节序列连接，以及字典的合并。
 func _ADD(a *Actuator, _ []any, _ any, vs ...any) []any {
@@ -1654,38 +1694,35 @@ func _ADD(a *Actuator, _ []any, _ any, vs ...any) []any {
 	case Dict:
 		return []any{dictMerge(x, vs[1].(Dict))}
 	}
-	return []any{number(vs[0]) + number(vs[1])}
+	return []any{expr.Add(vs[0], vs[1])}
 }
 
 // 指令：减
 // 实参：双实参，任意数值。
-// 返回：Float，单值
+// 返回：Int|BigInt|Float，单值（同 MUL）
 func _SUB(a *Actuator, _ []any, _ any, vs ...any) []any {
 	a.Revert()
-	return []any{number(vs[0]) - number(vs[1])}
+	return []any{expr.Sub(vs[0], vs[1])}
 }
 
 // 指令：幂
 // 实参：双实参，任意数值。
-// 返回：Float，单值
+// 返回：Int|BigInt|Float，单值
+// 注：
+// 整数的指数为负时结果为 Float，否则同 MUL。
 func _POW(a *Actuator, _ []any, _ any, vs ...any) []any {
 	a.Revert()
-	return []any{math.Pow(number(vs[0]), number(vs[1]))}
+	return []any{expr.Pow(vs[0], vs[1])}
 }
 
 // 指令：模
-// 实参：双实参，Int|Float 类型。
-// 返回：Int|Float 单值
+// 实参：双实参，任意数值。
+// 返回：Int|BigInt|Float 单值
+// 注：
+// 结果与被除数同号，整数除数为零时失败。
 func _MOD(a *Actuator, _ []any, _ any, vs ...any) []any {
 	a.Revert()
-
-	if i, ok := vs[0].(Int); ok {
-		return []any{i % vs[1].(Int)}
-	}
-	if f, ok := vs[0].(Float); ok {
-		return []any{math.Mod(f, vs[1].(Float))}
-	}
-	panic(neverToHere)
+	return []any{expr.Mod(vs[0], vs[1])}
 }
 
 // 指令：左移位（<<）
@@ -1752,18 +1789,13 @@ func _XOR(a *Actuator, _ []any, _ any, vs ...any) []any {
 }
 
 // 指令：取负（-v）
-// 实参：单实参，Int 或 Float 类型。
-// 返回：同类型单值
+// 实参：单实参，任意数值。
+// 返回：Int|BigInt|Float，单值
+// 注：
+// 同 expr 包的取负，Int 最小值取负溢出时失败。
 func _NEG(a *Actuator, _ []any, _ any, vs ...any) []any {
 	a.Revert()
-
-	switch x := vs[0].(type) {
-	case Int:
-		return []any{-x}
-	case Float:
-		return []any{-x}
-	}
-	panic(neverToHere)
+	return []any{expr.Neg(vs[0])}
 }
 
 // 指令：取反（!v）
@@ -2388,17 +2420,17 @@ func _SRAND(a *Actuator, _ []any, _ any, vs ...any) []any {
 
 	switch x := vs[0].(type) {
 	case Bytes:
-		return []any{randSlice(x)}
+		return []any{randSlice(a.Rand(), x)}
 	case Runes:
-		return []any{randSlice(x)}
+		return []any{randSlice(a.Rand(), x)}
 	case []any:
-		return []any{randSlice(x)}
+		return []any{randSlice(a.Rand(), x)}
 	case []Int:
-		return []any{randSlice(x)}
+		return []any{randSlice(a.Rand(), x)}
 	case []Float:
-		return []any{randSlice(x)}
+		return []any{randSlice(a.Rand(), x)}
 	case []String:
-		return []any{randSlice(x)}
+		return []any{randSlice(a.Rand(), x)}
 	}
 	panic(neverToHere)
 }
@@ -2415,14 +2447,14 @@ func _RANDOM(a *Actuator, _ []any, _ any, vs ...any) []any {
 	if len(vs) > 0 {
 		switch max := vs[0].(type) {
 		case Int:
-			return []any{randInt(max)}
+			return []any{randInt(a.Rand(), max)}
 		case *BigInt:
-			return []any{randBigInt(max)}
+			return []any{randBigInt(a.Rand(), max)}
 		default:
 			panic(neverToHere)
 		}
 	}
-	return []any{randInt(math.MaxInt64)}
+	return []any{randInt(a.Rand(), math.MaxInt64)}
 }
 
 // 指令：QRANDOM 获取一个随机数（快速）
@@ -2431,15 +2463,12 @@ func _RANDOM(a *Actuator, _ []any, _ any, vs ...any) []any {
 // 返回：一个随机正整数。
 func _QRANDOM(a *Actuator, _ []any, _ any, vs ...any) []any {
 	a.Revert()
-	// 种子：
-	// 辅以当前指令偏移值增加随机性。
-	t := time.Now().UnixMicro() * int64(a.Script.Offset()+1)
-	r := rand.New(rand.NewSource(t))
+	r := a.Rand()
 
 	if len(vs) == 0 {
-		return []any{r.Int63()}
+		return []any{r.Int64()}
 	}
-	return []any{r.Int63n(vs[0].(Int))}
+	return []any{r.Int64N(vs[0].(Int))}
 }
 
 // 指令：CMPFLO(1) 浮点数比较
@@ -2495,7 +2524,7 @@ func _RANGE(a *Actuator, aux []any, _ any, vs ...any) []any {
 // 返回：目标属性值（Int）或一个Time实例。
 func _SYS_TIME(a *Actuator, aux []any, _ any, _ ...any) []any {
 	a.Revert()
-	t := time.Now()
+	t := a.Now()
 
 	switch aux[0].(int) {
 	case instor.TimeDefault:
@@ -2884,37 +2913,55 @@ func privInst(code []byte) (Wrapper, int, *Insted) {
 }
 
 // 当前指令调用。
-// 会自动递进到下一个指令位置。
+// 调用前记录指令位置并计入成本，会自动递进到下一个指令位置。
 func instCall(a *Actuator) []any {
-	s := a.Script
-	f, n, ins := instGet(s.Bytes(), s.Code())
+	return instRun(a, decode(a.Script.Bytes()))
+}
+
+// 调用预解码的指令。
+// d 为当前位置的指令，说明同上。
+func instRun(a *Actuator, d *decoded) []any {
+	a.Mark()
+	s := &a.Script
+	to := a.BackTo
+
+	if d.fail != nil {
+		panic(d.fail)
+	}
+	ins := d.ins
+	data := d.data(s.Source(), s.Offset())
 
 	// 先步进，避免合理的panic原地踏步。
 	s.Next(ins.Size)
-	val := f(a, ins.Args, ins.Data, a.Arguments(n)...)
+	vs := a.Arguments(d.argn)
 
-	return val
-}
+	a.Charge(instCost(ins.Code, ins, vs))
+	val := d.call(a, ins.Args, data, vs...)
 
-// 表达式步进器。
-// 返回当前指令码和该指令调用后的原始返回值。
-// 如果抵达脚本末尾，返回 (-1, nil)
-// 注：
-// 用于构造表达式执行器。
-func exprNext(a *Actuator) (int, []any) {
-	if a.Script.End() {
-		return ibase.ExprEnd, nil
-	}
-	return a.Script.Code(), instCall(a)
+	a.TraceAfter(to, vs, val)
+	return val
 }
 
 // 代码执行（通用）。
 // 也用于无需捕获异常的子块代码，如：IF, ELSE, CASE 等，让异常正常向上传递。
+// 代码段预解码后缓存（见 programOf），仅首次执行时解析。
 // a 为脚本执行器。
 func codeRun(a *Actuator) {
-	for !a.Script.End() {
+	if a.Traced() {
+		a.TraceEnter()
+		defer a.TraceLeave()
+	}
+	p := programOf(a.Script.Source())
+	i, ok := p.index(a.Script.Offset())
+
+	for ; !a.Script.End(); i++ {
 		x := a.BackTo
-		a.ReturnPut(x, instCall(a))
+		// 非指令起点（不应发生），逐条解码
+		if !ok {
+			a.ReturnPut(x, instCall(a))
+			continue
+		}
+		a.ReturnPut(x, instRun(a, p.list[i]))
 	}
 }
 
@@ -2995,6 +3042,8 @@ func runEmbed(a *Actuator) {
 
 // 运行顶层代码。
 // 返回值：EXIT 的返回值。
+// 注：
+// 执行中的错误以 panic 向外传递，外部调用宜使用 Execute。
 func ScriptRun(a *Actuator) (x any) {
 	defer func() {
 		switch v := recover().(type) {
@@ -3052,7 +3101,8 @@ func mapDict(a *Actuator, data Dict, code []byte) []any {
 	var buf []any
 	size := len(data)
 
-	for k, v := range data {
+	for _, k := range dictKeys(data) {
+		v := data[k]
 		// 每次一个小新环境
 		a2 := a.BlockNew(code)
 		a2.LoopSet(k, v, data, size)
@@ -3091,7 +3141,8 @@ func filterDict(a *Actuator, data Dict, code []byte) Dict {
 	var dic = make(Dict)
 	size := len(data)
 
-	for k, v := range data {
+	for _, k := range dictKeys(data) {
+		v := data[k]
 		// 每次一个小新环境
 		a2 := a.BlockNew(code)
 		a2.LoopSet(k, v, data, size)
@@ -3283,7 +3334,8 @@ func dictEach(a *Actuator, data Dict, code []byte) {
 	orig := a.Jumps()
 	_max := orig
 
-	for k, v := range data {
+	for _, k := range dictKeys(data) {
+		v := data[k]
 		// 每次一个小新环境
 		a2 := a.BlockNew(code)
 
@@ -3316,7 +3368,7 @@ func convBytesToInt(x []byte) (int64, error) {
 	case 8:
 		return int64(binary.BigEndian.Uint64(x)), nil
 	}
-	return 0, fmt.Errorf(bytesLenFail)
+	return 0, errors.New(bytesLenFail)
 }
 
 // 转换到字节类型。
@@ -3356,22 +3408,6 @@ func convToAnys(data any) []any {
 	panic(neverToHere)
 }
 
-// 获取一个数值。
-// 转为 float64 以便于统一处理。
-func number(v any) Float {
-	switch x := v.(type) {
-	case Float:
-		return x
-	case Int:
-		return Float(x)
-	case Byte:
-		return Float(x)
-	case Rune:
-		return Float(x)
-	}
-	panic(neverToHere)
-}
-
 // 字节序列连接。
 func bytesGlue(b1, b2 Bytes) Bytes {
 	var buf bytes.Buffer
@@ -3491,18 +3527,24 @@ func newCopy[T any](s []T, ext int) []T {
 }
 
 // 获取字典的键值集。
-// 返回的键/值集成员按顺序一一对应。
+// 返回的键/值集成员按顺序一一对应，键名升序。
 func keyVals(d Dict) ([]string, []any) {
 	ks := make([]string, 0, len(d))
 	vs := make([]any, 0, len(d))
 
-	for k, v := range d {
+	for _, k := range dictKeys(d) {
 		ks = append(ks, k)
-		vs = append(vs, v)
+		vs = append(vs, d[k])
 	}
 	return ks, vs
 }
 
+// 获取字典的键名集。
+// 按字节序升序排列，作为字典迭代的确定顺序。
+func dictKeys(d Dict) []string {
+	return slices.Sorted(maps.Keys(d))
+}
+
 // 截取子字符串。
 // i 为起点字符位置（正数）。
 // n 为截取的字符数量。
@@ -3537,34 +3579,42 @@ func strSub2(s string, i, n int) string {
 }
 
 // 切片随机扰乱。
-// 随机数种子是安全的。
-func randSlice[T any](s []T) []T {
+// r 为执行器的随机数生成器。
+func randSlice[T any](r *rand.Rand, s []T) []T {
 	new := make([]T, len(s))
-	rand.Seed(
-		randInt(math.MaxInt64),
-	)
-	for i, n := range rand.Perm(len(s)) {
+
+	for i, n := range r.Perm(len(s)) {
 		new[i] = s[n]
 	}
 	return new
 }
 
-// 创建一个安全随机int64数。
-func randInt(max int64) int64 {
-	num, err := crand.Int(crand.Reader, big.NewInt(max))
-	if err != nil {
-		panic(err)
-	}
-	return num.Int64()
+// 创建一个随机int64数。
+func randInt(r *rand.Rand, max int64) int64 {
+	return r.Int64N(max)
 }
 
-// 创建一个安全随机大整数。
-func randBigInt(max *BigInt) *BigInt {
-	num, err := crand.Int(crand.Reader, max)
-	if err != nil {
-		panic(err)
+// 创建一个随机大整数。
+// 按上限的位数生成随机值，超出上限则重试。
+func randBigInt(r *rand.Rand, max *BigInt) *BigInt {
+	if max.Sign() <= 0 {
+		panic(_T("随机数上限值必须为正"))
+	}
+	k := new(big.Int).Sub(max, big.NewInt(1)).BitLen()
+	buf := make([]byte, (k+7)/8)
+	num := new(big.Int)
+
+	for {
+		for i := range buf {
+			buf[i] = byte(r.Uint32())
+		}
+		if len(buf) > 0 {
+			buf[0] &= byte(1<<(uint(k-1)%8+1) - 1)
+		}
+		if num.SetBytes(buf).Cmp(max) < 0 {
+			return num
+		}
 	}
-	return num
 }
 
 // 创建值范围切片。
@@ -3605,220 +3655,220 @@ func accessPanic(*Actuator, []any, any, ...any) []any {
 func init() {
 	// 值指令 20
 	// --------------------------------------
-	__InstSet[icode.NIL] = Instx{_NIL, 0}
-	__InstSet[icode.TRUE] = Instx{_TRUE, 0}
-	__InstSet[icode.FALSE] = Instx{_FALSE, 0}
-	__InstSet[icode.Uint8n] = Instx{_Int, 0}
-	__InstSet[icode.Uint8] = Instx{_Int, 0}
-	__InstSet[icode.Uint63n] = Instx{_Int, 0}
-	__InstSet[icode.Uint63] = Instx{_Int, 0}
-	__InstSet[icode.Byte] = Instx{_Byte, 0}
-	__InstSet[icode.Rune] = Instx{_Rune, 0}
-	__InstSet[icode.Float32] = Instx{_Float, 0}
-	__InstSet[icode.Float64] = Instx{_Float, 0}
-	__InstSet[icode.DATE] = Instx{_DATE, 0}
-	__InstSet[icode.BigInt] = Instx{_BigInt, 0}
-	__InstSet[icode.DATA8] = Instx{_DATA, 0}
-	__InstSet[icode.DATA16] = Instx{_DATA, 0}
-	__InstSet[icode.TEXT8] = Instx{_TEXT, 0}
-	__InstSet[icode.TEXT16] = Instx{_TEXT, 0}
-	__InstSet[icode.RegExp] = Instx{_RegExp, 0}
-	__InstSet[icode.CODE] = Instx{_CODE, 0}
+	__InstSet[icode.NIL] = Instx{Call: _NIL, Argn: 0}
+	__InstSet[icode.TRUE] = Instx{Call: _TRUE, Argn: 0}
+	__InstSet[icode.FALSE] = Instx{Call: _FALSE, Argn: 0}
+	__InstSet[icode.Uint8n] = Instx{Call: _Int, Argn: 0}
+	__InstSet[icode.Uint8] = Instx{Call: _Int, Argn: 0}
+	__InstSet[icode.Uint63n] = Instx{Call: _Int, Argn: 0}
+	__InstSet[icode.Uint63] = Instx{Call: _Int, Argn: 0}
+	__InstSet[icode.Byte] = Instx{Call: _Byte, Argn: 0}
+	__InstSet[icode.Rune] = Instx{Call: _Rune, Argn: 0}
+	__InstSet[icode.Float32] = Instx{Call: _Float, Argn: 0}
+	__InstSet[icode.Float64] = Instx{Call: _Float, Argn: 0}
+	__InstSet[icode.DATE] = Instx{Call: _DATE, Argn: 0}
+	__InstSet[icode.BigInt] = Instx{Call: _BigInt, Argn: 0}
+	__InstSet[icode.DATA8] = Instx{Call: _DATA, Argn: 0}
+	__InstSet[icode.DATA16] = Instx{Call: _DATA, Argn: 0}
+	__InstSet[icode.TEXT8] = Instx{Call: _TEXT, Argn: 0}
+	__InstSet[icode.TEXT16] = Instx{Call: _TEXT, Argn: 0}
+	__InstSet[icode.RegExp] = Instx{Call: _RegExp, Argn: 0}
+	__InstSet[icode.CODE] = Instx{Call: _CODE, Argn: 0}
 	// __InstSet[19] =
 
 	// 截取指令 5
 	// --------------------------------------
-	__InstSet[icode.Capture] = Instx{_Capture, 0}
-	__InstSet[icode.Bring] = Instx{_Bring, 0}
-	__InstSet[icode.ScopeAdd] = Instx{_ScopeAdd, 0}
-	__InstSet[icode.ScopeVal] = Instx{_ScopeVal, 0}
-	__InstSet[icode.LoopVal] = Instx{_LoopVal, 0}
+	__InstSet[icode.Capture] = Instx{Call: _Capture, Argn: 0}
+	__InstSet[icode.Bring] = Instx{Call: _Bring, Argn: 0}
+	__InstSet[icode.ScopeAdd] = Instx{Call: _ScopeAdd, Argn: 0}
+	__InstSet[icode.ScopeVal] = Instx{Call: _ScopeVal, Argn: 0}
+	__InstSet[icode.LoopVal] = Instx{Call: _LoopVal, Argn: 0}
 
 	// 栈操作指令 10
 	// --------------------------------------
-	__InstSet[icode.NOP] = Instx{_NOP, -1}
-	__InstSet[icode.PUSH] = Instx{_PUSH, -1}
-	__InstSet[icode.SHIFT] = Instx{_SHIFT, 0}
-	__InstSet[icode.CLONE] = Instx{_CLONE, 0}
-	__InstSet[icode.POP] = Instx{_POP, 0}
-	__InstSet[icode.POPS] = Instx{_POPS, 0}
-	__InstSet[icode.TOP] = Instx{_TOP, 0}
-	__InstSet[icode.TOPS] = Instx{_TOPS, 0}
-	__InstSet[icode.PEEK] = Instx{_PEEK, 0}
-	__InstSet[icode.PEEKS] = Instx{_PEEKS, 0}
+	__InstSet[icode.NOP] = Instx{Call: _NOP, Argn: -1}
+	__InstSet[icode.PUSH] = Instx{Call: _PUSH, Argn: -1}
+	__InstSet[icode.SHIFT] = Instx{Call: _SHIFT, Argn: 0}
+	__InstSet[icode.CLONE] = Instx{Call: _CLONE, Argn: 0}
+	__InstSet[icode.POP] = Instx{Call: _POP, Argn: 0}
+	__InstSet[icode.POPS] = Instx{Call: _POPS, Argn: 0}
+	__InstSet[icode.TOP] = Instx{Call: _TOP, Argn: 0}
+	__InstSet[icode.TOPS] = Instx{Call: _TOPS, Argn: 0}
+	__InstSet[icode.PEEK] = Instx{Call: _PEEK, Argn: 0}
+	__InstSet[icode.PEEKS] = Instx{Call: _PEEKS, Argn: 0}
 
 	// 集合指令 11
 	// --------------------------------------
-	__InstSet[icode.SLICE] = Instx{_SLICE, 3}
-	__InstSet[icode.REVERSE] = Instx{_REVERSE, 1}
-	__InstSet[icode.MERGE] = Instx{_MERGE, -1}
-	__InstSet[icode.EXPAND] = Instx{_EXPAND, -1}
-	__InstSet[icode.GLUE] = Instx{_GLUE, 1}
-	__InstSet[icode.SPREAD] = Instx{_SPREAD, 1}
-	__InstSet[icode.ITEM] = Instx{_ITEM, 2}
-	__InstSet[icode.SET] = Instx{_SET, 3}
-	__InstSet[icode.SIZE] = Instx{_SIZE, 1}
-	__InstSet[icode.MAP] = Instx{_MAP, -1}
-	__InstSet[icode.FILTER] = Instx{_FILTER, -1}
+	__InstSet[icode.SLICE] = Instx{Call: _SLICE, Argn: 3}
+	__InstSet[icode.REVERSE] = Instx{Call: _REVERSE, Argn: 1}
+	__InstSet[icode.MERGE] = Instx{Call: _MERGE, Argn: -1}
+	__InstSet[icode.EXPAND] = Instx{Call: _EXPAND, Argn: -1}
+	__InstSet[icode.GLUE] = Instx{Call: _GLUE, Argn: 1}
+	__InstSet[icode.SPREAD] = Instx{Call: _SPREAD, Argn: 1}
+	__InstSet[icode.ITEM] = Instx{Call: _ITEM, Argn: 2}
+	__InstSet[icode.SET] = Instx{Call: _SET, Argn: 3}
+	__InstSet[icode.SIZE] = Instx{Call: _SIZE, Argn: 1}
+	__InstSet[icode.MAP] = Instx{Call: _MAP, Argn: -1}
+	__InstSet[icode.FILTER] = Instx{Call: _FILTER, Argn: -1}
 
 	// 交互指令 5
 	// --------------------------------------
-	__InstSet[icode.INPUT] = Instx{_INPUT, 0}
-	__InstSet[icode.OUTPUT] = Instx{_OUTPUT, -1}
-	__InstSet[icode.BUFDUMP] = Instx{_BUFDUMP, 0}
+	__InstSet[icode.INPUT] = Instx{Call: _INPUT, Argn: 0}
+	__InstSet[icode.OUTPUT] = Instx{Call: _OUTPUT, Argn: -1}
+	__InstSet[icode.BUFDUMP] = Instx{Call: _BUFDUMP, Argn: 0}
 	// __InstSet[51] =
-	__InstSet[icode.PRINT] = Instx{_PRINT, -1}
+	__InstSet[icode.PRINT] = Instx{Call: _PRINT, Argn: -1}
 
 	// 结果指令 6
 	// --------------------------------------
-	__InstSet[icode.PASS] = Instx{_PASS, 1}
-	__InstSet[icode.FAIL] = Instx{_FAIL, 1}
-	__InstSet[icode.GOTO] = Instx{_GOTO, -1}
-	__InstSet[icode.JUMP] = Instx{_JUMP, 0}
-	__InstSet[icode.EXIT] = Instx{_EXIT, -1}
-	__InstSet[icode.RETURN] = Instx{_RETURN, 1}
+	__InstSet[icode.PASS] = Instx{Call: _PASS, Argn: 1}
+	__InstSet[icode.FAIL] = Instx{Call: _FAIL, Argn: 1}
+	__InstSet[icode.GOTO] = Instx{Call: _GOTO, Argn: -1}
+	__InstSet[icode.JUMP] = Instx{Call: _JUMP, Argn: 0}
+	__InstSet[icode.EXIT] = Instx{Call: _EXIT, Argn: -1}
+	__InstSet[icode.RETURN] = Instx{Call: _RETURN, Argn: 1}
 
 	// 流程指令 10
 	// --------------------------------------
-	__InstSet[icode.IF] = Instx{_IF, 1}
-	__InstSet[icode.ELSE] = Instx{_ELSE, 0}
-	__InstSet[icode.SWITCH] = Instx{_SWITCH, 2}
-	__InstSet[icode.CASE] = Instx{_CASE, 0}
-	__InstSet[icode.DEFAULT] = Instx{_DEFAULT, 0}
-	__InstSet[icode.EACH] = Instx{_EACH, 1}
-	__InstSet[icode.CONTINUE] = Instx{_CONTINUE, -1}
-	__InstSet[icode.BREAK] = Instx{_BREAK, -1}
-	__InstSet[icode.FALLTHROUGH] = Instx{_FALLTHROUGH, 0}
-	__InstSet[icode.BLOCK] = Instx{_BLOCK, 0}
+	__InstSet[icode.IF] = Instx{Call: _IF, Argn: 1}
+	__InstSet[icode.ELSE] = Instx{Call: _ELSE, Argn: 0}
+	__InstSet[icode.SWITCH] = Instx{Call: _SWITCH, Argn: 2}
+	__InstSet[icode.CASE] = Instx{Call: _CASE, Argn: 0}
+	__InstSet[icode.DEFAULT] = Instx{Call: _DEFAULT, Argn: 0}
+	__InstSet[icode.EACH] = Instx{Call: _EACH, Argn: 1}
+	__InstSet[icode.CONTINUE] = Instx{Call: _CONTINUE, Argn: -1}
+	__InstSet[icode.BREAK] = Instx{Call: _BREAK, Argn: -1}
+	__InstSet[icode.FALLTHROUGH] = Instx{Call: _FALLTHROUGH, Argn: 0}
+	__InstSet[icode.BLOCK] = Instx{Call: _BLOCK, Argn: 0}
 
 	// 转换指令 13
 	// --------------------------------------
-	__InstSet[icode.BOOL] = Instx{_BOOL, 1}
-	__InstSet[icode.BYTE] = Instx{_BYTE, 1}
-	__InstSet[icode.RUNE] = Instx{_RUNE, 1}
-	__InstSet[icode.INT] = Instx{_INT, 1}
-	__InstSet[icode.BIGINT] = Instx{_BIGINT, 1}
-	__InstSet[icode.FLOAT] = Instx{_FLOAT, 1}
-	__InstSet[icode.STRING] = Instx{_STRING, 1}
-	__InstSet[icode.BYTES] = Instx{_BYTES, 1}
-	__InstSet[icode.RUNES] = Instx{_RUNES, 1}
-	__InstSet[icode.TIME] = Instx{_TIME, 1}
-	__InstSet[icode.REGEXP] = Instx{_REGEXP, 1}
-	__InstSet[icode.ANYS] = Instx{_ANYS, 1}
-	__InstSet[icode.DICT] = Instx{_DICT, 2}
+	__InstSet[icode.BOOL] = Instx{Call: _BOOL, Argn: 1}
+	__InstSet[icode.BYTE] = Instx{Call: _BYTE, Argn: 1}
+	__InstSet[icode.RUNE] = Instx{Call: _RUNE, Argn: 1}
+	__InstSet[icode.INT] = Instx{Call: _INT, Argn: 1}
+	__InstSet[icode.BIGINT] = Instx{Call: _BIGINT, Argn: 1}
+	__InstSet[icode.FLOAT] = Instx{Call: _FLOAT, Argn: 1}
+	__InstSet[icode.STRING] = Instx{Call: _STRING, Argn: 1}
+	__InstSet[icode.BYTES] = Instx{Call: _BYTES, Argn: 1}
+	__InstSet[icode.RUNES] = Instx{Call: _RUNES, Argn: 1}
+	__InstSet[icode.TIME] = Instx{Call: _TIME, Argn: 1}
+	__InstSet[icode.REGEXP] = Instx{Call: _REGEXP, Argn: 1}
+	__InstSet[icode.ANYS] = Instx{Call: _ANYS, Argn: 1}
+	__InstSet[icode.DICT] = Instx{Call: _DICT, Argn: 2}
 
 	// 运算指令 24
 	// --------------------------------------
-	__InstSet[icode.Expr] = Instx{_Expr, 0}
-	__InstSet[icode.Mul] = Instx{accessPanic, 0}
-	__InstSet[icode.Div] = Instx{accessPanic, 0}
-	__InstSet[icode.Add] = Instx{accessPanic, 0}
-	__InstSet[icode.Sub] = Instx{accessPanic, 0}
-	__InstSet[icode.MUL] = Instx{_MUL, 2}
-	__InstSet[icode.DIV] = Instx{_DIV, 2}
-	__InstSet[icode.ADD] = Instx{_ADD, 2}
-	__InstSet[icode.SUB] = Instx{_SUB, 2}
-	__InstSet[icode.POW] = Instx{_POW, 2}
-	__InstSet[icode.MOD] = Instx{_MOD, 2}
-	__InstSet[icode.LMOV] = Instx{_LMOV, 2}
-	__InstSet[icode.RMOV] = Instx{_RMOV, 2}
-	__InstSet[icode.AND] = Instx{_AND, 2}
-	__InstSet[icode.ANDX] = Instx{_ANDX, 2}
-	__InstSet[icode.OR] = Instx{_OR, 2}
-	__InstSet[icode.XOR] = Instx{_XOR, 2}
-	__InstSet[icode.NEG] = Instx{_NEG, 1}
-	__InstSet[icode.NOT] = Instx{_NOT, 1}
-	__InstSet[icode.DIVMOD] = Instx{_DIVMOD, 2}
-	__InstSet[icode.DUP] = Instx{_DUP, 1}
-	__InstSet[icode.DEL] = Instx{_DEL, 2}
-	__InstSet[icode.CLEAR] = Instx{_CLEAR, 1}
+	__InstSet[icode.Expr] = Instx{Call: _Expr, Argn: 0}
+	__InstSet[icode.Mul] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.Div] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.Add] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.Sub] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.MUL] = Instx{Call: _MUL, Argn: 2}
+	__InstSet[icode.DIV] = Instx{Call: _DIV, Argn: 2}
+	__InstSet[icode.ADD] = Instx{Call: _ADD, Argn: 2}
+	__InstSet[icode.SUB] = Instx{Call: _SUB, Argn: 2}
+	__InstSet[icode.POW] = Instx{Call: _POW, Argn: 2}
+	__InstSet[icode.MOD] = Instx{Call: _MOD, Argn: 2}
+	__InstSet[icode.LMOV] = Instx{Call: _LMOV, Argn: 2}
+	__InstSet[icode.RMOV] = Instx{Call: _RMOV, Argn: 2}
+	__InstSet[icode.AND] = Instx{Call: _AND, Argn: 2}
+	__InstSet[icode.ANDX] = Instx{Call: _ANDX, Argn: 2}
+	__InstSet[icode.OR] = Instx{Call: _OR, Argn: 2}
+	__InstSet[icode.XOR] = Instx{Call: _XOR, Argn: 2}
+	__InstSet[icode.NEG] = Instx{Call: _NEG, Argn: 1}
+	__InstSet[icode.NOT] = Instx{Call: _NOT, Argn: 1}
+	__InstSet[icode.DIVMOD] = Instx{Call: _DIVMOD, Argn: 2}
+	__InstSet[icode.DUP] = Instx{Call: _DUP, Argn: 1}
+	__InstSet[icode.DEL] = Instx{Call: _DEL, Argn: 2}
+	__InstSet[icode.CLEAR] = Instx{Call: _CLEAR, Argn: 1}
 	// __InstSet[103] =
 
 	// 比较指令 8
 	// --------------------------------------
-	__InstSet[icode.EQUAL] = Instx{_EQUAL, 2}
-	__InstSet[icode.NEQUAL] = Instx{_NEQUAL, 2}
-	__InstSet[icode.LT] = Instx{_LT, 2}
-	__InstSet[icode.LTE] = Instx{_LTE, 2}
-	__InstSet[icode.GT] = Instx{_GT, 2}
-	__InstSet[icode.GTE] = Instx{_GTE, 2}
-	__InstSet[icode.ISNAN] = Instx{_ISNAN, 1}
-	__InstSet[icode.WITHIN] = Instx{_WITHIN, 3}
+	__InstSet[icode.EQUAL] = Instx{Call: _EQUAL, Argn: 2}
+	__InstSet[icode.NEQUAL] = Instx{Call: _NEQUAL, Argn: 2}
+	__InstSet[icode.LT] = Instx{Call: _LT, Argn: 2}
+	__InstSet[icode.LTE] = Instx{Call: _LTE, Argn: 2}
+	__InstSet[icode.GT] = Instx{Call: _GT, Argn: 2}
+	__InstSet[icode.GTE] = Instx{Call: _GTE, Argn: 2}
+	__InstSet[icode.ISNAN] = Instx{Call: _ISNAN, Argn: 1}
+	__InstSet[icode.WITHIN] = Instx{Call: _WITHIN, Argn: 3}
 
 	// 逻辑指令 4
 	// --------------------------------------
-	__InstSet[icode.BOTH] = Instx{_BOTH, 2}
-	__InstSet[icode.EVERY] = Instx{_EVERY, 1}
-	__InstSet[icode.EITHER] = Instx{_EITHER, 2}
-	__InstSet[icode.SOME] = Instx{_SOME, 1}
+	__InstSet[icode.BOTH] = Instx{Call: _BOTH, Argn: 2}
+	__InstSet[icode.EVERY] = Instx{Call: _EVERY, Argn: 1}
+	__InstSet[icode.EITHER] = Instx{Call: _EITHER, Argn: 2}
+	__InstSet[icode.SOME] = Instx{Call: _SOME, Argn: 1}
 
 	// 模式指令 12
 	// --------------------------------------
-	__InstSet[icode.MODEL] = Instx{_MODEL, 1}
-	__InstSet[icode.ValPick] = Instx{accessPanic, 0}
-	__InstSet[icode.Wildcard] = Instx{accessPanic, 0}
-	__InstSet[icode.Wildnum] = Instx{accessPanic, 0}
-	__InstSet[icode.Wildpart] = Instx{accessPanic, 0}
-	__InstSet[icode.Wildlist] = Instx{accessPanic, 0}
-	__InstSet[icode.TypeIs] = Instx{accessPanic, 0}
-	__InstSet[icode.WithinInt] = Instx{accessPanic, 0}
-	__InstSet[icode.WithinFloat] = Instx{accessPanic, 0}
-	__InstSet[icode.RE] = Instx{accessPanic, 0}
-	__InstSet[icode.RePick] = Instx{accessPanic, 0}
-	__InstSet[icode.WildLump] = Instx{accessPanic, 0}
+	__InstSet[icode.MODEL] = Instx{Call: _MODEL, Argn: 1}
+	__InstSet[icode.ValPick] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.Wildcard] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.Wildnum] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.Wildpart] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.Wildlist] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.TypeIs] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.WithinInt] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.WithinFloat] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.RE] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.RePick] = Instx{Call: accessPanic, Argn: 0}
+	__InstSet[icode.WildLump] = Instx{Call: accessPanic, Argn: 0}
 
 	// 环境指令 10
 	// --------------------------------------
-	__InstSet[icode.ENV] = Instx{_ENV, 0}
-	__InstSet[icode.OUT] = Instx{_OUT, 0}
-	__InstSet[icode.IN] = Instx{_IN, 0}
-	__InstSet[icode.INOUT] = Instx{_INOUT, 0}
-	__InstSet[icode.XFROM] = Instx{_XFROM, 0}
-	__InstSet[icode.VAR] = Instx{_VAR, 0}
-	__InstSet[icode.SETVAR] = Instx{_SETVAR, 1}
-	__InstSet[icode.SOURCE] = Instx{_SOURCE, 0}
-	__InstSet[icode.MULSIG] = Instx{_MULSIG, 0}
+	__InstSet[icode.ENV] = Instx{Call: _ENV, Argn: 0}
+	__InstSet[icode.OUT] = Instx{Call: _OUT, Argn: 0}
+	__InstSet[icode.IN] = Instx{Call: _IN, Argn: 0}
+	__InstSet[icode.INOUT] = Instx{Call: _INOUT, Argn: 0}
+	__InstSet[icode.XFROM] = Instx{Call: _XFROM, Argn: 0}
+	__InstSet[icode.VAR] = Instx{Call: _VAR, Argn: 0}
+	__InstSet[icode.SETVAR] = Instx{Call: _SETVAR, Argn: 1}
+	__InstSet[icode.SOURCE] = Instx{Call: _SOURCE, Argn: 0}
+	__InstSet[icode.MULSIG] = Instx{Call: _MULSIG, Argn: 0}
 	// __InstSet[137] =
 
 	// 工具指令 26
 	// --------------------------------------
-	__InstSet[icode.EVAL] = Instx{_EVAL, 1}
-	__InstSet[icode.COPY] = Instx{_COPY, 1}
-	__InstSet[icode.DCOPY] = Instx{_DCOPY, 1}
-	__InstSet[icode.KEYVAL] = Instx{_KEYVAL, 1}
-	__InstSet[icode.MATCH] = Instx{_MATCH, 2}
-	__InstSet[icode.SUBSTR] = Instx{_SUBSTR, 2}
-	__InstSet[icode.REPLACE] = Instx{_REPLACE, 3}
-	__InstSet[icode.SRAND] = Instx{_SRAND, 1}
-	__InstSet[icode.RANDOM] = Instx{_RANDOM, -1}
-	__InstSet[icode.QRANDOM] = Instx{_QRANDOM, -1}
-	__InstSet[icode.CMPFLO] = Instx{_CMPFLO, 3}
+	__InstSet[icode.EVAL] = Instx{Call: _EVAL, Argn: 1}
+	__InstSet[icode.COPY] = Instx{Call: _COPY, Argn: 1}
+	__InstSet[icode.DCOPY] = Instx{Call: _DCOPY, Argn: 1}
+	__InstSet[icode.KEYVAL] = Instx{Call: _KEYVAL, Argn: 1}
+	__InstSet[icode.MATCH] = Instx{Call: _MATCH, Argn: 2}
+	__InstSet[icode.SUBSTR] = Instx{Call: _SUBSTR, Argn: 2}
+	__InstSet[icode.REPLACE] = Instx{Call: _REPLACE, Argn: 3}
+	__InstSet[icode.SRAND] = Instx{Call: _SRAND, Argn: 1}
+	__InstSet[icode.RANDOM] = Instx{Call: _RANDOM, Argn: -1}
+	__InstSet[icode.QRANDOM] = Instx{Call: _QRANDOM, Argn: -1}
+	__InstSet[icode.CMPFLO] = Instx{Call: _CMPFLO, Argn: 3}
 	// __InstSet[149-154] =
-	__InstSet[icode.RANGE] = Instx{_RANGE, 2}
+	__InstSet[icode.RANGE] = Instx{Call: _RANGE, Argn: 2}
 	// __InstSet[156-163] =
 
 	// 系统指令 6
 	// --------------------------------------
-	__InstSet[icode.SYS_TIME] = Instx{_SYS_TIME, 0}
-	__InstSet[icode.SYS_AWARD] = Instx{_SYS_AWARD, 0}
+	__InstSet[icode.SYS_TIME] = Instx{Call: _SYS_TIME, Argn: 0}
+	__InstSet[icode.SYS_AWARD] = Instx{Call: _SYS_AWARD, Argn: 0}
 	// __InstSet[166-168] =
-	__InstSet[icode.SYS_NULL] = Instx{_SYS_NULL, 0}
+	__InstSet[icode.SYS_NULL] = Instx{Call: _SYS_NULL, Argn: 0}
 
 	// 函数指令 40
 	// --------------------------------------
-	__InstSet[icode.FN_BASE58] = Instx{_FN_BASE58, 1}
-	__InstSet[icode.FN_BASE32] = Instx{_FN_BASE32, 1}
-	__InstSet[icode.FN_BASE64] = Instx{_FN_BASE64, 1}
-	__InstSet[icode.FN_PUBHASH] = Instx{_FN_PUBHASH, 1}
-	__InstSet[icode.FN_MPUBHASH] = Instx{_FN_MPUBHASH, 2}
-	__InstSet[icode.FN_ADDRESS] = Instx{_FN_ADDRESS, 2}
-	__InstSet[icode.FN_CHECKSIG] = Instx{_FN_CHECKSIG, 2}
-	__InstSet[icode.FN_MCHECKSIG] = Instx{_FN_MCHECKSIG, 2}
-	__InstSet[icode.FN_HASH224] = Instx{_FN_HASH224, 1}
-	__InstSet[icode.FN_HASH256] = Instx{_FN_HASH256, 1}
-	__InstSet[icode.FN_HASH384] = Instx{_FN_HASH384, 1}
-	__InstSet[icode.FN_HASH512] = Instx{_FN_HASH512, 1}
+	__InstSet[icode.FN_BASE58] = Instx{Call: _FN_BASE58, Argn: 1}
+	__InstSet[icode.FN_BASE32] = Instx{Call: _FN_BASE32, Argn: 1}
+	__InstSet[icode.FN_BASE64] = Instx{Call: _FN_BASE64, Argn: 1}
+	__InstSet[icode.FN_PUBHASH] = Instx{Call: _FN_PUBHASH, Argn: 1}
+	__InstSet[icode.FN_MPUBHASH] = Instx{Call: _FN_MPUBHASH, Argn: 2}
+	__InstSet[icode.FN_ADDRESS] = Instx{Call: _FN_ADDRESS, Argn: 2}
+	__InstSet[icode.FN_CHECKSIG] = Instx{Call: _FN_CHECKSIG, Argn: 2}
+	__InstSet[icode.FN_MCHECKSIG] = Instx{Call: _FN_MCHECKSIG, Argn: 2}
+	__InstSet[icode.FN_HASH224] = Instx{Call: _FN_HASH224, Argn: 1}
+	__InstSet[icode.FN_HASH256] = Instx{Call: _FN_HASH256, Argn: 1}
+	__InstSet[icode.FN_HASH384] = Instx{Call: _FN_HASH384, Argn: 1}
+	__InstSet[icode.FN_HASH512] = Instx{Call: _FN_HASH512, Argn: 1}
 	// __InstSet[182-207] =
-	__InstSet[icode.FN_PRINTF] = Instx{_FN_PRINTF, -1}
+	__InstSet[icode.FN_PRINTF] = Instx{Call: _FN_PRINTF, Argn: -1}
 	// Done.
 }
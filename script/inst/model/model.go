@@ -7,7 +7,7 @@ package model
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
+	"errors"
 	"regexp"
 
 	"github.com/cxio/suite/cbase"
@@ -31,7 +31,7 @@ var (
 )
 
 // 段指令通配错误。
-var errLump = fmt.Errorf(_T("段指令通配（...）的目标脚本长度不足"))
+var errLump = errors.New(_T("段指令通配（...）的目标脚本长度不足"))
 
 const (
 	// 默认处理器索引
@@ -195,7 +195,7 @@ type Modeler func(_ *State, s, m []byte) (int, int, bool)
 
 // 模式处理器集。
 // 模式区内的各个模式功能指令配置。
-var __Process map[int]Modeler
+var __Process = make(map[int]Modeler)
 
 // 类型匹配检查配置。
 var __typeChecks = map[int]func(int) bool{
@@ -221,7 +221,7 @@ type lumpTester func(m, s []byte) (int, int, bool)
 
 // 片段通配（...）测试器集。
 // 适用 ... 片段比较的定制版。
-var __lumpProcess map[int]lumpTester
+var __lumpProcess = make(map[int]lumpTester)
 
 /*
  * 模式指令（处理器）
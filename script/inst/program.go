@@ -0,0 +1,225 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package inst
+
+import (
+	"bytes"
+	"hash/maphash"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cxio/suite/script/icode"
+)
+
+//
+// 预解码指令流
+// 代码段（脚本或子语句块）首次执行时整体解码为指令序列，
+// 每条指令预先确定调用器、实参数量和信息包，按代码内容的哈希缓存，
+// 在所有执行流之间共享。循环（EACH/MAP 等）内重复执行的子块无需再次解析。
+//
+// 注记：
+// 信息包中引用代码的字节序列（子语句块、DATA 等）不随缓存保存，
+// 执行时从当前代码段重新切取，以保持与所属脚本的子切片关系（见 Actuator.baseOf），
+// 因此内容相同的不同脚本可共享同一缓存条目。
+///////////////////////////////////////////////////////////////////////////////
+
+// 缓存容量（条目数）。
+// 满额时按时钟（CLOCK）算法移除条目：命中的条目获得一次保留机会，
+// 近似于最近最少使用（LRU），但命中时只需读锁和一次原子写，
+// 不像链表式 LRU（如 xpool）需在每次命中时加写锁调整顺序。
+const codeCacheSize = 1 << 12
+
+// 代码哈希种子。
+var codeSeed = maphash.MakeSeed()
+
+// 停用缓存。
+// 仅用于基准测试的对比，每次请求都重新构造。
+var codeCacheOff bool
+
+// 代码缓存。
+// 以代码内容的哈希为键，命中时比对内容以排除哈希碰撞。
+type codeCache[T any] struct {
+	mu   sync.RWMutex
+	m    map[uint64]*codeEntry[T]
+	ring []uint64 // 条目键环，时钟算法的扫描序
+	hand int      // 时钟指针
+}
+
+// 缓存条目。
+type codeEntry[T any] struct {
+	code []byte // 代码副本
+	val  T
+	used atomic.Bool // 上次扫描后曾被命中
+}
+
+// 新建代码缓存。
+func newCodeCache[T any]() *codeCache[T] {
+	return &codeCache[T]{m: make(map[uint64]*codeEntry[T])}
+}
+
+// 获取代码的缓存值。
+// 未命中时由 build 以代码副本构造并存入缓存。
+// 注：build 在锁外执行，并发的首次请求可能重复构造，结果等价。
+func (c *codeCache[T]) get(code []byte, build func([]byte) T) T {
+	if codeCacheOff {
+		return build(bytes.Clone(code))
+	}
+	k := maphash.Bytes(codeSeed, code)
+
+	c.mu.RLock()
+	e, ok := c.m[k]
+	c.mu.RUnlock()
+
+	if ok && bytes.Equal(e.code, code) {
+		e.used.Store(true)
+		return e.val
+	}
+	// 由副本构造，缓存值可能引用代码（如字面值数据），
+	// 不能与调用者的缓冲区关联。
+	code = bytes.Clone(code)
+	e = &codeEntry[T]{code: code, val: build(code)}
+
+	c.mu.Lock()
+	c.put(k, e)
+	c.mu.Unlock()
+
+	return e.val
+}
+
+// 存入条目。
+// 同键条目（哈希碰撞或并发构造）原位替换，否则满额时先移除一个条目。
+// 注：调用者需持有写锁。
+func (c *codeCache[T]) put(k uint64, e *codeEntry[T]) {
+	if _, ok := c.m[k]; ok {
+		c.m[k] = e
+		return
+	}
+	if len(c.ring) < codeCacheSize {
+		c.ring = append(c.ring, k)
+		c.m[k] = e
+		return
+	}
+	// 跳过近期命中的条目（清除其标记），移除首个未命中者。
+	// 至多扫描一圈即可找到。
+	for {
+		x := c.m[c.ring[c.hand]]
+		if !x.used.Load() {
+			break
+		}
+		x.used.Store(false)
+		c.hand = (c.hand + 1) % len(c.ring)
+	}
+	delete(c.m, c.ring[c.hand])
+	c.ring[c.hand] = k
+	c.m[k] = e
+	c.hand = (c.hand + 1) % len(c.ring)
+}
+
+// 预解码的指令。
+type decoded struct {
+	call Wrapper
+	argn int
+	ins  *Insted
+	rel  int // 关联数据在指令内的偏移，-1 表示不引用代码
+	size int // 关联数据长度（rel >= 0 时有效）
+	fail any // 解码时的异常，执行时抛出
+}
+
+// 解码指令。
+// code 为代码段从目标指令开始的部分。
+// 解码异常被记录而非抛出，留待执行到该指令时再抛出。
+func decode(code []byte) (d *decoded) {
+	d = &decoded{rel: -1}
+
+	defer func() {
+		if v := recover(); v != nil {
+			d.fail = v
+		}
+	}()
+	f, n, ins := instGet(code, int(code[0]))
+	d.call, d.argn = f, n
+
+	if b, ok := ins.Data.([]byte); ok && len(b) > 0 {
+		if i := cap(code) - cap(b); i >= 0 && i < len(code) && &code[i] == &b[0] {
+			x := *ins
+			x.Data = nil
+			d.ins, d.rel, d.size = &x, i, len(b)
+			return
+		}
+	}
+	d.ins = ins
+	return
+}
+
+// 关联数据。
+// start 为指令在代码段 src 中的偏移。
+func (d *decoded) data(src []byte, start int) any {
+	if d.rel < 0 {
+		return d.ins.Data
+	}
+	i := start + d.rel
+	return src[i : i+d.size]
+}
+
+// 指令程序。
+// 代码段解码后的指令序列。
+type program struct {
+	list []*decoded
+	offs []int // 各指令的偏移
+}
+
+// 程序缓存。
+var programs = newCodeCache[*program]()
+
+// 获取代码段的程序。
+func programOf(code []byte) *program {
+	return programs.get(code, compileCode)
+}
+
+// 解码代码段。
+// 遇到解码异常即终止，该指令作为序列的最后一条。
+// 子语句块、表达式和 CODE 代码一并预先解码。
+func compileCode(code []byte) *program {
+	p := new(program)
+
+	for i := 0; i < len(code); {
+		d := decode(code[i:])
+		p.list = append(p.list, d)
+		p.offs = append(p.offs, i)
+
+		if d.fail != nil {
+			break
+		}
+		if d.ins.Size <= 0 {
+			d.fail = neverToHere
+			break
+		}
+		prebuild(d.ins.Code, d.data(code, i))
+		i += d.ins.Size
+	}
+	return p
+}
+
+// 预先解码内嵌代码。
+// 出错时忽略，留待执行时处理。
+func prebuild(c int, data any) {
+	defer func() { _ = recover() }()
+
+	switch c {
+	case icode.IF, icode.ELSE, icode.SWITCH, icode.CASE, icode.DEFAULT,
+		icode.EACH, icode.BLOCK, icode.MAP, icode.FILTER:
+		programOf(data.([]byte))
+	case icode.CODE:
+		programOf(data.(*Script).Source())
+	case icode.Expr:
+		ExprCompile(data.([]byte))
+	}
+}
+
+// 查找指令下标。
+// off 为指令偏移，非指令起点时 ok 为假。
+func (p *program) index(off int) (int, bool) {
+	return slices.BinarySearch(p.offs, off)
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package instor
+
+import (
+	"encoding/binary"
+
+	"github.com/cxio/suite/script/icode"
+)
+
+//
+// 指令布局
+// 描述各指令附参和关联数据的字节构成，供校验、反汇编等外部工具使用。
+// 注：
+// 与上面的捡取器（__Pickes）保持一致，修改时需同步。
+///////////////////////////////////////////////////////////////////////////////
+
+// 布局类型。
+const (
+	LayoutSingle = iota // 单指令，无附参和数据
+	LayoutArgs          // 仅附参，各附参相互独立
+	LayoutValue         // 附参即数据（值指令）
+	LayoutBytes         // 末个附参为数据长度，其后为数据
+	LayoutExten         // 扩展类，附参为扩展索引，数据长度由扩展自身定义
+)
+
+// 变长整数附参的长度标识。
+const SizeVarint = -1
+
+// 指令布局。
+// Size 为各附参的字节数，变长整数为 SizeVarint。
+// 值指令（LayoutValue）的 Size[0] 即为数据的字节数。
+type Layout struct {
+	Kind    int   // 布局类型
+	Size    []int // 附参字节数序列
+	Pattern bool  // 是否为模式指令（仅限模式区内）
+}
+
+// 获取指令的布局。
+// 未定义的指令码返回单指令布局。
+func LayoutOf(c int) Layout {
+	return __Layouts[c]
+}
+
+// 从长度附参获取数据长度。
+// c 为指令码，arg 为长度附参的原始字节。
+// 注：MODEL 的高2位为标记位，需排除。
+func DataLen(c int, arg []byte) int {
+	switch len(arg) {
+	case 1:
+		return int(arg[0])
+	case 2:
+		n := binary.BigEndian.Uint16(arg)
+		if c == icode.MODEL {
+			n &^= 0b1100_0000_0000_0000
+		}
+		return int(n)
+	}
+	n, _ := binary.Uvarint(arg)
+	return int(n)
+}
+
+// 指令布局集。
+var __Layouts [256]Layout
+
+func init() {
+	set := func(lay Layout, cs ...int) {
+		for _, c := range cs {
+			__Layouts[c] = lay
+		}
+	}
+	// 值指令
+	set(Layout{Kind: LayoutValue, Size: []int{1}}, icode.Uint8n, icode.Uint8, icode.Byte)
+	set(Layout{Kind: LayoutValue, Size: []int{4}}, icode.Rune, icode.Float32)
+	set(Layout{Kind: LayoutValue, Size: []int{8}}, icode.Float64)
+	set(Layout{Kind: LayoutValue, Size: []int{SizeVarint}}, icode.Uint63n, icode.Uint63, icode.DATE)
+
+	// 长度+数据
+	set(Layout{Kind: LayoutBytes, Size: []int{1}},
+		icode.BigInt, icode.DATA8, icode.TEXT8, icode.RegExp, icode.CODE,
+		icode.MAP, icode.FILTER, icode.IF, icode.ELSE, icode.CASE, icode.DEFAULT,
+		icode.EACH, icode.Expr,
+	)
+	set(Layout{Kind: LayoutBytes, Size: []int{2}}, icode.DATA16, icode.TEXT16, icode.MODEL)
+	set(Layout{Kind: LayoutBytes, Size: []int{SizeVarint}}, icode.SWITCH, icode.BLOCK)
+
+	// 普通附参
+	set(Layout{Kind: LayoutArgs, Size: []int{1}},
+		icode.ScopeVal, icode.LoopVal, icode.SHIFT, icode.CLONE, icode.POPS, icode.TOPS, icode.PEEKS,
+		icode.INPUT, icode.BUFDUMP, icode.STRING, icode.ANYS, icode.DUP, icode.SOME,
+		icode.ENV, icode.IN, icode.INOUT, icode.XFROM, icode.VAR, icode.SETVAR, icode.SOURCE, icode.MULSIG,
+		icode.KEYVAL, icode.MATCH, icode.REPLACE, icode.CMPFLO, icode.SYS_TIME,
+		icode.FN_CHECKSIG, icode.FN_MCHECKSIG, icode.FN_HASH224, icode.FN_HASH256,
+		icode.FN_HASH384, icode.FN_HASH512, icode.FN_X,
+		icode.MO_RE, icode.MO_TIME, icode.MO_MATH, icode.MO_CRYPT,
+	)
+	set(Layout{Kind: LayoutArgs, Size: []int{2}}, icode.SUBSTR, icode.RANGE, icode.EX_FN)
+	set(Layout{Kind: LayoutArgs, Size: []int{4, 4, 2}}, icode.GOTO, icode.JUMP)
+	set(Layout{Kind: LayoutArgs, Size: []int{2, 1}}, icode.OUT)
+
+	// 扩展类
+	set(Layout{Kind: LayoutExten, Size: []int{1}}, icode.MO_X)
+	set(Layout{Kind: LayoutExten, Size: []int{2}}, icode.EX_INST, icode.EX_PRIV)
+
+	// 模式指令
+	set(Layout{Kind: LayoutSingle, Pattern: true}, icode.Wildcard, icode.WildLump)
+	set(Layout{Kind: LayoutArgs, Size: []int{1}, Pattern: true},
+		icode.ValPick, icode.Wildnum, icode.Wildpart, icode.TypeIs, icode.RePick,
+	)
+	set(Layout{Kind: LayoutArgs, Size: []int{SizeVarint, SizeVarint}, Pattern: true}, icode.WithinInt)
+	set(Layout{Kind: LayoutArgs, Size: []int{8, 8, 4}, Pattern: true}, icode.WithinFloat)
+	set(Layout{Kind: LayoutBytes, Size: []int{1}, Pattern: true}, icode.Wildlist)
+	set(Layout{Kind: LayoutBytes, Size: []int{1, 1}, Pattern: true}, icode.RE)
+}
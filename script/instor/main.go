@@ -155,6 +155,7 @@ type Insted struct {
 
 // 获取指令信息包
 // code 为脚本指令序列，从目标指令位置开始。
+// 注：不做边界检查，外部来源的脚本需先通过 Validate 校验。
 func Get(code []byte) *Insted {
 	c := int(code[0])
 
@@ -183,6 +184,7 @@ type Instor struct {
 
 // 获取指令原始信息包
 // code 为脚本指令序列，从目标指令位置开始。
+// 注：同上，不做边界检查。
 func Raw(code []byte) *Instor {
 	c := int(code[0])
 
@@ -1379,7 +1381,7 @@ func init() {
 	__Pickes[icode.ValPick] = instArg1
 	__Pickes[icode.Wildnum] = instArg1
 	__Pickes[icode.Wildpart] = instArg1
-	__Pickes[icode.Wildlist] = instArg1
+	__Pickes[icode.Wildlist] = instArg1Bytes
 	__Pickes[icode.TypeIs] = instArg1
 	__Pickes[icode.WithinInt] = withinInt
 	__Pickes[icode.WithinFloat] = withinFloat
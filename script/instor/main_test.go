@@ -0,0 +1,22 @@
+package instor_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cxio/suite/script/icode"
+	"github.com/cxio/suite/script/instor"
+)
+
+// 列表通配（?{...}）的附参为子序列长度，关联数据为子序列。
+func TestRawWildlist(t *testing.T) {
+	code := []byte{icode.Wildlist, 2, icode.NOP, icode.PASS, icode.NOP}
+	ins := instor.Raw(code)
+
+	if ins.Size != 4 || !bytes.Equal(ins.Data, []byte{icode.NOP, icode.PASS}) {
+		t.Errorf("Raw(Wildlist): size %d, data %x", ins.Size, ins.Data)
+	}
+	if n := instor.Get(code).Size; n != 4 {
+		t.Errorf("Get(Wildlist): size %d, want 4", n)
+	}
+}
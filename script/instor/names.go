@@ -3,6 +3,8 @@
 
 package instor
 
+import "github.com/cxio/suite/script/icode"
+
 // 循环域4个成员位置标识
 const (
 	LoopValue int = iota // 值（any）
@@ -249,6 +251,32 @@ var TimeNames = []string{
 	TimeMicrosecond: "Microsecond",
 }
 
+// 签名消息类型标识值。
+// 用于签名指令 FN_CHECKSIG/FN_MCHECKSIG，决定签名消息涵盖的交易数据。
+// 构成（1字节）：
+// - 低2位为输出方式（SigAll 等），值3保留。
+// - SigExclude 方式下，2-6 位为排除的输出位图，仅能标记序位 0-4 的输出，其它方式下须为零。
+// - 排除方式因此仅适用于输出数不超过 SigExcludes 的交易，更多输出时签名消息无法构造。
+// - 最高位为输入方式修饰（SigAnyOne）。
+const (
+	SigAll     = iota // 全部输入和输出
+	SigSingle         // 与当前输入同序位的输出
+	SigExclude        // 全部输出，但排除位图标记的输出
+
+	SigOutMask  = 0x03 // 输出方式掩码
+	SigExcludes = 5    // 排除方式适用的最大输出数
+	SigAnyOne   = 0x80 // 修饰：仅含当前输入，他人可追加输入
+)
+
+// 签名消息类型名称集。
+// 下标即标识值，未命名的组合（如排除位图）以数值表示。
+var SigNames = []string{
+	SigAll:                "All",
+	SigSingle:             "Single",
+	SigAll | SigAnyOne:    "AllAnyOne",
+	SigSingle | SigAnyOne: "SingleAnyOne",
+}
+
 // 哈希算法标识值。
 const (
 	HashSHA3 = iota
@@ -319,3 +347,221 @@ const (
 var MOTimeMethod = []string{
 	//
 }
+
+//
+// 指令名称
+// 按指令码值索引，用于脚本的汇编与反汇编。
+///////////////////////////////////////////////////////////////////////////////
+
+// 指令名称定义。
+// 与 icode 包中的常量名相同，未用的指令码为空串。
+// 注：
+// 仅首字母大写的为符号指令，源码中通常以符号形式书写。
+var CodeNames = [256]string{
+	// 值指令
+	icode.NIL:     "NIL",
+	icode.TRUE:    "TRUE",
+	icode.FALSE:   "FALSE",
+	icode.Uint8n:  "Uint8n",
+	icode.Uint8:   "Uint8",
+	icode.Uint63n: "Uint63n",
+	icode.Uint63:  "Uint63",
+	icode.Byte:    "Byte",
+	icode.Rune:    "Rune",
+	icode.Float32: "Float32",
+	icode.Float64: "Float64",
+	icode.DATE:    "DATE",
+	icode.BigInt:  "BigInt",
+	icode.DATA8:   "DATA8",
+	icode.DATA16:  "DATA16",
+	icode.TEXT8:   "TEXT8",
+	icode.TEXT16:  "TEXT16",
+	icode.RegExp:  "RegExp",
+	icode.CODE:    "CODE",
+
+	// 取值指令
+	icode.Capture:  "Capture",
+	icode.Bring:    "Bring",
+	icode.ScopeAdd: "ScopeAdd",
+	icode.ScopeVal: "ScopeVal",
+	icode.LoopVal:  "LoopVal",
+
+	// 栈操作指令
+	icode.NOP:   "NOP",
+	icode.PUSH:  "PUSH",
+	icode.SHIFT: "SHIFT",
+	icode.CLONE: "CLONE",
+	icode.POP:   "POP",
+	icode.POPS:  "POPS",
+	icode.TOP:   "TOP",
+	icode.TOPS:  "TOPS",
+	icode.PEEK:  "PEEK",
+	icode.PEEKS: "PEEKS",
+
+	// 集合指令
+	icode.SLICE:   "SLICE",
+	icode.REVERSE: "REVERSE",
+	icode.MERGE:   "MERGE",
+	icode.EXPAND:  "EXPAND",
+	icode.GLUE:    "GLUE",
+	icode.SPREAD:  "SPREAD",
+	icode.ITEM:    "ITEM",
+	icode.SET:     "SET",
+	icode.SIZE:    "SIZE",
+	icode.MAP:     "MAP",
+	icode.FILTER:  "FILTER",
+
+	// 交互指令
+	icode.INPUT:   "INPUT",
+	icode.OUTPUT:  "OUTPUT",
+	icode.BUFDUMP: "BUFDUMP",
+	icode.PRINT:   "PRINT",
+
+	// 结果指令
+	icode.PASS:   "PASS",
+	icode.FAIL:   "FAIL",
+	icode.GOTO:   "GOTO",
+	icode.JUMP:   "JUMP",
+	icode.EXIT:   "EXIT",
+	icode.RETURN: "RETURN",
+
+	// 流程指令
+	icode.IF:          "IF",
+	icode.ELSE:        "ELSE",
+	icode.SWITCH:      "SWITCH",
+	icode.CASE:        "CASE",
+	icode.DEFAULT:     "DEFAULT",
+	icode.EACH:        "EACH",
+	icode.CONTINUE:    "CONTINUE",
+	icode.BREAK:       "BREAK",
+	icode.FALLTHROUGH: "FALLTHROUGH",
+	icode.BLOCK:       "BLOCK",
+
+	// 转换指令
+	icode.BOOL:   "BOOL",
+	icode.BYTE:   "BYTE",
+	icode.RUNE:   "RUNE",
+	icode.INT:    "INT",
+	icode.BIGINT: "BIGINT",
+	icode.FLOAT:  "FLOAT",
+	icode.STRING: "STRING",
+	icode.BYTES:  "BYTES",
+	icode.RUNES:  "RUNES",
+	icode.TIME:   "TIME",
+	icode.REGEXP: "REGEXP",
+	icode.ANYS:   "ANYS",
+	icode.DICT:   "DICT",
+
+	// 运算指令
+	icode.Expr:   "Expr",
+	icode.Mul:    "Mul",
+	icode.Div:    "Div",
+	icode.Add:    "Add",
+	icode.Sub:    "Sub",
+	icode.MUL:    "MUL",
+	icode.DIV:    "DIV",
+	icode.ADD:    "ADD",
+	icode.SUB:    "SUB",
+	icode.POW:    "POW",
+	icode.MOD:    "MOD",
+	icode.LMOV:   "LMOV",
+	icode.RMOV:   "RMOV",
+	icode.AND:    "AND",
+	icode.ANDX:   "ANDX",
+	icode.OR:     "OR",
+	icode.XOR:    "XOR",
+	icode.NEG:    "NEG",
+	icode.NOT:    "NOT",
+	icode.DIVMOD: "DIVMOD",
+	icode.DUP:    "DUP",
+	icode.DEL:    "DEL",
+	icode.CLEAR:  "CLEAR",
+
+	// 比较指令
+	icode.EQUAL:  "EQUAL",
+	icode.NEQUAL: "NEQUAL",
+	icode.LT:     "LT",
+	icode.LTE:    "LTE",
+	icode.GT:     "GT",
+	icode.GTE:    "GTE",
+	icode.ISNAN:  "ISNAN",
+	icode.WITHIN: "WITHIN",
+
+	// 逻辑指令
+	icode.BOTH:   "BOTH",
+	icode.EVERY:  "EVERY",
+	icode.EITHER: "EITHER",
+	icode.SOME:   "SOME",
+
+	// 模式指令
+	icode.MODEL:       "MODEL",
+	icode.ValPick:     "ValPick",
+	icode.Wildcard:    "Wildcard",
+	icode.Wildnum:     "Wildnum",
+	icode.Wildpart:    "Wildpart",
+	icode.Wildlist:    "Wildlist",
+	icode.TypeIs:      "TypeIs",
+	icode.WithinInt:   "WithinInt",
+	icode.WithinFloat: "WithinFloat",
+	icode.RE:          "RE",
+	icode.RePick:      "RePick",
+	icode.WildLump:    "WildLump",
+
+	// 环境指令
+	icode.ENV:    "ENV",
+	icode.OUT:    "OUT",
+	icode.IN:     "IN",
+	icode.INOUT:  "INOUT",
+	icode.XFROM:  "XFROM",
+	icode.VAR:    "VAR",
+	icode.SETVAR: "SETVAR",
+	icode.SOURCE: "SOURCE",
+	icode.MULSIG: "MULSIG",
+
+	// 工具指令
+	icode.EVAL:    "EVAL",
+	icode.COPY:    "COPY",
+	icode.DCOPY:   "DCOPY",
+	icode.KEYVAL:  "KEYVAL",
+	icode.MATCH:   "MATCH",
+	icode.SUBSTR:  "SUBSTR",
+	icode.REPLACE: "REPLACE",
+	icode.SRAND:   "SRAND",
+	icode.RANDOM:  "RANDOM",
+	icode.QRANDOM: "QRANDOM",
+	icode.CMPFLO:  "CMPFLO",
+	icode.RANGE:   "RANGE",
+
+	// 系统指令
+	icode.SYS_TIME:  "SYS_TIME",
+	icode.SYS_AWARD: "SYS_AWARD",
+	icode.SYS_NULL:  "SYS_NULL",
+
+	// 函数指令
+	icode.FN_BASE58:    "FN_BASE58",
+	icode.FN_BASE32:    "FN_BASE32",
+	icode.FN_BASE64:    "FN_BASE64",
+	icode.FN_PUBHASH:   "FN_PUBHASH",
+	icode.FN_MPUBHASH:  "FN_MPUBHASH",
+	icode.FN_ADDRESS:   "FN_ADDRESS",
+	icode.FN_CHECKSIG:  "FN_CHECKSIG",
+	icode.FN_MCHECKSIG: "FN_MCHECKSIG",
+	icode.FN_HASH224:   "FN_HASH224",
+	icode.FN_HASH256:   "FN_HASH256",
+	icode.FN_HASH384:   "FN_HASH384",
+	icode.FN_HASH512:   "FN_HASH512",
+	icode.FN_PRINTF:    "FN_PRINTF",
+	icode.FN_X:         "FN_X",
+
+	// 模块指令
+	icode.MO_RE:    "MO_RE",
+	icode.MO_TIME:  "MO_TIME",
+	icode.MO_MATH:  "MO_MATH",
+	icode.MO_CRYPT: "MO_CRYPT",
+	icode.MO_X:     "MO_X",
+
+	// 扩展指令
+	icode.EX_FN:   "EX_FN",
+	icode.EX_INST: "EX_INST",
+	icode.EX_PRIV: "EX_PRIV",
+}
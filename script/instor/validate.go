@@ -0,0 +1,333 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package instor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"regexp"
+
+	"github.com/cxio/suite/locale"
+	"github.com/cxio/suite/script/icode"
+)
+
+//
+// 脚本校验
+// 解析器（Get/Raw）假定脚本格式合法，不做边界检查，
+// 因此外部来源的脚本应当在执行前先通过校验。
+///////////////////////////////////////////////////////////////////////////////
+
+var _T = locale.GetText
+
+// 校验出错消息。
+var (
+	errUnassigned = _T("未定义的指令码")
+	errTruncated  = _T("指令长度超出脚本末尾")
+	errVarint     = _T("变长整数编码无效")
+	errOverflow   = _T("整数值超出存储范围")
+	errPattern    = _T("模式指令只能用于模式区内")
+	errOperator   = _T("运算符只能用于表达式内")
+	errWildTarget = _T("局部通配之后缺少目标指令")
+	errWildModel  = _T("模式指令不能被局部通配修饰")
+	errFlag       = _T("无效的标记值")
+)
+
+// 表达式语法检查器。
+// 由指令实现包（inst）注册，校验时对顶层表达式（模式区外）调用。
+// code 为表达式指令的关联数据，出错时返回错误指令在 code 中的偏移和原因，
+// 偏移为 len(code) 表示表达式不完整。
+var ExprCheck func(code []byte) (int, error)
+
+// 匹配哈希长度。
+// 局部通配的哈希匹配形式（?(n) 高位置位）中数据为此长度。
+const wildHashSize = 20
+
+// 局部通配标识位。
+const (
+	wildData = 0b0100_0000 // 关联数据通配
+	wildHash = 0b1000_0000 // 哈希匹配
+)
+
+// 校验错误。
+// Offset 为出错指令相对于脚本起点的偏移，嵌套块内的指令亦同。
+type CodeError struct {
+	Offset int    // 指令偏移
+	Code   int    // 指令码
+	Reason string // 错误原因
+}
+
+func (e *CodeError) Error() string {
+	return fmt.Sprintf(_T("偏移 %d 处的指令（%d）无效：%s"), e.Offset, e.Code, e.Reason)
+}
+
+// 校验脚本。
+// 遍历全部指令（含子语句块），检查指令码、附参和数据的长度，以及正则表达式的合法性。
+// 无误时返回nil，否则返回 *CodeError。
+func Validate(code []byte) error {
+	v := validator{}
+	// 限制容量，避免越过末尾的切片
+	return v.list(code[:len(code):len(code)], 0)
+}
+
+// 校验器。
+type validator struct {
+	model int // 模式区嵌套
+	expr  int // 表达式嵌套
+}
+
+// 校验指令序列。
+// base 为序列起点在脚本中的偏移。
+func (v *validator) list(code []byte, base int) error {
+	for i := 0; i < len(code); {
+		c := int(code[i])
+		off := base + i
+
+		n, err := v.inst(code[i:], off)
+		if err != nil {
+			return err
+		}
+		i += n
+
+		if c != icode.Wildpart || v.model == 0 {
+			continue
+		}
+		// 局部通配的目标指令
+		if i >= len(code) {
+			return &CodeError{off, c, errWildTarget}
+		}
+		if n, err = v.wild(code[i:], base+i, code[i-1]); err != nil {
+			return err
+		}
+		i += n
+	}
+	return nil
+}
+
+// 校验单个指令。
+// 返回指令占用的字节数。
+func (v *validator) inst(code []byte, off int) (int, error) {
+	c := int(code[0])
+	lay := __Layouts[c]
+
+	fail := func(msg string) (int, error) {
+		return 0, &CodeError{off, c, msg}
+	}
+	if CodeNames[c] == "" {
+		return fail(errUnassigned)
+	}
+	if lay.Pattern && v.model == 0 {
+		return fail(errPattern)
+	}
+	switch c {
+	case icode.Mul, icode.Div, icode.Add, icode.Sub:
+		if v.expr == 0 {
+			return fail(errOperator)
+		}
+	}
+	args, n, msg := readArgs(code, lay.Size)
+	if msg != "" {
+		return fail(msg)
+	}
+	switch lay.Kind {
+	case LayoutValue:
+		if msg := checkValue(c, args[0]); msg != "" {
+			return fail(msg)
+		}
+		return n, nil
+
+	case LayoutExten:
+		sz := extenSize(c, DataLen(c, args[0]))
+		if n+sz > len(code) {
+			return fail(errTruncated)
+		}
+		return n + sz, nil
+
+	case LayoutBytes:
+		sz := DataLen(c, args[len(args)-1])
+		if n+sz > len(code) {
+			return fail(errTruncated)
+		}
+		if err := v.data(c, args[0], code[n:n+sz], off, off+n); err != nil {
+			return 0, err
+		}
+		return n + sz, nil
+	}
+	if c == icode.WithinInt {
+		for _, a := range args {
+			if _, k := binary.Varint(a); k <= 0 {
+				return fail(errVarint)
+			}
+		}
+	}
+	return n, nil
+}
+
+// 校验关联数据。
+// 子语句块递归校验，正则表达式尝试编译。
+// arg 为首个附参，off 为指令偏移，base 为数据的偏移。
+func (v *validator) data(c int, arg, data []byte, off, base int) error {
+	switch c {
+	case icode.RegExp, icode.RE:
+		if c == icode.RE {
+			if f := arg[0] &^ 0b1000_0000; f != 0 && f != 'g' && f != 'G' {
+				return &CodeError{off, c, errFlag}
+			}
+		}
+		if _, err := regexp.Compile(string(data)); err != nil {
+			return &CodeError{off, c, err.Error()}
+		}
+	case icode.MODEL:
+		v.model++
+		defer func() { v.model-- }()
+		return v.list(data, base)
+
+	case icode.Expr:
+		v.expr++
+		err := v.list(data, base)
+		v.expr--
+
+		if err != nil || v.expr > 0 || v.model > 0 || ExprCheck == nil {
+			return err
+		}
+		// 嵌套的分组由顶层一并检查
+		if i, err := ExprCheck(data); err != nil {
+			if i < len(data) {
+				return &CodeError{base + i, int(data[i]), err.Error()}
+			}
+			return &CodeError{off, c, err.Error()}
+		}
+
+	case icode.IF, icode.ELSE, icode.SWITCH, icode.CASE, icode.DEFAULT,
+		icode.EACH, icode.BLOCK, icode.MAP, icode.FILTER, icode.CODE, icode.Wildlist:
+		// 块内不继承表达式状态
+		x := v.expr
+		v.expr = 0
+		defer func() { v.expr = x }()
+		return v.list(data, base)
+	}
+	return nil
+}
+
+// 校验局部通配的目标指令。
+// 目标指令按通配标识省略了部分附参或数据，规则同模式匹配的捡取器。
+// flag 为 ?(n) 的附参。
+func (v *validator) wild(code []byte, off int, flag byte) (int, error) {
+	c := int(code[0])
+	lay := __Layouts[c]
+
+	fail := func(msg string) (int, error) {
+		return 0, &CodeError{off, c, msg}
+	}
+	if CodeNames[c] == "" {
+		return fail(errUnassigned)
+	}
+	if lay.Pattern {
+		return fail(errWildModel)
+	}
+	omit := func(n int) bool { return flag&(1<<n) != 0 }
+
+	switch lay.Kind {
+	case LayoutValue:
+		switch {
+		case flag&wildHash != 0:
+			return sized(code, 1+wildHashSize, off, c)
+		case omit(1) || flag&wildData != 0:
+			return 1, nil
+		}
+	case LayoutBytes, LayoutExten:
+		if lay.Kind == LayoutBytes && flag&wildHash != 0 {
+			return sized(code, 1+wildHashSize, off, c)
+		}
+		if omit(1) {
+			return 1, nil
+		}
+		if flag&wildData != 0 {
+			_, n, msg := readArgs(code, lay.Size)
+			if msg != "" {
+				return fail(msg)
+			}
+			return n, nil
+		}
+	case LayoutArgs:
+		size := make([]int, 0, len(lay.Size))
+		for i, n := range lay.Size {
+			if !omit(i + 1) {
+				size = append(size, n)
+			}
+		}
+		_, n, msg := readArgs(code, size)
+		if msg != "" {
+			return fail(msg)
+		}
+		return n, nil
+	}
+	// 完整指令
+	return v.inst(code, off)
+}
+
+//
+// 私有辅助
+///////////////////////////////////////////////////////////////////////////////
+
+// 读取附参序列。
+// size 为各附参的字节数（SizeVarint 为变长）。
+// 返回附参集、指令码与附参的总长和出错消息。
+func readArgs(code []byte, size []int) ([][]byte, int, string) {
+	args := make([][]byte, len(size))
+	at := 1
+
+	for i, n := range size {
+		if n == SizeVarint {
+			if _, n = binary.Uvarint(code[at:]); n <= 0 {
+				if n == 0 {
+					return nil, 0, errTruncated
+				}
+				return nil, 0, errVarint
+			}
+		}
+		if at+n > len(code) {
+			return nil, 0, errTruncated
+		}
+		args[i] = code[at : at+n]
+		at += n
+	}
+	return args, at, ""
+}
+
+// 值指令的数值检查。
+// 变长存储的整数不能超出 int64 范围。
+func checkValue(c int, b []byte) string {
+	switch c {
+	case icode.Uint63:
+		if x, _ := binary.Uvarint(b); x > math.MaxInt64 {
+			return errOverflow
+		}
+	case icode.Uint63n:
+		if x, _ := binary.Uvarint(b); x > 1<<63 {
+			return errOverflow
+		}
+	}
+	return ""
+}
+
+// 扩展类指令的数据长度。
+// i 为扩展目标索引。
+func extenSize(c, i int) int {
+	switch c {
+	case icode.MO_X:
+		return MoxSize(i)
+	case icode.EX_INST:
+		return ExtSize(i)
+	}
+	return PrivSize(i)
+}
+
+// 固定长度检查。
+func sized(code []byte, n, off, c int) (int, error) {
+	if n > len(code) {
+		return 0, &CodeError{off, c, errTruncated}
+	}
+	return n, nil
+}
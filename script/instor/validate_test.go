@@ -0,0 +1,68 @@
+package instor_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cxio/suite/script/asm"
+	"github.com/cxio/suite/script/icode"
+	"github.com/cxio/suite/script/instor"
+)
+
+// 合法脚本通过校验。
+func TestValidateOK(t *testing.T) {
+	srcs := []string{
+		``,
+		`1 -1 1000 -1000 1.5 0.1 'x' "text" DATA{0x01} /a+/ DATE{0} BigInt{1}`,
+		`IF{ ENV{Height} 10 GT } ELSE{ FAIL } SWITCH{ CASE{1} DEFAULT{} } BLOCK{ EACH{ ${Value} } }`,
+		`(1 + (2 * 3)) GOTO(1, 2, 3) OUT{1, Amount} MO_X(1, 2) EX_INST(3, 4) EX_PRIV(5)`,
+		`MODEL(1){ _ _(2) #(1) !{Int} !{1, 5} !{1.5, 2.5, 0.1} RE{!/x/g} &(1) ?{ NOP } ... }`,
+		`MODEL{ ?(2) OUT{1, Amount} ?(64) DATA{0x0102} ?(128) "hash" ?(2) 1000 IF{ _ } }`,
+	}
+	for _, src := range srcs {
+		code, err := asm.Assemble([]byte(src))
+		if err != nil {
+			t.Fatalf("Assemble(%q): %v", src, err)
+		}
+		if err := instor.Validate(code); err != nil {
+			t.Errorf("Validate(%q): %v", src, err)
+		}
+	}
+}
+
+// 非法脚本被拒绝，并报告出错指令的位置。
+func TestValidateFail(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   []byte
+		offset int
+		op     int
+	}{
+		{"unassigned", []byte{icode.NOP, 19}, 1, 19},
+		{"reserved", []byte{255}, 0, 255},
+		{"truncated args", []byte{icode.GOTO, 0, 0, 0, 1}, 0, icode.GOTO},
+		{"truncated data", []byte{icode.DATA8, 3, 1, 2}, 0, icode.DATA8},
+		{"truncated varint", []byte{icode.Uint63, 0x80}, 0, icode.Uint63},
+		{"overflow", []byte{icode.Uint63, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, 0, icode.Uint63},
+		{"bad regexp", []byte{icode.RegExp, 2, '(', '('}, 0, icode.RegExp},
+		{"nested", []byte{icode.IF, 2, icode.NOP, icode.SHIFT}, 3, icode.SHIFT},
+		{"pattern outside", []byte{icode.Wildcard}, 0, icode.Wildcard},
+		{"operator outside", []byte{icode.Add}, 0, icode.Add},
+		{"wild target", []byte{icode.MODEL, 0, 2, icode.Wildpart, 2}, 3, icode.Wildpart},
+		{"wild pattern", []byte{icode.MODEL, 0, 3, icode.Wildpart, 2, icode.Wildcard}, 5, icode.Wildcard},
+		{"model length", []byte{icode.MODEL, 0x80, 9, icode.NOP}, 0, icode.MODEL},
+		{"re flag", []byte{icode.MODEL, 0, 4, icode.RE, 'x', 1, 'a'}, 3, icode.RE},
+	}
+	for _, tt := range tests {
+		err := instor.Validate(tt.code)
+
+		var e *instor.CodeError
+		if !errors.As(err, &e) {
+			t.Errorf("%s: expect *CodeError, got %v", tt.name, err)
+			continue
+		}
+		if e.Offset != tt.offset || e.Code != tt.op {
+			t.Errorf("%s: error at %d (code %d), want %d (code %d): %s", tt.name, e.Offset, e.Code, tt.offset, tt.op, e.Reason)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+// Package trace 脚本执行跟踪记录器。
+// 作为执行跟踪器（ibase.Tracer）挂接到执行器，逐条记录执行过的指令，
+// 以 JSON 行（每行一条记录）的格式输出，便于比较不同版本解释器的执行过程。
+//
+// 记录分为三类：
+//   - inst  指令调用，含实参、返回值和返回值的存放区。
+//   - enter 进入执行域（子块、循环、GOTO/JUMP 脚本、EVAL 代码、表达式）。
+//   - leave 离开执行域。
+//
+// 指令记录在指令返回时写出，因此含子块的指令（如 IF、EACH）位于其子块的记录之后。
+// 中断了执行流的指令（如 BREAK、EXIT，或出错）在离开执行域时补记，标记 abort。
+package trace
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/cxio/suite/script/ibase"
+	"github.com/cxio/suite/script/instor"
+)
+
+// 记录类型。
+const (
+	KindInst  = "inst"
+	KindEnter = "enter"
+	KindLeave = "leave"
+)
+
+// 执行域名称。
+// 下标为 ibase.FrameXXX 值。
+var __frameNames = []string{
+	ibase.FrameTop:    "top",
+	ibase.FrameBlock:  "block",
+	ibase.FrameSwitch: "switch",
+	ibase.FrameCase:   "case",
+	ibase.FrameScope:  "scope",
+	ibase.FrameLoop:   "loop",
+	ibase.FrameGoto:   "goto",
+	ibase.FrameJump:   "jump",
+	ibase.FrameEval:   "eval",
+	ibase.FrameExpr:   "expr",
+}
+
+// 返回值存放区名称。
+// 下标为 ibase.StackFlag 等值。
+var __placeNames = []string{
+	ibase.StackFlag: "stack",
+	ibase.ArgsFlag:  "args",
+	ibase.ScopeFlag: "scope",
+}
+
+// 跟踪记录。
+// 值集成员转换为 JSON 友好的形式：字节序列为 0x 前缀的十六进制串，
+// 正则表达式为 /.../ 形式，时间为 RFC3339 格式，其它无法直接表示的类型为 %v 格式。
+// 数值标记其类型，以区分整数和浮点数的运算结果：
+//   - 整数为 {"int":"1"}，大整数为 {"bigint":"1"}，取十进制串以免精度损失。
+//   - 浮点数为 {"float":1.5}，非有限值（NaN、±Inf）取串形式，如 {"float":"+Inf"}。
+type Record struct {
+	Kind   string `json:"kind"`            // 记录类型
+	ID     string `json:"id"`              // 脚本标识（十六进制）
+	Offset int    `json:"offset"`          // 指令偏移，执行域记录为代码段起点
+	Depth  int    `json:"depth"`           // 嵌套深度
+	Frame  string `json:"frame,omitempty"` // 执行域类型
+	Code   int    `json:"code,omitempty"`  // 指令码
+	Name   string `json:"name,omitempty"`  // 指令名称
+	Args   []any  `json:"args,omitempty"`  // 实参
+	Rets   []any  `json:"rets,omitempty"`  // 返回值
+	To     string `json:"to,omitempty"`    // 返回值存放区
+	Gas    int64  `json:"gas"`             // 已消耗成本（记录时）
+	Abort  bool   `json:"abort,omitempty"` // 指令中断了执行流
+}
+
+// 跟踪记录器。
+// 实现 ibase.Tracer 接口，记录即时写出。
+// 注：
+// 一个记录器仅用于单个脚本的执行，非并发安全。
+type Recorder struct {
+	enc  *json.Encoder
+	err  error
+	pend []*Record // 已开始但尚未返回的指令（嵌套）
+}
+
+// 新建一个记录器。
+// w 为 JSON 行的输出目标。
+func New(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// 返回首个写出错误。
+// 出错之后的记录被忽略。
+func (r *Recorder) Err() error {
+	return r.err
+}
+
+// 指令执行前。
+// 暂存指令信息，待返回后补齐写出。
+func (r *Recorder) Before(a *ibase.Actuator) {
+	pos := a.Position()
+
+	r.pend = append(r.pend, &Record{
+		Kind:   KindInst,
+		ID:     hex.EncodeToString(pos.ID),
+		Offset: pos.Offset,
+		Depth:  a.Depth(),
+		Frame:  frameName(a.FrameKind()),
+		Code:   pos.Code,
+		Name:   instor.CodeNames[pos.Code],
+	})
+}
+
+// 指令正常返回。
+// 注：
+// 指令在返回时写出，因此含子块的指令记录位于其子块的记录之后。
+func (r *Recorder) After(a *ibase.Actuator, to int, args, rets []any) {
+	n := len(r.pend) - 1
+	if n < 0 {
+		return
+	}
+	rec := r.pend[n]
+	r.pend = r.pend[:n]
+
+	rec.Args = values(args)
+	rec.Rets = values(rets)
+	rec.Gas = a.GasUsed()
+
+	if rets != nil && to >= 0 && to < len(__placeNames) {
+		rec.To = __placeNames[to]
+	}
+	r.write(rec)
+}
+
+// 进入执行域。
+func (r *Recorder) Enter(a *ibase.Actuator) {
+	r.frame(KindEnter, a)
+}
+
+// 离开执行域。
+// 域内未返回的指令（中断了执行流）先行补记。
+func (r *Recorder) Leave(a *ibase.Actuator) {
+	d := a.Depth()
+
+	for n := len(r.pend) - 1; n >= 0 && r.pend[n].Depth >= d; n-- {
+		rec := r.pend[n]
+		r.pend = r.pend[:n]
+
+		rec.Abort = true
+		rec.Gas = a.GasUsed()
+		r.write(rec)
+	}
+	r.frame(KindLeave, a)
+}
+
+// 写出执行域记录。
+func (r *Recorder) frame(kind string, a *ibase.Actuator) {
+	r.write(&Record{
+		Kind:   kind,
+		ID:     hex.EncodeToString(a.ID),
+		Offset: a.Base(),
+		Depth:  a.Depth(),
+		Frame:  frameName(a.FrameKind()),
+		Gas:    a.GasUsed(),
+	})
+}
+
+// 写出一条记录。
+func (r *Recorder) write(rec *Record) {
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(rec)
+}
+
+// 读取跟踪记录。
+// 从 JSON 行格式的输入中读取全部记录，值集成员为 JSON 解码后的通用类型。
+func Read(r io.Reader) ([]Record, error) {
+	var buf []Record
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	for dec.More() {
+		var rec Record
+		if err := dec.Decode(&rec); err != nil {
+			return buf, err
+		}
+		buf = append(buf, rec)
+	}
+	return buf, nil
+}
+
+// 执行域名称。
+func frameName(k int) string {
+	if k < 0 || k >= len(__frameNames) {
+		return fmt.Sprint(k)
+	}
+	return __frameNames[k]
+}
+
+// 值集转换。
+func values(vs []any) []any {
+	if len(vs) == 0 {
+		return nil
+	}
+	buf := make([]any, len(vs))
+
+	for i, v := range vs {
+		buf[i] = value(v)
+	}
+	return buf
+}
+
+// 转换为 JSON 友好的值。
+func value(v any) any {
+	switch x := v.(type) {
+	case nil, bool, string:
+		return x
+	case int:
+		return map[string]any{"int": strconv.Itoa(x)}
+	case int64:
+		return map[string]any{"int": strconv.FormatInt(x, 10)}
+	case float64:
+		return floatValue(x)
+	case float32:
+		return floatValue(float64(x))
+	case byte:
+		return int(x)
+	case rune:
+		return string(x)
+	case []byte:
+		return "0x" + hex.EncodeToString(x)
+	case *big.Int:
+		return map[string]any{"bigint": x.String()}
+	case *regexp.Regexp:
+		return "/" + x.String() + "/"
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case []any:
+		return values(x)
+	case map[string]any:
+		m := make(map[string]any, len(x))
+		for k, v := range x {
+			m[k] = value(v)
+		}
+		return m
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// 浮点数的标记值。
+// 非有限值无法以 JSON 数值表示，取串形式。
+func floatValue(x float64) any {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return map[string]any{"float": strconv.FormatFloat(x, 'g', -1, 64)}
+	}
+	return map[string]any{"float": x}
+}
@@ -0,0 +1,108 @@
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cxio/suite/script/asm"
+	"github.com/cxio/suite/script/ibase"
+	"github.com/cxio/suite/script/inst"
+	"github.com/cxio/suite/script/trace"
+)
+
+// 执行脚本并读回跟踪记录。
+func record(t *testing.T, src string) ([]trace.Record, error) {
+	t.Helper()
+
+	code, err := asm.Assemble([]byte(src))
+	if err != nil {
+		t.Fatalf("Assemble(%q): %v", src, err)
+	}
+	var buf bytes.Buffer
+	rec := trace.New(&buf)
+
+	a := ibase.NewActuator([]byte{1, 2}, code, nil, ibase.NewEnvs(nil, nil, 0), 1)
+	a.SetMonitor(rec)
+	_, err = inst.Execute(context.Background(), a)
+
+	if rec.Err() != nil {
+		t.Fatal(rec.Err())
+	}
+	list, e := trace.Read(&buf)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return list, err
+}
+
+// 记录概要：类型/名称/深度。
+func brief(list []trace.Record) string {
+	var ss []string
+
+	for _, r := range list {
+		s := r.Kind + ":" + r.Frame
+		if r.Kind == trace.KindInst {
+			s = r.Name
+			if r.Abort {
+				s += "!"
+			}
+		}
+		ss = append(ss, s)
+	}
+	return strings.Join(ss, " ")
+}
+
+func TestRecord(t *testing.T) {
+	list, err := record(t, `1 BLOCK{ 2 } true PASS`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "enter:top Uint8 enter:block Uint8 leave:block BLOCK TRUE PASS leave:top"
+	if got := brief(list); got != want {
+		t.Errorf("trace:\n got %s\nwant %s", got, want)
+	}
+	// 偏移和返回值
+	r := list[3]
+	if r.ID != "0102" || r.Offset != 4 || r.Depth != 1 || r.To != "stack" || len(r.Rets) != 1 || fmt.Sprint(r.Rets[0]) != "map[int:2]" {
+		t.Errorf("inner record: %+v", r)
+	}
+	if p := list[7]; len(p.Args) != 1 || p.Args[0] != true {
+		t.Errorf("PASS args: %+v", p)
+	}
+}
+
+// 中断执行流的指令补记。
+func TestRecordAbort(t *testing.T) {
+	list, err := record(t, `BLOCK{ false PASS } NOP`)
+	if err == nil {
+		t.Fatal("expect failure")
+	}
+	want := "enter:top enter:block FALSE PASS! leave:block BLOCK! leave:top"
+	if got := brief(list); got != want {
+		t.Errorf("trace:\n got %s\nwant %s", got, want)
+	}
+}
+
+// 数值带类型标记，非有限的浮点数不中断记录。
+func TestRecordNumbers(t *testing.T) {
+	list, err := record(t, `1 1.5 (1.0 / 0.0) (-1.0 / 0.0) true PASS`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rets []string
+	for _, r := range list {
+		if r.Kind == trace.KindInst && len(r.Rets) == 1 {
+			rets = append(rets, fmt.Sprint(r.Rets[0]))
+		}
+	}
+	want := "[map[int:1] map[float:1.5] map[float:+Inf] map[float:-Inf] true]"
+	if got := fmt.Sprint(rets); got != want {
+		t.Errorf("rets:\n got %s\nwant %s", got, want)
+	}
+	if brief(list[len(list)-2:]) != "PASS leave:top" {
+		t.Errorf("records after Inf dropped: %s", brief(list))
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2022 of chainx.zh@gmail.com, All rights reserved.
+// Use of this source code is governed by a MIT license.
+
+package xpool
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// 目录解析器。
+// 从本地目录读取脚本文件，文件名为“高度-序位-脚本序位”（如 100-2-0），
+// 内容为脚本的原始字节序列。
+// 用于测试或离线执行跨脚本的 GOTO/JUMP。
+type DirResolver string
+
+// 目标脚本的文件名。
+func FileName(h, n, i int) string {
+	return fmt.Sprintf("%d-%d-%d", h, n, i)
+}
+
+// 读取目标脚本。
+// 文件不存在时返回 ErrNotFound。
+func (d DirResolver) Resolve(h, n, i int) ([]byte, error) {
+	code, err := os.ReadFile(filepath.Join(string(d), FileName(h, n, i)))
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return code, err
+}
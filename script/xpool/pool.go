@@ -3,88 +3,362 @@
 
 // Package xpool 第三方脚本片段集存储与检索。
 // 用于 GOTO、JUMP 指令快速获取第三方脚本。在所有Goroutines之间共享，并发安全。
+// 脚本的实际来源由外部注册的解析器（Resolver）提供，池仅作为缓存。
 package xpool
 
 import (
+	"container/list"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cxio/suite/cbase"
+	"github.com/cxio/suite/locale"
 )
 
-// 池大小。
-// 超出的量会被监控服务器定期削平（随机移除）。
-const Size = 1 << 14
+var _T = locale.GetText // 本地化文本获取。
 
+// 池容量默认值。
+// 条目数和字节数任一超出，即按最近最少使用的顺序移除。
 const (
-	// 池检查间隔时间。
-	chkTime = 30 * time.Minute
+	Size      = 1 << 14  // 条目数
+	SizeBytes = 16 << 20 // 脚本字节数
 )
 
+const (
+	// 过期否定结果的清理间隔。
+	chkTime = 5 * time.Minute
+
+	// 否定结果的有效期。
+	// 期内对同一目标的查询直接返回 ErrNotFound，不再请求解析器。
+	missTime = time.Minute
+)
+
+var (
+	// 目标脚本不存在。
+	ErrNotFound = errors.New(_T("目标脚本不存在"))
+
+	// 未注册解析器。
+	ErrNoResolver = errors.New(_T("脚本解析器未注册"))
+)
+
+// 脚本解析器。
+// 从外部（如区块查询服务）获取目标脚本，参数含义同 Get。
+// 目标不存在时应当返回 ErrNotFound（或包装它的错误），以便缓存否定结果。
+// 其它错误视为暂时性的，不会被缓存。
+type Resolver interface {
+	Resolve(h, n, i int) ([]byte, error)
+}
+
+// 函数形式的解析器。
+type ResolverFunc func(h, n, i int) ([]byte, error)
+
+func (f ResolverFunc) Resolve(h, n, i int) ([]byte, error) {
+	return f(h, n, i)
+}
+
+// 脚本键长度。
+// 同 cbase.KeyID 的构成：高度（4）+ 序位（4）+ 脚本序位（2）。
+const keySize = 4 + 4 + 2
+
+// 脚本键。
+// 即 cbase.KeyID 的定长形式，可用作映射键。
+type Key [keySize]byte
+
+// 构造脚本键。
+func KeyOf(h, n, i int) Key {
+	var k Key
+	copy(k[:], cbase.KeyID(h, n, i))
+	return k
+}
+
+// 池条目。
+type entry struct {
+	key  Key
+	code []byte    // 脚本序列
+	miss time.Time // 否定结果的记录时间，零值表示有效脚本
+}
+
+// 条目计量大小。
+func (e *entry) size() int64 {
+	return int64(len(e.code))
+}
+
+// 进行中的解析。
+// 同一目标的并发请求等待首个请求的结果，不重复解析。
+type call struct {
+	done chan struct{}
+	code []byte
+	err  error
+}
+
+// 池统计。
+type Stats struct {
+	Hits      int64 // 命中（含否定结果）
+	Misses    int64 // 未命中（请求解析器）
+	Shared    int64 // 等待同一目标的进行中解析
+	Evictions int64 // 因容量限制移除
+	Entries   int   // 当前条目数
+	Bytes     int64 // 当前脚本字节数
+}
+
+// 脚本缓存。
+// 最近最少使用（LRU）策略，链表头部为最近使用。
+type cache struct {
+	mu       sync.Mutex
+	ll       *list.List
+	items    map[Key]*list.Element
+	calls    map[Key]*call
+	bytes    int64
+	maxItems int
+	maxBytes int64
+	stats    Stats
+}
+
+// 新建缓存。
+func newCache(items int, bytes int64) *cache {
+	return &cache{
+		ll:       list.New(),
+		items:    make(map[Key]*list.Element),
+		calls:    make(map[Key]*call),
+		maxItems: items,
+		maxBytes: bytes,
+	}
+}
+
+// 查询缓存。
+// 返回有效的脚本或否定结果，ok 为假表示需要解析。
+// 过期的否定结果被移除。
+// 注：调用者需持有锁。
+func (c *cache) lookup(k Key) (code []byte, err error, ok bool) {
+	el := c.items[k]
+	if el == nil {
+		return nil, nil, false
+	}
+	e := el.Value.(*entry)
+
+	if e.miss.IsZero() {
+		c.ll.MoveToFront(el)
+		return e.code, nil, true
+	}
+	if time.Since(e.miss) < missTime {
+		return nil, ErrNotFound, true
+	}
+	c.remove(el)
+	return nil, nil, false
+}
+
+// 添加条目。
+// 已有的同键条目被替换，然后按容量限制移除最久未用的条目。
+// 超出字节上限的单个脚本不入池，以免清空其它条目。
+// 注：调用者需持有锁。
+func (c *cache) add(e *entry) {
+	if el := c.items[e.key]; el != nil {
+		c.remove(el)
+	}
+	if e.size() > c.maxBytes {
+		return
+	}
+	c.items[e.key] = c.ll.PushFront(e)
+	c.bytes += e.size()
+	c.trim()
+}
+
+// 移除条目。
+// 注：调用者需持有锁。
+func (c *cache) remove(el *list.Element) {
+	e := c.ll.Remove(el).(*entry)
+	delete(c.items, e.key)
+	c.bytes -= e.size()
+}
+
+// 按容量限制移除最久未用的条目。
+// 注：调用者需持有锁。
+func (c *cache) trim() {
+	for c.ll.Len() > c.maxItems || c.bytes > c.maxBytes {
+		el := c.ll.Back()
+		if el == nil {
+			return
+		}
+		c.remove(el)
+		c.stats.Evictions++
+	}
+}
+
+// 清理过期的否定结果。
+func (c *cache) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for el := c.ll.Back(); el != nil; {
+		prev := el.Prev()
+		if e := el.Value.(*entry); !e.miss.IsZero() && time.Since(e.miss) >= missTime {
+			c.remove(el)
+		}
+		el = prev
+	}
+}
+
 // 池服务是否已经运行。
-var serving = false
+var serving atomic.Bool
+
+// 当前解析器。
+var resolver atomic.Pointer[Resolver]
 
 // 脚本池。
-// key:   string 由区块高度、交易ID和脚本序位构成。
-// value: []byte 脚本序列。
-var pool sync.Map
+// 键由区块高度、交易ID和脚本序位构成，值为脚本序列或否定结果。
+var pool = newCache(Size, SizeBytes)
+
+// 注册解析器。
+// 应当在脚本执行之前设置，传递nil清除注册。
+// 注：
+// 替换解析器不会清除池中已有的条目，需要时可调用 Reset。
+func SetResolver(r Resolver) {
+	resolver.Store(&r)
+}
+
+// 获取当前解析器。
+func currentResolver() Resolver {
+	if p := resolver.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// 设置池容量。
+// items 为条目数上限，bytes 为脚本字节数上限，小于等于零时取默认值。
+// 超出新限制的条目即时移除。
+func SetLimit(items int, bytes int64) {
+	if items <= 0 {
+		items = Size
+	}
+	if bytes <= 0 {
+		bytes = SizeBytes
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.maxItems, pool.maxBytes = items, bytes
+	pool.trim()
+}
+
+// 清空池。
+// 移除全部条目并重置统计，进行中的解析不受影响。
+func Reset() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.ll.Init()
+	clear(pool.items)
+	pool.bytes = 0
+	pool.stats = Stats{}
+}
+
+// 获取池统计。
+func Metrics() Stats {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	s := pool.stats
+	s.Entries = pool.ll.Len()
+	s.Bytes = pool.bytes
+
+	return s
+}
 
 // 获取目标脚本。
 // 参数：
 // h 交易所在区块高度。
 // n 交易ID在其区块中的序位，从0开始。
 // i 脚本在输出集中的序位，从0开始。
-func Get(h, n, i int) []byte {
-	k := cbase.KeyID(h, n, i)
+// 目标不存在时返回 ErrNotFound，解析器出错时返回其错误。
+// 对同一目标的并发请求仅解析一次。
+func Get(h, n, i int) ([]byte, error) {
+	k := KeyOf(h, n, i)
 
-	if v, ok := pool.Load(k); ok {
-		return v.([]byte)
+	pool.mu.Lock()
+	if code, err, ok := pool.lookup(k); ok {
+		pool.stats.Hits++
+		pool.mu.Unlock()
+		return code, err
+	}
+	if c := pool.calls[k]; c != nil {
+		pool.stats.Shared++
+		pool.mu.Unlock()
+		<-c.done
+		return c.code, c.err
 	}
-	var code []byte
-	//?...
-	// 向外获取目标脚本（blockqs）
+	r := currentResolver()
+	if r == nil {
+		pool.mu.Unlock()
+		return nil, ErrNoResolver
+	}
+	pool.stats.Misses++
+	c := &call{done: make(chan struct{})}
+	pool.calls[k] = c
+	pool.mu.Unlock()
+
+	fetch(c, k, r, h, n, i)
 
-	pool.Store(k, code)
-	return code
+	return c.code, c.err
 }
 
-// 创建一个池服务。
-// 主要用于监控池大小是否超出限定，
-// 在到达limit时间后检查，超出多少即移除多少（恢复到限定水平）。
-// 注意：
-// 应当仅被调用一次，此函数自身并非并发安全。
-// 服务启动后会一直执行，直到程序自身停止运行。
-func Serve(limit int) {
-	if serving {
+// 解析目标脚本并存入池。
+// 解析器的 panic 转为错误，确保等待者总能获得结果。
+func fetch(c *call, k Key, r Resolver, h, n, i int) {
+	defer func() {
+		if v := recover(); v != nil {
+			c.code, c.err = nil, fmt.Errorf(_T("脚本解析器异常：%v"), v)
+		}
+		pool.mu.Lock()
+		delete(pool.calls, k)
+
+		switch {
+		case c.err == nil:
+			pool.add(&entry{key: k, code: c.code})
+		case errors.Is(c.err, ErrNotFound):
+			pool.add(&entry{key: k, miss: time.Now()})
+		}
+		pool.mu.Unlock()
+		close(c.done)
+	}()
+	c.code, c.err = r.Resolve(h, n, i)
+
+	if c.err == nil && len(c.code) == 0 {
+		c.err = ErrNotFound // 空脚本无意义
+	}
+	if c.err != nil {
+		c.code = nil
+	}
+}
+
+// 启动池服务。
+// 定期清理过期的否定结果，直到 ctx 取消。
+// 容量限制在存入时即时执行，无需服务参与。
+// 注：
+// 同一时间仅可运行一个服务，重复启动会被忽略。
+func Serve(ctx context.Context) {
+	if !serving.CompareAndSwap(false, true) {
 		fmt.Fprintln(os.Stderr, "The xpool service is already running")
 		return
 	}
-	if limit <= 0 {
-		limit = Size
-	}
 	go func() {
-		tick := time.Tick(chkTime)
+		defer serving.Store(false)
+
+		tick := time.NewTicker(chkTime)
+		defer tick.Stop()
+
 		for {
-			<-tick
-			shear(limit)
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				pool.expire()
+			}
 		}
 	}()
-	serving = true
-}
-
-// 削去多余的量，维持一定规模。
-// 注记：
-// sync.Map 不支持获取条目数量，只能完整迭代。
-// 因此执行一次应当间隔足够长的时间，避免浪费。
-func shear(max int) {
-	len := 0
-	pool.Range(func(k, _ any) bool {
-		len++
-		if len > max {
-			pool.Delete(k)
-		}
-		return true
-	})
 }
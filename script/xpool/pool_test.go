@@ -0,0 +1,180 @@
+package xpool_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/cxio/suite/script/xpool"
+)
+
+func TestDirResolver(t *testing.T) {
+	dir := t.TempDir()
+	code := []byte{1, 2, 3}
+
+	if err := os.WriteFile(filepath.Join(dir, xpool.FileName(100, 2, 0)), code, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := xpool.DirResolver(dir)
+
+	got, err := r.Resolve(100, 2, 0)
+	if err != nil || !bytes.Equal(got, code) {
+		t.Errorf("Resolve(100, 2, 0) = %v, %v", got, err)
+	}
+	if _, err := r.Resolve(100, 2, 1); !errors.Is(err, xpool.ErrNotFound) {
+		t.Errorf("Resolve(100, 2, 1): expect ErrNotFound, got %v", err)
+	}
+}
+
+// 以高度为内容的解析器，i 为脚本长度。
+func sized(calls *int) xpool.Resolver {
+	return xpool.ResolverFunc(func(h, n, i int) ([]byte, error) {
+		*calls++
+		return bytes.Repeat([]byte{byte(h)}, i), nil
+	})
+}
+
+func TestGet(t *testing.T) {
+	xpool.Reset()
+	defer xpool.SetResolver(nil)
+
+	if _, err := xpool.Get(200, 0, 0); !errors.Is(err, xpool.ErrNoResolver) {
+		t.Errorf("no resolver: expect ErrNoResolver, got %v", err)
+	}
+	calls := 0
+	transient := errors.New("transient")
+
+	xpool.SetResolver(xpool.ResolverFunc(func(h, n, i int) ([]byte, error) {
+		calls++
+		switch i {
+		case 0:
+			return []byte{byte(h)}, nil
+		case 1:
+			return nil, transient
+		}
+		return nil, xpool.ErrNotFound
+	}))
+	// 有效脚本被缓存
+	for range 2 {
+		if code, err := xpool.Get(201, 0, 0); err != nil || !bytes.Equal(code, []byte{201}) {
+			t.Errorf("Get(201, 0, 0) = %v, %v", code, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("found: expect 1 call, got %d", calls)
+	}
+	// 否定结果被缓存
+	calls = 0
+	for range 2 {
+		if _, err := xpool.Get(201, 0, 2); !errors.Is(err, xpool.ErrNotFound) {
+			t.Errorf("Get(201, 0, 2): expect ErrNotFound, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("not found: expect 1 call, got %d", calls)
+	}
+	// 暂时性错误不缓存
+	calls = 0
+	for range 2 {
+		if _, err := xpool.Get(201, 0, 1); !errors.Is(err, transient) {
+			t.Errorf("Get(201, 0, 1): expect transient error, got %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("transient: expect 2 calls, got %d", calls)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	xpool.Reset()
+	xpool.SetLimit(2, 0)
+	defer xpool.SetLimit(0, 0)
+	defer xpool.SetResolver(nil)
+
+	calls := 0
+	xpool.SetResolver(sized(&calls))
+
+	xpool.Get(1, 0, 1)
+	xpool.Get(2, 0, 1)
+	xpool.Get(1, 0, 1) // 1 成为最近使用
+	xpool.Get(3, 0, 1) // 移除 2
+
+	calls = 0
+	xpool.Get(1, 0, 1)
+	xpool.Get(3, 0, 1)
+	if calls != 0 {
+		t.Errorf("recent entries evicted: %d calls", calls)
+	}
+	xpool.Get(2, 0, 1)
+	if calls != 1 {
+		t.Errorf("oldest entry kept: %d calls", calls)
+	}
+	s := xpool.Metrics()
+	if s.Hits != 3 || s.Misses != 4 || s.Evictions != 2 || s.Entries != 2 || s.Bytes != 2 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+func TestLimitBytes(t *testing.T) {
+	xpool.Reset()
+	xpool.SetLimit(0, 100)
+	defer xpool.SetLimit(0, 0)
+	defer xpool.SetResolver(nil)
+
+	calls := 0
+	xpool.SetResolver(sized(&calls))
+
+	xpool.Get(1, 0, 60)
+	xpool.Get(2, 0, 30)
+	xpool.Get(3, 0, 30) // 移除 1
+	xpool.Get(4, 0, 200)
+
+	s := xpool.Metrics()
+	if s.Entries != 2 || s.Bytes != 60 || s.Evictions != 1 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+// 并发请求同一目标仅解析一次。
+func TestShared(t *testing.T) {
+	xpool.Reset()
+	defer xpool.SetResolver(nil)
+
+	var calls sync.WaitGroup
+	calls.Add(1)
+	start := make(chan struct{})
+	count := 0
+
+	xpool.SetResolver(xpool.ResolverFunc(func(h, n, i int) ([]byte, error) {
+		count++
+		calls.Done()
+		<-start
+		return []byte{1}, nil
+	}))
+	const N = 8
+	var wg sync.WaitGroup
+	wg.Add(N)
+
+	for range N {
+		go func() {
+			defer wg.Done()
+			if code, err := xpool.Get(5, 0, 0); err != nil || len(code) != 1 {
+				t.Errorf("Get = %v, %v", code, err)
+			}
+		}()
+	}
+	calls.Wait()
+	for xpool.Metrics().Shared+1 < N {
+		runtime.Gosched()
+	}
+	close(start)
+	wg.Wait()
+
+	if count != 1 {
+		t.Errorf("resolver called %d times", count)
+	}
+}